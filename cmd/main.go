@@ -29,11 +29,19 @@ import (
 )
 
 func main() {
+	const errMessagePrefix = "kubevirt-storage-checkup failed"
+
+	if len(os.Args) > 1 && os.Args[1] == pkg.ImageServerCommand {
+		log.Println("kubevirt-storage-checkup image server starting...")
+		if err := pkg.RunImageServer(); err != nil {
+			log.Fatalf("%s: %v\n", errMessagePrefix, err)
+		}
+		return
+	}
+
 	log.Println("kubevirt-storage-checkup starting...")
 	rawEnv := environment.EnvToMap(os.Environ())
 
-	const errMessagePrefix = "kubevirt-storage-checkup failed"
-
 	namespace, err := environment.ReadNamespaceFile()
 	if err != nil {
 		log.Fatalf("%s: %v\n", errMessagePrefix, err)
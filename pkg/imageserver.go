@@ -0,0 +1,31 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package pkg
+
+import (
+	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/imageserver"
+)
+
+// ImageServerCommand is the argument the checkup image is started with to serve the import check disk images
+const ImageServerCommand = imageserver.Command
+
+func RunImageServer() error {
+	return imageserver.ListenAndServe()
+}
@@ -0,0 +1,179 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+// boot phases a concurrently booted VM can stall at
+const (
+	bootPhaseVMCreation      = "VM creation"
+	bootPhaseDVPending       = "DV pending"
+	bootPhaseDVFailed        = "DV failed"
+	bootPhaseCloneInProgress = "clone in progress"
+	bootPhaseScheduling      = "scheduling"
+	bootPhaseLauncherPod     = "launcher pod error"
+	bootPhaseGuestAgent      = "guest agent"
+)
+
+var cloneInProgressPhases = []cdiv1.DataVolumePhase{
+	cdiv1.CloneScheduled,
+	cdiv1.CloneInProgress,
+	cdiv1.SnapshotForSmartCloneInProgress,
+	cdiv1.CloneFromSnapshotSourceInProgress,
+	cdiv1.SmartClonePVCInProgress,
+	cdiv1.CSICloneInProgress,
+	cdiv1.NamespaceTransferInProgress,
+	cdiv1.PrepClaimInProgress,
+	cdiv1.RebindInProgress,
+	cdiv1.ExpansionInProgress,
+}
+
+// vmBootFailure is the boot phase a concurrently booted VM stalled at, with the state of its DataVolumes and VMI
+type vmBootFailure struct {
+	vmName  string
+	phase   string
+	details []string
+}
+
+// diagnoseVMBootFailure finds the first boot phase the VM did not complete, DataVolumes first and then the VMI
+func (c *Checkup) diagnoseVMBootFailure(ctx context.Context, vm *kvcorev1.VirtualMachine) vmBootFailure {
+	failure := vmBootFailure{vmName: vm.Name}
+
+	for i := range vm.Spec.DataVolumeTemplates {
+		dvName := vm.Spec.DataVolumeTemplates[i].Name
+		dv, err := c.client.GetDataVolume(ctx, c.namespace, dvName)
+		if err != nil {
+			failure.details = append(failure.details, fmt.Sprintf("DV %s: %s", dvName, err))
+			continue
+		}
+		var conditions []string
+		for _, condition := range dv.Status.Conditions {
+			conditions = append(conditions,
+				formatBootCondition(string(condition.Type), condition.Status, condition.Reason, condition.Message))
+		}
+		failure.details = append(failure.details,
+			fmt.Sprintf("DV %s %s: %s", dvName, dv.Status.Phase, strings.Join(conditions, ", ")))
+		if failure.phase == "" {
+			failure.phase = dvBootPhase(dv.Status.Phase)
+		}
+	}
+
+	vmi, err := c.client.GetVirtualMachineInstance(ctx, c.namespace, vm.Name)
+	if err != nil {
+		failure.details = append(failure.details, fmt.Sprintf("VMI: %s", err))
+		if failure.phase == "" {
+			failure.phase = bootPhaseScheduling
+		}
+		return failure
+	}
+	var conditions []string
+	for _, condition := range vmi.Status.Conditions {
+		conditions = append(conditions,
+			formatBootCondition(string(condition.Type), condition.Status, condition.Reason, condition.Message))
+	}
+	failure.details = append(failure.details, fmt.Sprintf("VMI %s: %s", vmi.Status.Phase, strings.Join(conditions, ", ")))
+	if failure.phase == "" {
+		failure.phase = vmiBootPhase(vmi)
+	}
+
+	return failure
+}
+
+// dvBootPhase returns the boot phase a DataVolume in the given phase stalls, or empty when it succeeded
+func dvBootPhase(phase cdiv1.DataVolumePhase) string {
+	switch {
+	case phase == cdiv1.Succeeded:
+		return ""
+	case phase == cdiv1.Failed:
+		return bootPhaseDVFailed
+	case containsDataVolumePhase(cloneInProgressPhases, phase):
+		return bootPhaseCloneInProgress
+	default:
+		return bootPhaseDVPending
+	}
+}
+
+func containsDataVolumePhase(phases []cdiv1.DataVolumePhase, phase cdiv1.DataVolumePhase) bool {
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// vmiBootPhase returns the boot phase a VMI whose DataVolumes succeeded stalls
+func vmiBootPhase(vmi *kvcorev1.VirtualMachineInstance) string {
+	for _, condition := range vmi.Status.Conditions {
+		if condition.Status != corev1.ConditionFalse {
+			continue
+		}
+		switch condition.Type {
+		case kvcorev1.VirtualMachineInstanceConditionType(corev1.PodScheduled):
+			return bootPhaseScheduling
+		case kvcorev1.VirtualMachineInstanceSynchronized:
+			return bootPhaseLauncherPod
+		}
+	}
+
+	switch vmi.Status.Phase {
+	case kvcorev1.Running:
+		return bootPhaseGuestAgent
+	case kvcorev1.Scheduled, kvcorev1.Failed:
+		return bootPhaseLauncherPod
+	default:
+		return bootPhaseScheduling
+	}
+}
+
+func formatBootCondition(conditionType string, status corev1.ConditionStatus, reason, message string) string {
+	res := fmt.Sprintf("%s=%s", conditionType, status)
+	switch {
+	case reason != "" && message != "":
+		res += fmt.Sprintf(" (%s: %s)", reason, message)
+	case reason != "" || message != "":
+		res += fmt.Sprintf(" (%s%s)", reason, message)
+	}
+	return res
+}
+
+// formatBootFailures lists the failures by VM name, one VM per line
+func formatBootFailures(failures []vmBootFailure) string {
+	sort.Slice(failures, func(i, j int) bool { return failures[i].vmName < failures[j].vmName })
+	var res string
+	for i := range failures {
+		line := fmt.Sprintf("%s: stalled at %s", failures[i].vmName, failures[i].phase)
+		if len(failures[i].details) > 0 {
+			line += "; " + strings.Join(failures[i].details, "; ")
+		}
+		appendSep(&res, line)
+	}
+	return res
+}
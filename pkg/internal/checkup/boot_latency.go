@@ -0,0 +1,230 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+// unknownDuration marks a boot phase whose timestamp is missing
+const unknownDuration = time.Duration(-1)
+
+// vmBootTiming is the time since a VM creation to each of its boot phases, and the node it booted on
+type vmBootTiming struct {
+	vmName         string
+	node           string
+	dvReady        time.Duration
+	scheduled      time.Duration
+	running        time.Duration
+	agentConnected time.Duration
+}
+
+// getVMBootTiming reads the boot phases timestamps of a booted VM, falling back to the local creation time when the VM
+// has no creation timestamp. When they cannot be read, the timing is returned with unknown durations along with the error.
+func (c *Checkup) getVMBootTiming(ctx context.Context, vm *kvcorev1.VirtualMachine, start time.Time) (vmBootTiming, error) {
+	created := vm.CreationTimestamp.Time
+	if created.IsZero() {
+		created = start
+	}
+
+	timing := vmBootTiming{
+		vmName:         vm.Name,
+		dvReady:        unknownDuration,
+		scheduled:      unknownDuration,
+		running:        unknownDuration,
+		agentConnected: unknownDuration,
+	}
+	vmi, err := c.client.GetVirtualMachineInstance(ctx, c.namespace, vm.Name)
+	if err != nil {
+		return timing, err
+	}
+	timing.node = vmi.Status.NodeName
+	for _, transition := range vmi.Status.PhaseTransitionTimestamps {
+		switch transition.Phase {
+		case kvcorev1.Scheduled:
+			timing.scheduled = sinceCreation(created, transition.PhaseTransitionTimestamp)
+		case kvcorev1.Running:
+			timing.running = sinceCreation(created, transition.PhaseTransitionTimestamp)
+		}
+	}
+	for _, condition := range vmi.Status.Conditions {
+		if condition.Type == kvcorev1.VirtualMachineInstanceAgentConnected && condition.Status == corev1.ConditionTrue {
+			timing.agentConnected = sinceCreation(created, condition.LastTransitionTime)
+		}
+	}
+
+	// the VM disks are ready when the last of its DataVolumes is
+	for i := range vm.Spec.DataVolumeTemplates {
+		dv, err := c.client.GetDataVolume(ctx, c.namespace, vm.Spec.DataVolumeTemplates[i].Name)
+		if err != nil {
+			return timing, err
+		}
+		for _, condition := range dv.Status.Conditions {
+			if condition.Type == cdiv1.DataVolumeReady && condition.Status == corev1.ConditionTrue {
+				if ready := sinceCreation(created, condition.LastTransitionTime); ready > timing.dvReady {
+					timing.dvReady = ready
+				}
+			}
+		}
+	}
+
+	log.Printf("VM %q booted on node %q: DV ready %s, scheduled %s, running %s, agent connected %s", timing.vmName,
+		timing.node, formatBootDuration(timing.dvReady), formatBootDuration(timing.scheduled),
+		formatBootDuration(timing.running), formatBootDuration(timing.agentConnected))
+	return timing, nil
+}
+
+func sinceCreation(created time.Time, t metav1.Time) time.Duration {
+	if t.IsZero() {
+		return unknownDuration
+	}
+	if d := t.Sub(created); d > 0 {
+		return d
+	}
+	return 0
+}
+
+func formatBootDuration(d time.Duration) string {
+	if d == unknownDuration {
+		return "unknown"
+	}
+	return d.String()
+}
+
+// bootPhases are the VM boot phases in their order, the boot completes when the guest agent connects
+var bootPhases = []struct {
+	name     string
+	duration func(*vmBootTiming) time.Duration
+}{
+	{"DV ready", func(t *vmBootTiming) time.Duration { return t.dvReady }},
+	{"scheduled", func(t *vmBootTiming) time.Duration { return t.scheduled }},
+	{"running", func(t *vmBootTiming) time.Duration { return t.running }},
+	{"agent connected", func(t *vmBootTiming) time.Duration { return t.agentConnected }},
+}
+
+// reportBootLatency reports the distribution of the time to each boot phase and of the VMs over the nodes, and fails
+// when the boot time percentiles exceed their thresholds
+func (c *Checkup) reportBootLatency(timings []vmBootTiming, errStr *string) {
+	if len(timings) == 0 {
+		return
+	}
+
+	res := formatBootLatency(timings)
+	exceeded := c.exceededBootThresholds(timings)
+	for _, line := range exceeded {
+		log.Print(line)
+		appendSep(&res, line)
+	}
+	c.results.ConcurrentVMBootLatency = res
+	if len(exceeded) != 0 {
+		appendSep(errStr, ErrBootTimeThresholdExceeded)
+	}
+}
+
+func formatBootLatency(timings []vmBootTiming) string {
+	var res string
+	for _, phase := range bootPhases {
+		durations := sortedPhaseDurations(timings, phase.duration)
+		appendSep(&res, fmt.Sprintf("time to %s: %s", phase.name, formatDurationDistribution(durations)))
+	}
+
+	nodeVMs := map[string]int{}
+	for i := range timings {
+		node := timings[i].node
+		if node == "" {
+			node = "unknown"
+		}
+		nodeVMs[node]++
+	}
+	var distribution []string
+	for _, node := range sortedKeys(nodeVMs) {
+		distribution = append(distribution, fmt.Sprintf("%s=%d", node, nodeVMs[node]))
+	}
+	appendSep(&res, "node distribution: "+strings.Join(distribution, ", "))
+
+	return res
+}
+
+// sortedPhaseDurations returns the known durations of a boot phase in increasing order
+func sortedPhaseDurations(timings []vmBootTiming, duration func(*vmBootTiming) time.Duration) []time.Duration {
+	var durations []time.Duration
+	for i := range timings {
+		if d := duration(&timings[i]); d != unknownDuration {
+			durations = append(durations, d)
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return durations
+}
+
+// exceededBootThresholds returns the boot time percentiles exceeding their thresholds
+func (c *Checkup) exceededBootThresholds(timings []vmBootTiming) []string {
+	bootDurations := sortedPhaseDurations(timings, bootPhases[len(bootPhases)-1].duration)
+	if len(bootDurations) == 0 {
+		return nil
+	}
+
+	var exceeded []string
+	for _, threshold := range []struct {
+		percentile float64
+		max        time.Duration
+	}{
+		{90, c.checkupConfig.BootP90Threshold},
+		{99, c.checkupConfig.BootP99Threshold},
+	} {
+		if threshold.max == 0 {
+			continue
+		}
+		if d := percentile(bootDurations, threshold.percentile); d > threshold.max {
+			exceeded = append(exceeded,
+				fmt.Sprintf("%s: p%g %s exceeds %s", ErrBootTimeThresholdExceeded, threshold.percentile, d, threshold.max))
+		}
+	}
+	return exceeded
+}
+
+func formatDurationDistribution(sorted []time.Duration) string {
+	if len(sorted) == 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("min %s, p50 %s, p90 %s, p99 %s, max %s (%d VMs)", sorted[0], percentile(sorted, 50),
+		percentile(sorted, 90), percentile(sorted, 99), sorted[len(sorted)-1], len(sorted))
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,87 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// checkConcurrentVMIBootRamp boots and tears down waves of doubling size up to numOfVMs, until a wave has too many
+// failed VMs or exceeds the boot time thresholds, and reports the largest wave which met them and the latency curve
+func (c *Checkup) checkConcurrentVMIBootRamp(ctx context.Context, errStr *string) {
+	log.Printf("checkConcurrentVMIBootRamp initialVMs:%d maxFailedVMs:%d", c.checkupConfig.BootRampInitialVMs,
+		c.checkupConfig.BootRampMaxFailedVMs)
+
+	var curve string
+	largestWave := 0
+	var largestWaveTimings []vmBootTiming
+	for _, size := range rampWaveSizes(c.checkupConfig.BootRampInitialVMs, c.checkupConfig.NumOfVMs) {
+		timings, failures := c.bootVMsConcurrently(ctx, size, true)
+		failed := len(failures)
+		bootDurations := sortedPhaseDurations(timings, bootPhases[len(bootPhases)-1].duration)
+		wave := fmt.Sprintf("wave of %d VMs: %d failed, time to agent connected: %s", size, failed,
+			formatDurationDistribution(bootDurations))
+		exceeded := c.exceededBootThresholds(timings)
+		if failed > 0 {
+			for _, failure := range strings.Split(formatBootFailures(failures), "\n") {
+				appendSep(&c.results.ConcurrentVMBootFailures, fmt.Sprintf("wave of %d VMs: %s", size, failure))
+			}
+		}
+		if failed > c.checkupConfig.BootRampMaxFailedVMs {
+			exceeded = append(exceeded, fmt.Sprintf("%d failed VMs exceed %d", failed, c.checkupConfig.BootRampMaxFailedVMs))
+		}
+		if len(exceeded) > 0 {
+			wave += "\n" + strings.Join(exceeded, "\n")
+		}
+		log.Print(wave)
+		appendSep(&curve, wave)
+		if len(exceeded) > 0 {
+			break
+		}
+		largestWave = size
+		largestWaveTimings = timings
+	}
+	c.results.ConcurrentVMBootRamp = curve
+
+	if largestWave == 0 {
+		log.Print(ErrNoBootRampWaveMetSLO)
+		c.results.ConcurrentVMBoot = ErrNoBootRampWaveMetSLO
+		appendSep(errStr, ErrNoBootRampWaveMetSLO)
+		return
+	}
+	if len(largestWaveTimings) > 0 {
+		c.results.ConcurrentVMBootLatency = formatBootLatency(largestWaveTimings)
+	}
+	res := fmt.Sprintf("largest concurrent boot wave meeting the SLO: %d VMs", largestWave)
+	log.Print(res)
+	c.results.ConcurrentVMBoot = res
+}
+
+// rampWaveSizes returns the wave sizes doubling from initial up to numOfVMs, which is always the last one
+func rampWaveSizes(initial, numOfVMs int) []int {
+	var sizes []int
+	for size := initial; size < numOfVMs; size *= 2 {
+		sizes = append(sizes, size)
+	}
+	return append(sizes, numOfVMs)
+}
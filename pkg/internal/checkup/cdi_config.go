@@ -0,0 +1,267 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+const (
+	featureGateHonorWaitForFirstConsumer = "HonorWaitForFirstConsumer"
+
+	ErrCDIConfigIssues     = "there are CDI settings known to break VM provisioning"
+	MessageSkipNoCDIConfig = "Skip check - no CDIConfig"
+)
+
+// checkCDIConfig reports the CDI settings VM provisioning depends on, and flags the ones known to break it
+func (c *Checkup) checkCDIConfig(ctx context.Context, scs *storagev1.StorageClassList, sps *cdiv1.StorageProfileList,
+	errStr *string) error {
+	log.Print("checkCDIConfig")
+
+	cdiConfig, err := c.client.GetCDIConfig(ctx)
+	if err != nil {
+		if ignoreNotFound(err) != nil {
+			return err
+		}
+		log.Print(MessageSkipNoCDIConfig)
+		c.results.CDIConfig = MessageSkipNoCDIConfig
+		return nil
+	}
+
+	// The CDI CR is the source of truth of the spec, which the CDI operator copies to the CDIConfig
+	spec := &cdiConfig.Spec
+	cdis, err := c.client.ListCDIs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(cdis.Items) != 0 && cdis.Items[0].Spec.Config != nil {
+		spec = cdis.Items[0].Spec.Config
+	}
+
+	var config, issues string
+	c.checkScratchSpace(cdiConfig.Status.ScratchSpaceStorageClass, scs, sps, &config, &issues)
+	checkFilesystemOverhead(cdiConfig.Status.FilesystemOverhead, &config, &issues)
+	checkFeatureGates(spec.FeatureGates, scs, &config, &issues)
+	c.checkPodResourceRequirements(ctx, cdiConfig.Status.DefaultPodResourceRequirements, &config, &issues)
+	if ttl := spec.DataVolumeTTLSeconds; ttl != nil && *ttl >= 0 {
+		appendSep(&config, fmt.Sprintf("dataVolumeTTLSeconds: %d, completed DataVolumes are garbage collected", *ttl))
+	} else {
+		appendSep(&config, "dataVolumeTTLSeconds: unset, completed DataVolumes are kept")
+	}
+
+	log.Print(config)
+	c.results.CDIConfig = config
+	if issues != "" {
+		log.Print(issues)
+		c.results.CDIConfigIssues = issues
+		appendSep(errStr, ErrCDIConfigIssues)
+	}
+
+	return nil
+}
+
+func (c *Checkup) checkScratchSpace(scratchSc string, scs *storagev1.StorageClassList, sps *cdiv1.StorageProfileList,
+	config, issues *string) {
+	if scratchSc == "" {
+		appendSep(config, "scratchSpaceStorageClass: unset, the storage class of the DataVolume is used")
+		return
+	}
+	appendSep(config, "scratchSpaceStorageClass: "+scratchSc)
+
+	found := false
+	for i := range scs.Items {
+		if scs.Items[i].Name == scratchSc {
+			found = true
+			break
+		}
+	}
+	if !found {
+		appendSep(issues, fmt.Sprintf("scratchSpaceStorageClass %q does not exist, "+
+			"imports and uploads needing scratch space never complete", scratchSc))
+		return
+	}
+
+	// Scratch space is always a Filesystem volume
+	for i := range sps.Items {
+		sp := &sps.Items[i]
+		if sc := sp.Status.StorageClass; sc == nil || *sc != scratchSc || len(sp.Status.ClaimPropertySets) == 0 {
+			continue
+		}
+		for _, cps := range sp.Status.ClaimPropertySets {
+			if cps.VolumeMode == nil || *cps.VolumeMode == corev1.PersistentVolumeFilesystem {
+				return
+			}
+		}
+		appendSep(issues, fmt.Sprintf("scratchSpaceStorageClass %q supports only Block volume mode, "+
+			"while scratch space requires Filesystem", scratchSc))
+	}
+}
+
+func checkFilesystemOverhead(overhead *cdiv1.FilesystemOverhead, config, issues *string) {
+	if overhead == nil {
+		appendSep(config, "filesystemOverhead: unset")
+		return
+	}
+
+	res := "filesystemOverhead: global " + string(overhead.Global)
+	checkOverheadPercent("global", overhead.Global, issues)
+	scs := make([]string, 0, len(overhead.StorageClass))
+	for sc := range overhead.StorageClass {
+		scs = append(scs, sc)
+	}
+	sort.Strings(scs)
+	for _, sc := range scs {
+		res += fmt.Sprintf(", %s %s", sc, overhead.StorageClass[sc])
+		checkOverheadPercent(fmt.Sprintf("storage class %q", sc), overhead.StorageClass[sc], issues)
+	}
+	appendSep(config, res)
+}
+
+func checkOverheadPercent(name string, percent cdiv1.Percent, issues *string) {
+	if percent == "" {
+		return
+	}
+	value, err := strconv.ParseFloat(string(percent), 64)
+	if err != nil || value < 0 || value >= 1 {
+		appendSep(issues, fmt.Sprintf("filesystemOverhead of %s is %q, while it should be a fraction in [0, 1)",
+			name, percent))
+	}
+}
+
+func checkFeatureGates(featureGates []string, scs *storagev1.StorageClassList, config, issues *string) {
+	if len(featureGates) == 0 {
+		appendSep(config, "featureGates: none")
+	} else {
+		appendSep(config, "featureGates: "+strings.Join(featureGates, ", "))
+	}
+
+	if contains(featureGates, featureGateHonorWaitForFirstConsumer) {
+		return
+	}
+	wffcScs := ""
+	for i := range scs.Items {
+		sc := &scs.Items[i]
+		if sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+			if wffcScs != "" {
+				wffcScs += ", "
+			}
+			wffcScs += sc.Name
+		}
+	}
+	if wffcScs != "" {
+		appendSep(issues, fmt.Sprintf("featureGate %s is disabled, so DataVolumes on WaitForFirstConsumer storage "+
+			"classes (%s) are bound by CDI pods to nodes VMs may not be scheduled on", featureGateHonorWaitForFirstConsumer,
+			wffcScs))
+	}
+}
+
+// checkPodResourceRequirements checks the importer, uploader and cloner pods resources against the resource quotas of
+// the namespaces the checkup provisions VM disks in, reporting the namespaces whose quotas cannot be listed
+func (c *Checkup) checkPodResourceRequirements(ctx context.Context, requirements *corev1.ResourceRequirements,
+	config, issues *string) {
+	if requirements == nil {
+		requirements = &corev1.ResourceRequirements{}
+	}
+	appendSep(config, fmt.Sprintf("podResourceRequirements: requests %s, limits %s",
+		formatResourceList(requirements.Requests), formatResourceList(requirements.Limits)))
+
+	namespaces := []string{c.namespace}
+	if ns := c.getGoldenImagesNamespace(); ns != "" && ns != c.namespace {
+		namespaces = append(namespaces, ns)
+	}
+	for _, ns := range namespaces {
+		quotas, err := c.client.ListResourceQuotas(ctx, ns)
+		if err != nil {
+			appendSep(config, fmt.Sprintf("resourceQuotas of namespace %q not checked: %v", ns, err))
+			continue
+		}
+		for i := range quotas.Items {
+			for _, issue := range checkResourceQuota(&quotas.Items[i], requirements) {
+				appendSep(issues, issue)
+			}
+		}
+	}
+}
+
+func checkResourceQuota(quota *corev1.ResourceQuota, requirements *corev1.ResourceRequirements) []string {
+	var issues []string
+	quotaResources := []struct {
+		name     corev1.ResourceName
+		resource corev1.ResourceName
+		values   corev1.ResourceList
+	}{
+		{corev1.ResourceRequestsCPU, corev1.ResourceCPU, requirements.Requests},
+		{corev1.ResourceRequestsMemory, corev1.ResourceMemory, requirements.Requests},
+		{corev1.ResourceCPU, corev1.ResourceCPU, requirements.Requests},
+		{corev1.ResourceMemory, corev1.ResourceMemory, requirements.Requests},
+		{corev1.ResourceLimitsCPU, corev1.ResourceCPU, requirements.Limits},
+		{corev1.ResourceLimitsMemory, corev1.ResourceMemory, requirements.Limits},
+	}
+
+	for _, qr := range quotaResources {
+		hard, ok := quota.Spec.Hard[qr.name]
+		if !ok {
+			continue
+		}
+		value, ok := qr.values[qr.resource]
+		if !ok {
+			issues = append(issues, fmt.Sprintf("ResourceQuota %s/%s constrains %s, which CDI pods do not set, "+
+				"so they are rejected", quota.Namespace, quota.Name, qr.name))
+			continue
+		}
+		left := hard.DeepCopy()
+		if used, ok := quota.Status.Used[qr.name]; ok {
+			left.Sub(used)
+		}
+		if value.Cmp(left) > 0 {
+			issues = append(issues, fmt.Sprintf("ResourceQuota %s/%s has %s %s left, while CDI pods need %s",
+				quota.Namespace, quota.Name, left.String(), qr.name, value.String()))
+		}
+	}
+
+	return issues
+}
+
+func formatResourceList(resources corev1.ResourceList) string {
+	if len(resources) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	res := make([]string, 0, len(names))
+	for _, name := range names {
+		quantity := resources[corev1.ResourceName(name)]
+		res = append(res, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	return strings.Join(res, " ")
+}
@@ -29,11 +29,13 @@ import (
 	"sync"
 	"time"
 
+	authv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -46,37 +48,79 @@ import (
 	configv1 "github.com/openshift/api/config/v1"
 
 	kvcorev1 "kubevirt.io/api/core/v1"
+	exportv1 "kubevirt.io/api/export/v1alpha1"
 	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	cdiuploadv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/upload/v1beta1"
 )
 
 type kubeVirtStorageClient interface {
 	CreateVirtualMachine(ctx context.Context, namespace string, vm *kvcorev1.VirtualMachine) (*kvcorev1.VirtualMachine, error)
 	DeleteVirtualMachine(ctx context.Context, namespace, name string) error
+	PatchVirtualMachine(ctx context.Context, namespace, name string, patch []byte) error
 	GetVirtualMachineInstance(ctx context.Context, namespace, name string) (*kvcorev1.VirtualMachineInstance, error)
 	CreateVirtualMachineInstanceMigration(ctx context.Context, namespace string,
 		vmim *kvcorev1.VirtualMachineInstanceMigration) (*kvcorev1.VirtualMachineInstanceMigration, error)
+	CreateUnstructuredVirtualMachineInstanceMigration(ctx context.Context, namespace string,
+		vmim *unstructured.Unstructured) (*unstructured.Unstructured, error)
 	AddVirtualMachineInstanceVolume(ctx context.Context, namespace, name string, addVolumeOptions *kvcorev1.AddVolumeOptions) error
 	RemoveVirtualMachineInstanceVolume(ctx context.Context, namespace, name string,
 		removeVolumeOptions *kvcorev1.RemoveVolumeOptions) error
 	CreateDataVolume(ctx context.Context, namespace string, dv *cdiv1.DataVolume) (*cdiv1.DataVolume, error)
 	DeleteDataVolume(ctx context.Context, namespace, name string) error
+	GetDataVolume(ctx context.Context, namespace, name string) (*cdiv1.DataVolume, error)
+	CreateUploadTokenRequest(ctx context.Context, namespace string, request *cdiuploadv1.UploadTokenRequest) (
+		*cdiuploadv1.UploadTokenRequest, error)
+	CreateSubjectAccessReview(ctx context.Context, sar *authv1.SubjectAccessReview) (*authv1.SubjectAccessReview, error)
 	DeletePersistentVolumeClaim(ctx context.Context, namespace, name string) error
+	CreatePod(ctx context.Context, namespace string, pod *corev1.Pod) (*corev1.Pod, error)
+	DeletePod(ctx context.Context, namespace, name string) error
+	CreateVolumeSnapshot(ctx context.Context, namespace string, snapshot *snapshotv1.VolumeSnapshot) (*snapshotv1.VolumeSnapshot, error)
+	DeleteVolumeSnapshot(ctx context.Context, namespace, name string) error
 	ListNodes(ctx context.Context) (*corev1.NodeList, error)
 	ListNamespaces(ctx context.Context) (*corev1.NamespaceList, error)
 	ListStorageClasses(ctx context.Context) (*storagev1.StorageClassList, error)
 	ListStorageProfiles(ctx context.Context) (*cdiv1.StorageProfileList, error)
+	ListCSIDrivers(ctx context.Context) (*storagev1.CSIDriverList, error)
+	ListCSINodes(ctx context.Context) (*storagev1.CSINodeList, error)
 	ListVolumeSnapshotClasses(ctx context.Context) (*snapshotv1.VolumeSnapshotClassList, error)
 	ListDataImportCrons(ctx context.Context, namespace string) (*cdiv1.DataImportCronList, error)
+	ListServices(ctx context.Context, namespace, labelSelector string) (*corev1.ServiceList, error)
+	ListPersistentVolumeClaims(ctx context.Context, namespace, labelSelector string) (*corev1.PersistentVolumeClaimList, error)
+	ListVolumeSnapshots(ctx context.Context, namespace, labelSelector string) (*snapshotv1.VolumeSnapshotList, error)
+	ListResourceQuotas(ctx context.Context, namespace string) (*corev1.ResourceQuotaList, error)
+	ListPods(ctx context.Context, namespace, labelSelector string) (*corev1.PodList, error)
 	ListVirtualMachinesInstances(ctx context.Context, namespace string) (*kvcorev1.VirtualMachineInstanceList, error)
+	ListVirtualMachineInstanceMigrations(ctx context.Context, namespace string) (*kvcorev1.VirtualMachineInstanceMigrationList, error)
+	ListEvents(ctx context.Context, namespace string) (*corev1.EventList, error)
 	ListCDIs(ctx context.Context) (*cdiv1.CDIList, error)
 	GetNamespace(ctx context.Context, name string) (*corev1.Namespace, error)
+	GetConfigMap(ctx context.Context, namespace, name string) (*corev1.ConfigMap, error)
+	CreateConfigMap(ctx context.Context, namespace string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error)
+	UpdateConfigMap(ctx context.Context, namespace string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error)
+	GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error)
+	GetPodLogs(ctx context.Context, namespace, name, container string, tailLines int64) (string, error)
 	GetPersistentVolumeClaim(ctx context.Context, namespace, name string) (*corev1.PersistentVolumeClaim, error)
 	GetPersistentVolume(ctx context.Context, name string) (*corev1.PersistentVolume, error)
 	GetVolumeSnapshot(ctx context.Context, namespace, name string) (*snapshotv1.VolumeSnapshot, error)
+	GetVolumeSnapshotContent(ctx context.Context, name string) (*snapshotv1.VolumeSnapshotContent, error)
 	GetCSIDriver(ctx context.Context, name string) (*storagev1.CSIDriver, error)
 	GetDataSource(ctx context.Context, namespace, name string) (*cdiv1.DataSource, error)
+	GetCDIConfig(ctx context.Context) (*cdiv1.CDIConfig, error)
 	GetClusterVersion(ctx context.Context, name string) (*configv1.ClusterVersion, error)
 	GetKubeVirt(ctx context.Context, namespace, name string) (*kvcorev1.KubeVirt, error)
+	ListKubeVirts(ctx context.Context) (*kvcorev1.KubeVirtList, error)
+	GetUnstructuredKubeVirt(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error)
+	ListVirtualMachines(ctx context.Context, namespace string) (*kvcorev1.VirtualMachineList, error)
+	ListDataVolumes(ctx context.Context, namespace string) (*cdiv1.DataVolumeList, error)
+	ListPersistentVolumes(ctx context.Context) (*corev1.PersistentVolumeList, error)
+	ListVolumeAttachments(ctx context.Context) (*storagev1.VolumeAttachmentList, error)
+	GetHyperConverged(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error)
+	CreateVirtualMachineExport(ctx context.Context, namespace string, export *exportv1.VirtualMachineExport) (
+		*exportv1.VirtualMachineExport, error)
+	GetVirtualMachineExport(ctx context.Context, namespace, name string) (*exportv1.VirtualMachineExport, error)
+	DeleteVirtualMachineExport(ctx context.Context, namespace, name string) error
+	CreateSecret(ctx context.Context, namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	DeleteSecret(ctx context.Context, namespace, name string) error
 	GetKubernetesVersion() (string, error)
 }
 
@@ -89,6 +133,7 @@ const (
 
 	AnnDefaultVirtStorageClass = "storageclass.kubevirt.io/is-default-virt-class"
 	AnnDefaultStorageClass     = "storageclass.kubernetes.io/is-default-class"
+	AnnDefaultSnapshotClass    = "snapshot.storage.kubernetes.io/is-default-class"
 
 	ErrNoDefaultStorageClass         = "no default storage class"
 	ErrPvcNotBound                   = "pvc failed to bound"
@@ -102,10 +147,17 @@ const (
 	ErrGoldenImageNoDataSource       = "dataSource has no PVC or Snapshot source"
 	ErrBootFailedOnSomeVMs           = "some of the VMs failed to complete boot on time"
 	MessageBootCompletedOnAllVMs     = "Boot completed on all VMs on time"
+	ErrBootTimeThresholdExceeded     = "boot time percentile exceeds its threshold"
+	ErrNoBootRampWaveMetSLO          = "no concurrent boot wave met the boot time and failure thresholds"
 	MessageSkipNoDefaultStorageClass = "Skip check - no default storage class"
 	MessageSkipNoGoldenImage         = "Skip check - no golden image PVC or Snapshot"
 	MessageSkipNoVMI                 = "Skip check - no VMI"
+	MessageSkipPVCNotBound           = "Skip check - PVC failed to bound"
 	MessageSkipSingleNode            = "Skip check - single node"
+	MessageSkipAuditFailed           = "Skip check - failed to read the audited objects"
+
+	ErrMultipleDefaultVolumeSnapshotClasses = "there are CSI drivers with multiple default VolumeSnapshotClasses"
+	ErrInvalidStorageProfileSnapshotClass   = "there are StorageProfiles with a missing or mismatched snapshotClass"
 
 	pollInterval = 5 * time.Second
 )
@@ -127,18 +179,31 @@ type Checkup struct {
 	goldenImageScs      []string
 	goldenImagePvc      *corev1.PersistentVolumeClaim
 	goldenImageSnap     *snapshotv1.VolumeSnapshot
+	goldenImages        []goldenImage
+	pvcBound            bool
+	podIOMetrics        []ioMetric
+	cdiNamespace        string
 	vmUnderTest         *kvcorev1.VirtualMachine
+	kubeVirt            *kvcorev1.KubeVirt
+	vmRolloutStrategy   string
 	results             status.Results
 	// Platform detection fields
 	platform         platform.Type
 	platformDetector *platform.Detector
+	// Deletions of the objects of failed checks, left to the teardown for the diagnostics bundle
+	keptObjectsMutex    sync.Mutex
+	keptObjectDeletions []func(ctx context.Context)
 }
 
 type goldenImagesCheckState struct {
-	notReadyDicNames     string
-	noDataSourceDicNames string
-	fallbackPvcDefaultSC *corev1.PersistentVolumeClaim
-	fallbackPvc          *corev1.PersistentVolumeClaim
+	notReadyDicNames        string
+	noDataSourceDicNames    string
+	outdatedDics            string
+	missedScheduleDics      string
+	unevaluatedScheduleDics string
+	excessImportsDics       string
+	fallbackPvcDefaultSC    *corev1.PersistentVolumeClaim
+	fallbackPvc             *corev1.PersistentVolumeClaim
 }
 
 func New(client kubeVirtStorageClient, namespace string, checkupConfig config.Config) *Checkup {
@@ -154,12 +219,21 @@ func (c *Checkup) Setup(ctx context.Context) error {
 	return nil
 }
 
-func (c *Checkup) Run(ctx context.Context) error {
+func (c *Checkup) Run(ctx context.Context) (runErr error) {
 	errStr := ""
 
+	defer func() {
+		if runErr != nil {
+			c.collectDiagnostics()
+		}
+	}()
+
 	if err := c.checkVersions(ctx); err != nil {
 		return err
 	}
+	if err := c.checkKubeVirtConfig(ctx); err != nil {
+		return err
+	}
 
 	scs, err := c.client.ListStorageClasses(ctx)
 	if err != nil {
@@ -171,6 +245,15 @@ func (c *Checkup) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if err := c.checkPodIOBaseline(ctx, &errStr); err != nil {
+		return err
+	}
+	if err := c.checkUploadProxy(ctx, &errStr); err != nil {
+		return err
+	}
+	if err := c.checkImport(ctx, &errStr); err != nil {
+		return err
+	}
 
 	sps, err := c.client.ListStorageProfiles(ctx)
 	if err != nil {
@@ -183,7 +266,22 @@ func (c *Checkup) Run(ctx context.Context) error {
 	}
 
 	c.checkStorageProfiles(ctx, sps, vscs, &errStr)
+	if err := c.checkCSIDrivers(ctx, sps, &errStr); err != nil {
+		auditFailed(&c.results.CSIDriverCapabilities, err)
+	}
+	if err := c.checkCSINodeCoverage(ctx, scs, &errStr); err != nil {
+		auditFailed(&c.results.CSINodeCoverage, err)
+	}
+	if err := c.checkCDIConfig(ctx, scs, sps, &errStr); err != nil {
+		auditFailed(&c.results.CDIConfig, err)
+	}
+	if err := c.checkHyperConverged(ctx, scs, &errStr); err != nil {
+		auditFailed(&c.results.HyperConverged, err)
+	}
 	c.checkVolumeSnapShotClasses(sps, vscs, &errStr)
+	if err := c.checkVolumeSnapshotRoundTrip(ctx, sps, vscs, &errStr); err != nil {
+		return err
+	}
 
 	nss, err := c.client.ListNamespaces(ctx)
 	if err != nil {
@@ -193,24 +291,51 @@ func (c *Checkup) Run(ctx context.Context) error {
 	if err := c.checkGoldenImages(ctx, nss, &errStr); err != nil {
 		return err
 	}
+	if err := c.checkGoldenImageClonePermission(ctx, &errStr); err != nil {
+		return err
+	}
+	c.checkGoldenImageStorageClassDrift(ctx, sps, vscs)
 	if err := c.checkVMIs(ctx, nss, scs, &errStr); err != nil {
 		return err
 	}
+	if err := c.checkStorageHygiene(ctx, scs, &errStr); err != nil {
+		auditFailed(&c.results.UnhealthyStorageObjects, err)
+	}
 
 	if err := c.checkVMIBoot(ctx, &errStr); err != nil {
 		return err
 	}
+	if err := c.checkVolumeAttachments(ctx, &errStr); err != nil {
+		return err
+	}
+	if err := c.checkVolumeAttachLimits(ctx, scs); err != nil {
+		auditFailed(&c.results.VolumeAttachLimits, err)
+	}
 	if err := c.checkVMILiveMigration(ctx, &errStr); err != nil {
 		return err
 	}
 	if err := c.checkVMIHotplugVolume(ctx, &errStr); err != nil {
 		return err
 	}
+	if err := c.checkVMIStorageMigration(ctx, scs, &errStr); err != nil {
+		return err
+	}
+	if err := c.checkVMExport(ctx, &errStr); err != nil {
+		return err
+	}
+
+	if err := c.checkVMIOBaseline(ctx, &errStr); err != nil {
+		return err
+	}
 
 	if err := c.checkConcurrentVMIBoot(ctx, &errStr); err != nil {
 		return err
 	}
 
+	if err := c.checkRemediations(ctx, scs, sps, vscs); err != nil {
+		auditFailed(&c.results.Remediations, err)
+	}
+
 	if errStr != "" {
 		return errors.New(errStr)
 	}
@@ -416,6 +541,18 @@ func (c *Checkup) checkGoldenImages(ctx context.Context, namespaces *corev1.Name
 		c.results.GoldenImagesNoDataSource = cs.noDataSourceDicNames
 		appendSep(errStr, ErrGoldenImageNoDataSource)
 	}
+	if cs.outdatedDics != "" {
+		c.results.GoldenImagesOutdated = cs.outdatedDics
+		appendSep(errStr, ErrGoldenImagesOutdated)
+	}
+	if cs.missedScheduleDics != "" {
+		c.results.DataImportCronsMissedSchedule = cs.missedScheduleDics
+		appendSep(errStr, ErrDataImportCronsMissedSchedule)
+	}
+	if cs.unevaluatedScheduleDics != "" {
+		appendSep(&c.results.DataImportCronsMissedSchedule, cs.unevaluatedScheduleDics)
+	}
+	c.results.DataImportCronsExcessImports = cs.excessImportsDics
 	return nil
 }
 
@@ -460,6 +597,10 @@ func (c *Checkup) checkDataImportCrons(ctx context.Context, namespace string, cs
 
 	for i := range dics.Items {
 		dic := &dics.Items[i]
+		if err := c.checkDataImportCronHistory(ctx, dic, cs); err != nil {
+			return err
+		}
+
 		pvc, snap, err := c.getGoldenImage(ctx, dic)
 		if err != nil {
 			if err.Error() == ErrGoldenImageNoDataSource {
@@ -472,6 +613,7 @@ func (c *Checkup) checkDataImportCrons(ctx context.Context, namespace string, cs
 			return err
 		}
 
+		c.goldenImages = append(c.goldenImages, goldenImage{dic: dic, pvc: pvc, snap: snap})
 		c.updateGoldenImageSnapshot(snap)
 		c.updateGoldenImagePvc(pvc, cs)
 	}
@@ -609,9 +751,32 @@ func (c *Checkup) checkPVCCreationAndBinding(ctx context.Context, errStr *string
 		return nil
 	}
 
+	dv := c.newBlankDataVolume(pvcName, "10Mi")
+	if sc := c.checkupConfig.StorageClass; sc != "" {
+		log.Printf("PVC storage class %q", sc)
+	}
+
+	checkFailed := failedSince(errStr)
+	if _, err := c.client.CreateDataVolume(ctx, c.namespace, dv); err != nil {
+		return err
+	}
+
+	c.pvcBound = c.waitForPVCBound(ctx, pvcName, &c.results.PVCBound, errStr)
+
+	var err error
+	c.deleteUnlessFailed(ctx, checkFailed, func(ctx context.Context) {
+		if err = c.client.DeleteDataVolume(ctx, c.namespace, pvcName); err != nil {
+			log.Printf("failed to delete DataVolume %q: %s", pvcName, err)
+		}
+	})
+	return err
+}
+
+// newBlankDataVolume returns a blank DataVolume owned by the checkup pod, on the configured storage class if set
+func (c *Checkup) newBlankDataVolume(name, size string) *cdiv1.DataVolume {
 	dv := &cdiv1.DataVolume{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: pvcName,
+			Name: name,
 			OwnerReferences: []metav1.OwnerReference{{
 				APIVersion: "v1",
 				Kind:       "Pod",
@@ -626,7 +791,7 @@ func (c *Checkup) checkPVCCreationAndBinding(ctx context.Context, errStr *string
 			Storage: &cdiv1.StorageSpec{
 				Resources: corev1.ResourceRequirements{
 					Requests: corev1.ResourceList{
-						corev1.ResourceStorage: resource.MustParse("10Mi"),
+						corev1.ResourceStorage: resource.MustParse(size),
 					},
 				},
 			},
@@ -637,22 +802,32 @@ func (c *Checkup) checkPVCCreationAndBinding(ctx context.Context, errStr *string
 	}
 
 	if sc := c.checkupConfig.StorageClass; sc != "" {
-		log.Printf("PVC storage class %q", sc)
 		dv.Spec.Storage.StorageClassName = &sc
 	}
 
-	if _, err := c.client.CreateDataVolume(ctx, c.namespace, dv); err != nil {
-		return err
-	}
+	return dv
+}
 
-	c.waitForPVCBound(ctx, &c.results.PVCBound, errStr)
+func (c *Checkup) waitForPVCBound(ctx context.Context, pvcName string, result, errStr *string) bool {
+	if _, err := c.pollPVCBound(ctx, pvcName, time.Minute); err != nil {
+		log.Printf("PVC %q failed to bound", pvcName)
+		appendSep(result, ErrPvcNotBound)
+		appendSep(errStr, ErrPvcNotBound)
+		return false
+	}
 
-	return c.client.DeleteDataVolume(ctx, c.namespace, pvcName)
+	res := fmt.Sprintf("PVC %q bound", pvcName)
+	log.Print(res)
+	appendSep(result, res)
+	return true
 }
 
-func (c *Checkup) waitForPVCBound(ctx context.Context, result, errStr *string) {
+// pollPVCBound returns the PVC once it is bound, or ErrPvcNotBound when it is not bound within the timeout
+func (c *Checkup) pollPVCBound(ctx context.Context, pvcName string, timeout time.Duration) (*corev1.PersistentVolumeClaim, error) {
+	var pvc *corev1.PersistentVolumeClaim
 	conditionFn := func(ctx context.Context) (bool, error) {
-		pvc, err := c.client.GetPersistentVolumeClaim(ctx, c.namespace, pvcName)
+		var err error
+		pvc, err = c.client.GetPersistentVolumeClaim(ctx, c.namespace, pvcName)
 		if err != nil {
 			return false, ignoreNotFound(err)
 		}
@@ -660,16 +835,11 @@ func (c *Checkup) waitForPVCBound(ctx context.Context, result, errStr *string) {
 	}
 
 	log.Printf("Waiting for PVC %q bound", pvcName)
-	if err := wait.PollImmediateWithContext(ctx, pollInterval, time.Minute, conditionFn); err != nil {
-		log.Printf("PVC %q failed to bound", pvcName)
-		appendSep(result, ErrPvcNotBound)
-		appendSep(errStr, ErrPvcNotBound)
-		return
+	if err := wait.PollImmediateWithContext(ctx, pollInterval, timeout, conditionFn); err != nil {
+		return nil, errors.New(ErrPvcNotBound)
 	}
 
-	res := fmt.Sprintf("PVC %q bound", pvcName)
-	log.Print(res)
-	appendSep(result, res)
+	return pvc, nil
 }
 
 func (c *Checkup) hasSmartClone(ctx context.Context, sp *cdiv1.StorageProfile, vscs *snapshotv1.VolumeSnapshotClassList) bool {
@@ -757,25 +927,92 @@ func hasRWX(cpSets []cdiv1.ClaimPropertySet) bool {
 	return false
 }
 
-func (c *Checkup) checkVolumeSnapShotClasses(sps *cdiv1.StorageProfileList, vscs *snapshotv1.VolumeSnapshotClassList, _ *string) {
+func (c *Checkup) checkVolumeSnapShotClasses(sps *cdiv1.StorageProfileList, vscs *snapshotv1.VolumeSnapshotClassList, errStr *string) {
 	log.Print("checkVolumeSnapShotClasses")
 
 	spNames := ""
+	spNoDefaultVsc := ""
+	spInvalidVsc := ""
 	for i := range sps.Items {
 		sp := sps.Items[i]
 		strategy := sp.Status.CloneStrategy
 		provisioner := sp.Status.Provisioner
-		if (strategy == nil || *strategy == cdiv1.CloneStrategySnapshot) &&
-			provisioner != nil && !unsupportedProvisioner(*provisioner) &&
-			!hasDriver(vscs, *provisioner) {
+		if provisioner == nil || unsupportedProvisioner(*provisioner) {
+			continue
+		}
+		if (strategy == nil || *strategy == cdiv1.CloneStrategySnapshot) && !hasDriver(vscs, *provisioner) {
 			appendSep(&spNames, sp.Name)
 		}
+		if vscName := sp.Spec.SnapshotClass; vscName != nil {
+			if vsc := getVolumeSnapshotClass(vscs, *vscName); vsc == nil {
+				appendSep(&spInvalidVsc, fmt.Sprintf("%s: snapshotClass %q not found", sp.Name, *vscName))
+			} else if vsc.Driver != *provisioner {
+				appendSep(&spInvalidVsc, fmt.Sprintf("%s: snapshotClass %q driver %q does not match provisioner %q",
+					sp.Name, *vscName, vsc.Driver, *provisioner))
+			}
+		} else if hasDriver(vscs, *provisioner) && len(defaultVolumeSnapshotClasses(vscs, *provisioner)) == 0 {
+			appendSep(&spNoDefaultVsc, sp.Name)
+		}
 	}
 	if spNames != "" {
 		c.results.StorageProfileMissingVolumeSnapshotClass = spNames
 		// FIXME: not sure the checkup should fail on this one
 		// appendSep(errStr, errMissingVolumeSnapshotClass)
 	}
+	if spNoDefaultVsc != "" {
+		c.results.StorageProfilesNoDefaultSnapshotClass = spNoDefaultVsc
+	}
+	if spInvalidVsc != "" {
+		c.results.StorageProfilesInvalidSnapshotClass = spInvalidVsc
+		appendSep(errStr, ErrInvalidStorageProfileSnapshotClass)
+	}
+
+	c.checkVolumeSnapshotClassPolicies(vscs, errStr)
+}
+
+func (c *Checkup) checkVolumeSnapshotClassPolicies(vscs *snapshotv1.VolumeSnapshotClassList, errStr *string) {
+	multipleDefaults := ""
+	retainPolicy := ""
+	drivers := map[string]struct{}{}
+	for i := range vscs.Items {
+		vsc := vscs.Items[i]
+		if _, checked := drivers[vsc.Driver]; !checked {
+			drivers[vsc.Driver] = struct{}{}
+			if defaults := defaultVolumeSnapshotClasses(vscs, vsc.Driver); len(defaults) > 1 {
+				appendSep(&multipleDefaults, fmt.Sprintf("%s: %s", vsc.Driver, strings.Join(defaults, ", ")))
+			}
+		}
+		if vsc.DeletionPolicy == snapshotv1.VolumeSnapshotContentRetain {
+			appendSep(&retainPolicy, vsc.Name)
+		}
+	}
+	if multipleDefaults != "" {
+		c.results.VolumeSnapshotClassesMultipleDefault = multipleDefaults
+		appendSep(errStr, ErrMultipleDefaultVolumeSnapshotClasses)
+	}
+	if retainPolicy != "" {
+		c.results.VolumeSnapshotClassesRetainPolicy = retainPolicy
+	}
+}
+
+func getVolumeSnapshotClass(vscs *snapshotv1.VolumeSnapshotClassList, name string) *snapshotv1.VolumeSnapshotClass {
+	for i := range vscs.Items {
+		if vscs.Items[i].Name == name {
+			return &vscs.Items[i]
+		}
+	}
+	return nil
+}
+
+func defaultVolumeSnapshotClasses(vscs *snapshotv1.VolumeSnapshotClassList, driver string) []string {
+	var names []string
+	for i := range vscs.Items {
+		vsc := vscs.Items[i]
+		if vsc.Driver == driver && vsc.Annotations[AnnDefaultSnapshotClass] == StrTrue {
+			names = append(names, vsc.Name)
+		}
+	}
+	return names
 }
 
 func unsupportedProvisioner(provisioner string) bool {
@@ -954,6 +1191,8 @@ func pvHasUnsetEfsStorageClass(pv *corev1.PersistentVolume, unsetEfsSC *string)
 }
 
 func (c *Checkup) Teardown(ctx context.Context) error {
+	c.deleteKeptObjects(ctx)
+
 	if c.vmUnderTest == nil {
 		return nil
 	}
@@ -970,6 +1209,11 @@ func (c *Checkup) Teardown(ctx context.Context) error {
 		return fmt.Errorf("teardown: %v", err)
 	}
 
+	migratedDvName := getStorageMigrationDvName(c.vmUnderTest.Name)
+	if err := c.client.DeleteDataVolume(ctx, c.namespace, migratedDvName); ignoreNotFound(err) != nil {
+		return fmt.Errorf("teardown: %v", err)
+	}
+
 	return nil
 }
 
@@ -1060,10 +1304,22 @@ func (c *Checkup) checkVMILiveMigration(ctx context.Context, errStr *string) err
 		if condition.Type == kvcorev1.VirtualMachineInstanceIsMigratable && condition.Status == corev1.ConditionFalse {
 			log.Print(condition.Message)
 			c.results.VMLiveMigration = condition.Message
+			if condition.Reason == kvcorev1.VirtualMachineInstanceReasonDisksNotMigratable {
+				advice, err := c.adviseMigratability(ctx)
+				if err != nil {
+					return err
+				}
+				log.Print(advice)
+				c.results.VMLiveMigrationAdvice = advice
+			}
 			return nil
 		}
 	}
 
+	if c.isTargetedLiveMigration() {
+		return c.checkVMILiveMigrationMatrix(ctx, vmi, nodes, errStr)
+	}
+
 	vmim := &kvcorev1.VirtualMachineInstanceMigration{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       kvcorev1.VirtualMachineInstanceGroupVersionKind.Kind,
@@ -1108,6 +1364,12 @@ func (c *Checkup) checkVMIHotplugVolume(ctx context.Context, errStr *string) err
 		return nil
 	}
 
+	if !c.isFeatureGateEnabled(FeatureGateHotplugVolumes) {
+		c.results.VMHotplugVolume = fmt.Sprintf(MessageSkipFeatureGateDisabled, FeatureGateHotplugVolumes)
+		log.Print(c.results.VMHotplugVolume)
+		return nil
+	}
+
 	dv := &cdiv1.DataVolume{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: hotplugVolumeName,
@@ -1201,8 +1463,33 @@ func (c *Checkup) checkConcurrentVMIBoot(ctx context.Context, errStr *string) er
 		return nil
 	}
 
+	if c.checkupConfig.BootRamp {
+		c.checkConcurrentVMIBootRamp(ctx, errStr)
+		return nil
+	}
+
+	timings, failures := c.bootVMsConcurrently(ctx, numOfVMs, false)
+	c.reportBootLatency(timings, errStr)
+	if len(failures) > 0 {
+		log.Print(ErrBootFailedOnSomeVMs)
+		c.results.ConcurrentVMBoot = ErrBootFailedOnSomeVMs
+		c.results.ConcurrentVMBootFailures = formatBootFailures(failures)
+		appendSep(errStr, ErrBootFailedOnSomeVMs)
+		return nil
+	}
+
+	log.Print(MessageBootCompletedOnAllVMs)
+	c.results.ConcurrentVMBoot = MessageBootCompletedOnAllVMs
+
+	return nil
+}
+
+// bootVMsConcurrently boots numOfVMs VMs at once and deletes them, optionally waiting for their VMIs deletion, returning
+// the boot timings and the diagnosis of the VMs which failed to boot
+func (c *Checkup) bootVMsConcurrently(ctx context.Context, numOfVMs int, waitForDeletion bool) (
+	timings []vmBootTiming, failures []vmBootFailure) {
 	var wg sync.WaitGroup
-	isBootOk := true
+	var mutex sync.Mutex
 
 	for i := 0; i < numOfVMs; i++ {
 		wg.Add(1)
@@ -1212,38 +1499,54 @@ func (c *Checkup) checkConcurrentVMIBoot(ctx context.Context, errStr *string) er
 			vmName := uniqueVMName()
 			log.Printf("Creating VM %q", vmName)
 			vm := newVMUnderTest(vmName, c.goldenImagePvc, c.goldenImageSnap, c.checkupConfig, true)
-			if _, err := c.client.CreateVirtualMachine(ctx, c.namespace, vm); err != nil {
+			start := time.Now()
+			createdVM, err := c.client.CreateVirtualMachine(ctx, c.namespace, vm)
+			if err != nil {
 				log.Printf("failed to create VM %q: %s", vmName, err)
-				isBootOk = false
+				mutex.Lock()
+				failures = append(failures, vmBootFailure{vmName: vmName, phase: bootPhaseVMCreation, details: []string{err.Error()}})
+				mutex.Unlock()
 				return
 			}
 
-			defer func() {
+			// A ramp wave's VMs, the failed ones included, are deleted before the next wave, so they do not skew it
+			bootFailed := false
+			defer c.deleteUnlessFailed(ctx, func() bool { return bootFailed && !waitForDeletion }, func(ctx context.Context) {
 				if err := c.client.DeleteVirtualMachine(ctx, c.namespace, vmName); err != nil {
 					log.Printf("failed to delete VM %q: %s", vmName, err)
+					return
+				}
+				if waitForDeletion {
+					if err := c.waitForVMIDeleted(ctx, vmName); err != nil {
+						log.Printf("failed waiting for VMI %q deletion: %s", vmName, err)
+					}
 				}
-			}()
+			})
 
 			var result, errs string
 			if err := c.waitForVMIBoot(ctx, vmName, &result, &errs); err != nil || errs != "" {
-				log.Printf("failed waiting for VM boot %q", vmName)
-				isBootOk = false
+				bootFailed = true
+				failure := c.diagnoseVMBootFailure(ctx, createdVM)
+				log.Printf("failed waiting for VM boot %q, stalled at %s", vmName, failure.phase)
+				mutex.Lock()
+				failures = append(failures, failure)
+				mutex.Unlock()
+				return
+			}
+
+			// A booted VM whose timing cannot be read still counts, with unknown durations
+			timing, err := c.getVMBootTiming(ctx, createdVM, start)
+			if err != nil {
+				log.Printf("failed to get VM %q boot timing: %s", vmName, err)
 			}
+			mutex.Lock()
+			timings = append(timings, timing)
+			mutex.Unlock()
 		}()
 	}
 
 	wg.Wait()
-	if !isBootOk {
-		log.Print(ErrBootFailedOnSomeVMs)
-		c.results.ConcurrentVMBoot = ErrBootFailedOnSomeVMs
-		appendSep(errStr, ErrBootFailedOnSomeVMs)
-		return nil
-	}
-
-	log.Print(MessageBootCompletedOnAllVMs)
-	c.results.ConcurrentVMBoot = MessageBootCompletedOnAllVMs
-
-	return nil
+	return timings, failures
 }
 
 func (c *Checkup) waitForVMIBoot(ctx context.Context, vmName string, result, errStr *string) error {
@@ -1291,6 +1594,34 @@ func (c *Checkup) waitForVMIStatus(ctx context.Context, vmName, checkMsg string,
 	return nil
 }
 
+// restrictPod makes the pod comply with the restricted Pod Security Standard: running as non-root with the
+// RuntimeDefault seccomp profile, and its containers dropping all capabilities and disallowing privilege escalation
+func restrictPod(pod *corev1.Pod) *corev1.Pod {
+	if pod.Spec.SecurityContext == nil {
+		pod.Spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	runAsNonRoot := true
+	pod.Spec.SecurityContext.RunAsNonRoot = &runAsNonRoot
+	pod.Spec.SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+
+	for i := range pod.Spec.Containers {
+		allowPrivilegeEscalation := false
+		pod.Spec.Containers[i].SecurityContext = &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		}
+	}
+
+	return pod
+}
+
+// auditFailed records why a read-only audit could not run in its result, so it does not abort the later checks
+func auditFailed(result *string, err error) {
+	res := fmt.Sprintf("%s: %v", MessageSkipAuditFailed, err)
+	log.Print(res)
+	appendSep(result, res)
+}
+
 func appendSep(s *string, appended string) {
 	if s == nil {
 		return
@@ -20,47 +20,131 @@
 package checkup_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
 	configv1 "github.com/openshift/api/config/v1"
 	assert "github.com/stretchr/testify/require"
 
+	authv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 
 	kvcorev1 "kubevirt.io/api/core/v1"
+	exportv1 "kubevirt.io/api/export/v1alpha1"
 	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	cdiuploadv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/upload/v1beta1"
 
 	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/checkup"
 	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/config"
+	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/imageserver"
 	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/reporter"
 )
 
 const (
-	testNamespace = "target-ns"
-	testNode      = "test-node"
+	testNamespace     = "target-ns"
+	testNode          = "test-node"
+	testDiagnosticsCM = "storage-checkup-diagnostics"
 )
 
 var (
-	testVMIName    = "test-vmi"
-	testScName     = "test-sc"
-	testScName2    = "test-sc2"
-	efsSc          = "efs.csi.aws.com"
-	testDIC        = "test-dic"
-	testPodName    = "test-pod"
-	testPodUID     = "test-uid"
-	testOCPVersion = "1.2.3"
-	testCNVVersion = "4.5.6"
+	testVMIName          = "test-vmi"
+	testScName           = "test-sc"
+	testScName2          = "test-sc2"
+	testWFFCScName       = "test-sc-wffc"
+	efsSc                = "efs.csi.aws.com"
+	testRbdProvisioner   = "openshift-storage.rbd.csi.ceph.com"
+	testDIC              = "test-dic"
+	testPodName          = "test-pod"
+	testPodUID           = "test-uid"
+	testOCPVersion       = "1.2.3"
+	testCNVVersion       = "4.5.6"
+	testPodImage         = "test-image"
+	testToken            = "test-token"
+	testCDINamespace     = "openshift-cnv"
+	testCDIUID           = "test-cdi-uid"
+	testUploadProxyRoute = "cdi-uploadproxy-openshift-cnv.apps.example.com"
+	uploadProxyResult    = "Upload proxy: https://cdi-uploadproxy." + testCDINamespace + ".svc\n" +
+		"Upload proxy route: https://" + testUploadProxyRoute
+	testVMNamespace         = "vm-ns"
+	testPodIP               = "10.0.0.1"
+	testPodSA               = "storage-checkup-sa"
+	testDICURL              = "docker://quay.io/containerdisks/fedora:latest"
+	testDICDataSource       = testNamespace + "/" + testDIC + " (DataSource test-das)"
+	testGoldenImageSnapshot = "test-golden-image-snapshot"
+	testDICRef              = testNamespace + "/" + testDIC + " (source " + testDICURL + ", digest sha256:1234)"
 )
 
+// uploadProxy stands in for cdi-uploadproxy
+var uploadProxy *httptest.Server
+
+// exportServer stands in for the VirtualMachineExport server, serving a valid and an invalid raw image
+var exportServer *httptest.Server
+
+func TestMain(m *testing.M) {
+	uploadProxy = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1beta1/upload" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	uploadProxy.TLS = &tls.Config{Certificates: []tls.Certificate{newServiceCertificate("cdi-uploadproxy." + testCDINamespace + ".svc")}}
+	uploadProxy.StartTLS()
+	exportServer = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-kubevirt-export-token") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/volumes/disk.img":
+			_, _ = w.Write(imageserver.NewRawImage(imageserver.ImageSize))
+		case "/volumes/bad.img":
+			_, _ = w.Write(make([]byte, imageserver.ImageSize))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	code := m.Run()
+	uploadProxy.Close()
+	exportServer.Close()
+	os.Exit(code)
+}
+
 func TestCheckupShouldSucceed(t *testing.T) {
 	testClient := newClientStub(clientConfig{})
 	testConfig := newTestConfig()
@@ -77,6 +161,7 @@ func TestCheckupShouldSucceed(t *testing.T) {
 
 	assert.Empty(t, testClient.createdVMs)
 	assert.Empty(t, testClient.createdVMIs)
+	assert.Empty(t, testClient.createdSnapshots)
 
 	expectedResults := successfulRunResults(vmiUnderTestName)
 	actualResults := reporter.FormatResults(testCheckup.Results())
@@ -85,33 +170,51 @@ func TestCheckupShouldSucceed(t *testing.T) {
 
 var tests = map[string]struct {
 	clientConfig    clientConfig
+	checkupConfig   func(cfg *config.Config)
 	expectedResults map[string]string
 	expectedErr     string
 }{
 	"noStorageClasses": {
 		clientConfig: clientConfig{noStorageClasses: true, expectNoVMI: true},
 		expectedResults: map[string]string{
-			reporter.DefaultStorageClassKey:   checkup.ErrNoDefaultStorageClass,
-			reporter.PVCBoundKey:              checkup.MessageSkipNoDefaultStorageClass,
-			reporter.VMBootFromGoldenImageKey: checkup.MessageSkipNoDefaultStorageClass,
-			reporter.ConcurrentVMBootKey:      checkup.MessageSkipNoDefaultStorageClass,
+			reporter.CDIConfigIssuesKey: "scratchSpaceStorageClass \"test-sc\" does not exist, imports and uploads " +
+				"needing scratch space never complete",
+			reporter.HyperConvergedIssuesKey: "scratchSpaceStorageClass \"test-sc\" does not exist\n" +
+				"dataImportCronTemplate centos-stream9-image-cron storageClassName \"test-sc\" does not exist\n" +
+				"enableCommonBootImageImport is set, but there is no default storage class to import the common boot images to",
+			reporter.DefaultStorageClassKey:           checkup.ErrNoDefaultStorageClass,
+			reporter.CSINodeCoverageKey:               "",
+			reporter.PVCBoundKey:                      checkup.MessageSkipNoDefaultStorageClass,
+			reporter.PodIOBaselineKey:                 checkup.MessageSkipNoDefaultStorageClass,
+			reporter.CDIUploadKey:                     checkup.MessageSkipNoDefaultStorageClass,
+			reporter.CDIImportKey:                     checkup.MessageSkipNoDefaultStorageClass,
+			reporter.GoldenImagesStorageClassDriftKey: checkup.MessageSkipNoDefaultStorageClass,
+			reporter.VMBootFromGoldenImageKey:         checkup.MessageSkipNoDefaultStorageClass,
+			reporter.ConcurrentVMBootKey:              checkup.MessageSkipNoDefaultStorageClass,
 		},
 		expectedErr: checkup.ErrNoDefaultStorageClass,
 	},
 	"noDefaultStorageClass": {
 		clientConfig: clientConfig{noDefaultStorageClass: true, expectNoVMI: true},
 		expectedResults: map[string]string{
-			reporter.DefaultStorageClassKey:   checkup.ErrNoDefaultStorageClass,
-			reporter.PVCBoundKey:              checkup.MessageSkipNoDefaultStorageClass,
-			reporter.VMBootFromGoldenImageKey: checkup.MessageSkipNoDefaultStorageClass,
-			reporter.ConcurrentVMBootKey:      checkup.MessageSkipNoDefaultStorageClass,
+			reporter.HyperConvergedIssuesKey: "enableCommonBootImageImport is set, but there is no default storage class to import " +
+				"the common boot images to",
+			reporter.DefaultStorageClassKey:           checkup.ErrNoDefaultStorageClass,
+			reporter.PVCBoundKey:                      checkup.MessageSkipNoDefaultStorageClass,
+			reporter.PodIOBaselineKey:                 checkup.MessageSkipNoDefaultStorageClass,
+			reporter.CDIUploadKey:                     checkup.MessageSkipNoDefaultStorageClass,
+			reporter.CDIImportKey:                     checkup.MessageSkipNoDefaultStorageClass,
+			reporter.GoldenImagesStorageClassDriftKey: checkup.MessageSkipNoDefaultStorageClass,
+			reporter.VMBootFromGoldenImageKey:         checkup.MessageSkipNoDefaultStorageClass,
+			reporter.ConcurrentVMBootKey:              checkup.MessageSkipNoDefaultStorageClass,
 		},
 		expectedErr: checkup.ErrNoDefaultStorageClass,
 	},
 	"onlyDefaultStorageClass": {
-		clientConfig:    clientConfig{onlyDefaultStorageClass: true},
-		expectedResults: map[string]string{reporter.DefaultStorageClassKey: testScName2},
-		expectedErr:     "",
+		clientConfig: clientConfig{onlyDefaultStorageClass: true},
+		expectedResults: map[string]string{reporter.DefaultStorageClassKey: testScName2,
+			reporter.GoldenImagesStorageClassDriftKey: goldenImageOnOtherSC(testScName, testScName2)},
+		expectedErr: "",
 	},
 	"onlyDefaultVirtStorageClass": {
 		clientConfig:    clientConfig{onlyDefaultVirtStorageClass: true},
@@ -124,52 +227,375 @@ var tests = map[string]struct {
 		expectedErr:     "",
 	},
 	"multipleDefaultStorageClasses": {
-		clientConfig:    clientConfig{multipleDefaultStorageClasses: true},
-		expectedResults: map[string]string{reporter.DefaultStorageClassKey: checkup.ErrMultipleDefaultStorageClasses},
-		expectedErr:     checkup.ErrMultipleDefaultStorageClasses,
+		clientConfig: clientConfig{multipleDefaultStorageClasses: true},
+		expectedResults: map[string]string{reporter.DefaultStorageClassKey: checkup.ErrMultipleDefaultStorageClasses,
+			reporter.RemediationsKey: "# Finding: storage class test-sc2 is one of multiple default storage classes\n" +
+				"# keep a single default storage class: test-sc for storageclass.kubernetes.io/is-default-class\n" +
+				"# Apply: kubectl patch storageclass test-sc2 --type merge --patch-file storageclass-test-sc2.yaml\n" +
+				"metadata:\n  annotations:\n    storageclass.kubernetes.io/is-default-class: \"false\"\n"},
+		expectedErr: checkup.ErrMultipleDefaultStorageClasses,
 	},
 	"multipleDefaultVirtStorageClasses": {
-		clientConfig:    clientConfig{multipleDefaultVirtStorageClasses: true},
-		expectedResults: map[string]string{reporter.DefaultStorageClassKey: checkup.ErrMultipleDefaultStorageClasses},
-		expectedErr:     checkup.ErrMultipleDefaultStorageClasses,
+		clientConfig: clientConfig{multipleDefaultVirtStorageClasses: true},
+		expectedResults: map[string]string{reporter.DefaultStorageClassKey: checkup.ErrMultipleDefaultStorageClasses,
+			reporter.RemediationsKey: "# Finding: storage class test-sc2 is one of multiple default storage classes\n" +
+				"# keep a single default storage class: test-sc for storageclass.kubevirt.io/is-default-virt-class\n" +
+				"# Apply: kubectl patch storageclass test-sc2 --type merge --patch-file storageclass-test-sc2.yaml\n" +
+				"metadata:\n  annotations:\n    storageclass.kubevirt.io/is-default-virt-class: \"false\"\n"},
+		expectedErr: checkup.ErrMultipleDefaultStorageClasses,
 	},
 	"failPvcBound": {
-		clientConfig:    clientConfig{failPvcBound: true},
-		expectedResults: map[string]string{reporter.PVCBoundKey: checkup.ErrPvcNotBound},
-		expectedErr:     checkup.ErrPvcNotBound,
+		clientConfig: clientConfig{failPvcBound: true},
+		expectedResults: map[string]string{reporter.PVCBoundKey: checkup.ErrPvcNotBound,
+			reporter.PodIOBaselineKey: checkup.MessageSkipPVCNotBound,
+			reporter.CDIUploadKey:     checkup.MessageSkipPVCNotBound,
+			reporter.CDIImportKey:     checkup.MessageSkipPVCNotBound,
+			reporter.VolumeSnapshotRoundTripKey: testScName + ": " + checkup.ErrVolumeSnapshotRoundTripFailed +
+				": source PVC \"checkup-snapshot-src-0\": " + checkup.ErrPvcNotBound},
+		expectedErr: checkup.ErrPvcNotBound,
+	},
+	"ioBaselinePodFails": {
+		clientConfig: clientConfig{failIOBaselinePod: true},
+		expectedResults: map[string]string{
+			reporter.PodIOBaselineKey: "PVC \"checkup-io-baseline-pvc\" bound\npod I/O baseline failed: pod failed"},
+		expectedErr: checkup.ErrPodIOBaselineFailed,
+	},
+	"ioBaselineVMFails": {
+		clientConfig: clientConfig{failIOBaselineVM: true},
+		expectedResults: map[string]string{
+			reporter.PodIOBaselineKey: "PVC \"checkup-io-baseline-pvc\" bound\n" +
+				"Pod I/O baseline on storage class \"test-sc\": write 224 MB/s, read 512 MB/s\n" +
+				"failed waiting for VMI \"checkup-io-baseline-vm\" powered off after the I/O workload: VMI failed"},
+		expectedErr: "failed waiting for VMI \"checkup-io-baseline-vm\" powered off after the I/O workload: VMI failed",
 	},
 	"storageProfileIncomplete": {
 		clientConfig: clientConfig{spIncomplete: true},
 		expectedResults: map[string]string{reporter.StorageProfilesWithEmptyClaimPropertySetsKey: testScName,
-			reporter.StorageProfilesWithSpecClaimPropertySetsKey: testScName, reporter.StorageProfilesWithRWXKey: ""},
+			reporter.StorageProfilesWithSpecClaimPropertySetsKey: testScName, reporter.StorageProfilesWithRWXKey: "",
+			reporter.RemediationsKey: "# Finding: StorageProfile test-sc has empty claimPropertySets\n" +
+				"# CDI does not know provisioner \"test-sc\", set the access and volume modes it supports " +
+				"(ReadWriteMany is required for live migration)\n" +
+				"# Template, edit before applying: replace the placeholder accessModes and volumeMode with the ones " +
+				"the provisioner supports\n" +
+				"# Apply: kubectl patch storageprofile test-sc --type merge --patch-file storageprofile-test-sc.yaml\n" +
+				"spec:\n  claimPropertySets:\n  - accessModes:\n    - ReadWriteOnce\n    volumeMode: Filesystem\n"},
 		expectedErr: checkup.ErrEmptyClaimPropertySets,
 	},
+	"csiDriverNotRegistered": {
+		clientConfig: clientConfig{csiDriverNotRegistered: true},
+		expectedResults: map[string]string{
+			reporter.CSIDriverCapabilitiesKey: testScName + ": attachRequired=true, fsGroupPolicy=File, " +
+				"volumeLifecycleModes=Persistent, podInfoOnMount=false, requiresRepublish=false, registeredNodes=0",
+			reporter.StorageProfilesCSIDriverMismatchKey: testScName + ": CSIDriver \"test-sc\" is not registered on any node",
+			reporter.CSINodeCoverageKey: testScName + ": registered on 0/2 schedulable nodes, missing on node-0, node-1\n" +
+				"node-0 allocatable volumes: test-sc=none\nnode-1 allocatable volumes: test-sc=none",
+			reporter.CSINodeCoverageIssuesKey: "node-0: no CSI node plugin of \"test-sc\", " +
+				"VMs using default storage class \"test-sc\" can never start\n" +
+				"node-1: no CSI node plugin of \"test-sc\", VMs using default storage class \"test-sc\" can never start",
+			reporter.VolumeAttachLimitsKey: "room for 0 more VMs like \"%s\" within the volume attach limits",
+		},
+		expectedErr: checkup.ErrStorageProfileCSIDriverMismatch,
+	},
+	"csiNodeMissingDriver": {
+		clientConfig: clientConfig{csiNodeMissingDriver: true},
+		expectedResults: map[string]string{
+			reporter.CSIDriverCapabilitiesKey: testScName + ": attachRequired=true, fsGroupPolicy=File, " +
+				"volumeLifecycleModes=Persistent, podInfoOnMount=false, requiresRepublish=false, registeredNodes=1",
+			reporter.CSINodeCoverageKey: testScName + ": registered on 1/2 schedulable nodes, missing on node-1\n" +
+				"node-0 allocatable volumes: test-sc=39\nnode-1 allocatable volumes: test-sc=none",
+			reporter.CSINodeCoverageIssuesKey: "node-1: no CSI node plugin of \"test-sc\", " +
+				"VMs using default storage class \"test-sc\" can never start",
+			reporter.VolumeAttachLimitsKey: "node-0 test-sc: 2 attached (0 by hotplug attachment pods), limit 39, headroom 94%%\n" +
+				"room for 37 more VMs like \"%s\" within the volume attach limits",
+		},
+		expectedErr: checkup.ErrNodesWithoutDefaultCSIDriver,
+	},
+	"csiNodeMissingDriverDefaultScNotCSI": {
+		clientConfig: clientConfig{csiNodeMissingDriver: true, defaultScNotCSI: true},
+		expectedResults: map[string]string{
+			reporter.CSIDriverCapabilitiesKey: testScName + ": attachRequired=true, fsGroupPolicy=File, " +
+				"volumeLifecycleModes=Persistent, podInfoOnMount=false, requiresRepublish=false, registeredNodes=1",
+			reporter.CSINodeCoverageKey: "",
+			reporter.VolumeAttachLimitsKey: "node-0 test-sc: 2 attached (0 by hotplug attachment pods), limit 39, headroom 94%%\n" +
+				"room for 0 more VMs like \"%s\" within the volume attach limits",
+		},
+	},
+	"csiDriverFsGroupPolicyRWO": {
+		clientConfig: clientConfig{csiDriverFsGroupPolicyRWO: true},
+		expectedResults: map[string]string{
+			reporter.CSIDriverCapabilitiesKey: testScName + ": attachRequired=true, fsGroupPolicy=ReadWriteOnceWithFSType, " +
+				"volumeLifecycleModes=Persistent, podInfoOnMount=false, requiresRepublish=false, registeredNodes=2",
+			reporter.StorageProfilesFSGroupPolicyKey: testScName + ": advertises ReadWriteMany Filesystem but CSIDriver " +
+				"fsGroupPolicy ReadWriteOnceWithFSType skips fsGroup ownership changes on ReadWriteMany volumes",
+		},
+		expectedErr: "",
+	},
+	"rwoOnlyStorageClassAdvertisesRWX": {
+		checkupConfig: func(cfg *config.Config) {
+			cfg.RWOOnlyStorageClasses = []string{testScName}
+		},
+		expectedResults: map[string]string{
+			reporter.StorageProfilesCSIDriverMismatchKey: testScName + ": advertises ReadWriteMany Filesystem but storage class " +
+				"\"test-sc\" is configured as supporting ReadWriteOnce only",
+		},
+		expectedErr: checkup.ErrStorageProfileCSIDriverMismatch,
+	},
 	"noVolumeSnapshotClasses": {
 		clientConfig: clientConfig{noVolumeSnapshotClasses: true},
 		expectedResults: map[string]string{reporter.StorageProfileMissingVolumeSnapshotClassKey: testScName,
-			reporter.StorageProfilesWithSmartCloneKey: ""},
+			reporter.StorageProfilesWithSmartCloneKey: "",
+			reporter.VolumeSnapshotRoundTripKey:       checkup.MessageSkipNoSnapshotCapableStorage,
+			reporter.GoldenImagesStorageClassDriftKey: testDICDataSource + ": storage class \"test-sc\" of PVC " +
+				"target-ns/test-pvc has no smart clone, so VM disks are cloned host-assisted. Recommendation: add a " +
+				"VolumeSnapshotClass for \"test-sc\", or set cloneStrategy csi-clone in its StorageProfile if the CSI " +
+				"driver supports volume cloning",
+			reporter.RemediationsKey: "# Finding: StorageProfile test-sc clones with snapshots, " +
+				"but there is no VolumeSnapshotClass for test-sc\n" +
+				"# create a default VolumeSnapshotClass if the CSI driver supports snapshots, " +
+				"otherwise set the StorageProfile spec.cloneStrategy to csi-clone or copy\n" +
+				"# Apply: kubectl create -f volumesnapshotclass-test-sc-snapclass.yaml\n" +
+				"apiVersion: snapshot.storage.k8s.io/v1\ndeletionPolicy: Delete\ndriver: test-sc\n" +
+				"kind: VolumeSnapshotClass\nmetadata:\n  annotations:\n" +
+				"    snapshot.storage.kubernetes.io/is-default-class: \"true\"\n  name: test-sc-snapclass\n"},
+		expectedErr: "",
+	},
+	"csiNodesForbidden": {
+		clientConfig: clientConfig{csiNodesForbidden: true},
+		expectedResults: map[string]string{
+			reporter.CSIDriverCapabilitiesKey: checkup.MessageSkipAuditFailed + ": csinodes.storage.k8s.io is forbidden: " +
+				"no RBAC permission",
+			reporter.CSINodeCoverageKey:    checkup.MessageSkipAuditFailed + ": csinodes.storage.k8s.io is forbidden: no RBAC permission",
+			reporter.VolumeAttachLimitsKey: checkup.MessageSkipAuditFailed + ": csinodes.storage.k8s.io is forbidden: no RBAC permission",
+		},
+		expectedErr: "",
+	},
+	"noDefaultVolumeSnapshotClass": {
+		clientConfig:    clientConfig{noDefaultVolumeSnapshotClass: true},
+		expectedResults: map[string]string{reporter.StorageProfilesNoDefaultSnapshotClassKey: testScName},
+		expectedErr:     "",
+	},
+	"multipleDefaultVolumeSnapshotClasses": {
+		clientConfig:    clientConfig{multipleDefaultVolumeSnapshotCls: true},
+		expectedResults: map[string]string{reporter.VolumeSnapshotClassesMultipleDefaultKey: testScName + ": test-sc, test-sc-2"},
+		expectedErr:     checkup.ErrMultipleDefaultVolumeSnapshotClasses,
+	},
+	"retainVolumeSnapshotClass": {
+		clientConfig:    clientConfig{retainVolumeSnapshotClass: true},
+		expectedResults: map[string]string{reporter.VolumeSnapshotClassesRetainPolicyKey: testScName},
+		expectedErr:     "",
+	},
+	"spInvalidSnapshotClass": {
+		clientConfig: clientConfig{spInvalidSnapshotClass: true},
+		expectedResults: map[string]string{
+			reporter.StorageProfilesInvalidSnapshotClassKey: testScName + ": snapshotClass \"missing-vsc\" not found",
+		},
+		expectedErr: checkup.ErrInvalidStorageProfileSnapshotClass,
+	},
+	"uploadProxyUnknownCA": {
+		clientConfig: clientConfig{uploadProxyUnknownCA: true},
+		expectedResults: map[string]string{
+			reporter.CDIUploadKey: uploadProxyResult + "\n" +
+				checkup.ErrUploadProxyCertificate + ": x509: certificate signed by unknown authority",
+		},
+		expectedErr: checkup.ErrUploadProxyCertificate,
+	},
+	"uploadProxyUnknownCAInsecure": {
+		clientConfig:  clientConfig{uploadProxyUnknownCA: true},
+		checkupConfig: func(cfg *config.Config) { cfg.UploadProxyInsecure = true },
+		expectedResults: map[string]string{
+			reporter.CDIUploadKey: uploadProxyResult + "\n" +
+				checkup.ErrUploadProxyCertificate + ": x509: certificate signed by unknown authority\n" +
+				"DataVolume \"checkup-upload\" upload succeeded\nVMI \"checkup-upload-vm\" ready",
+		},
+		expectedErr: checkup.ErrUploadProxyCertificate,
+	},
+	"uploadProxyServiceMissing": {
+		clientConfig: clientConfig{uploadProxyServiceMissing: true},
+		expectedResults: map[string]string{
+			reporter.CDIUploadKey:      checkup.ErrUploadFailed + ": no cdi-uploadproxy service of CDI CR \"cdi\" found",
+			reporter.HyperConvergedKey: checkup.MessageSkipAuditFailed + ": no cdi-uploadproxy service of CDI CR \"cdi\" found",
+		},
+		expectedErr: checkup.ErrUploadFailed,
+	},
+	"uploadDataVolumeFails": {
+		clientConfig: clientConfig{failUploadDv: true},
+		expectedResults: map[string]string{
+			reporter.CDIUploadKey: uploadProxyResult + "\n" +
+				checkup.ErrUploadFailed + ": DataVolume \"checkup-upload\": DataVolume failed: Upload failed",
+		},
+		expectedErr: checkup.ErrUploadFailed,
+	},
+	"importDataVolumeFails": {
+		clientConfig: clientConfig{failImportDv: true},
+		expectedResults: map[string]string{
+			reporter.CDIImportKey: "Image server: http://10.0.0.1:8080\n" +
+				"DataVolume \"checkup-import-raw\" imported from disk.img\n" +
+				checkup.ErrImportFailed + ": DataVolume \"checkup-import-qcow2\" from disk.qcow2: DataVolume failed: " +
+				"Unable to process data\n" +
+				"DataVolume \"checkup-import-gz\" imported from disk.img.gz",
+		},
+		expectedErr: checkup.ErrImportFailed,
+	},
+	"volumeSnapshotNotReady": {
+		clientConfig: clientConfig{failVolumeSnapshot: true},
+		expectedResults: map[string]string{
+			reporter.VolumeSnapshotRoundTripKey: testScName + ": " + checkup.ErrVolumeSnapshotRoundTripFailed +
+				": VolumeSnapshot \"checkup-snapshot-0\": Failed to check and update snapshot content",
+		},
+		expectedErr: checkup.ErrVolumeSnapshotRoundTripFailed,
+	},
+	"cloneNotAllowed": {
+		clientConfig: clientConfig{cloneNotAllowed: true},
+		expectedResults: map[string]string{
+			reporter.GoldenImageClonePermissionKey: fmt.Sprintf("service account %q may clone from namespace %q\n"+
+				"service account %q may not clone from namespace %q: missing rule apiGroups: [\"cdi.kubevirt.io\"], "+
+				"resources: [\"datavolumes/source\"], verbs: [\"create\"] in namespace %q (no RBAC policy matched)",
+				testNamespace+"/"+testPodSA, testNamespace, testNamespace+"/default", testNamespace, testNamespace),
+		},
+		expectedErr: checkup.ErrGoldenImageClonePermission,
+	},
+	"goldenImageOutdated": {
+		clientConfig:    clientConfig{goldenImageOutdated: true},
+		expectedResults: map[string]string{reporter.GoldenImagesOutdatedKey: testDICRef + ": last import 40d ago, older than 30d"},
+		expectedErr:     checkup.ErrGoldenImagesOutdated,
+	},
+	"dicMissedSchedule": {
+		clientConfig: clientConfig{dicMissedSchedule: true},
+		expectedResults: map[string]string{reporter.DataImportCronsMissedScheduleKey: testDICRef +
+			": schedule \"0 */12 * * *\" was due 4d ago, last execution 5d ago"},
+		expectedErr: checkup.ErrDataImportCronsMissedSchedule,
+	},
+	"dicInvalidSchedule": {
+		clientConfig: clientConfig{dicInvalidSchedule: true},
+		expectedResults: map[string]string{reporter.DataImportCronsMissedScheduleKey: testDICRef + ": " +
+			checkup.MessageScheduleNotEvaluated + ": cron schedule \"@every 12h\": expected 5 fields, found 2"},
 		expectedErr: "",
 	},
+	"dicExcessImports": {
+		clientConfig: clientConfig{dicExcessImports: true},
+		expectedResults: map[string]string{reporter.DataImportCronsExcessImportsKey: testDICRef +
+			": 5 PVCs and 0 VolumeSnapshots retained, importsToKeep 3"},
+	},
+	"goldenImageSnapshotWithoutClass": {
+		clientConfig:    clientConfig{goldenImageSnapshot: true},
+		expectedResults: map[string]string{reporter.VMVolumeCloneKey: "DV cloneType: snapshot"},
+	},
+	"goldenImageOtherSC": {
+		clientConfig: clientConfig{goldenImageOtherSC: true},
+		expectedResults: map[string]string{
+			reporter.GoldenImagesStorageClassDriftKey: goldenImageOnOtherSC(testScName2, testScName),
+		},
+	},
+	"cdiMissingScratchSC": {
+		clientConfig: clientConfig{cdiMissingScratchSC: true},
+		expectedResults: map[string]string{
+			reporter.CDIConfigKey: "scratchSpaceStorageClass: missing-sc\nfilesystemOverhead: global 0.055, test-sc 0.1\n" +
+				"featureGates: HonorWaitForFirstConsumer\n" +
+				"podResourceRequirements: requests cpu=100m memory=60M, limits cpu=750m memory=600M\n" +
+				"dataVolumeTTLSeconds: unset, completed DataVolumes are kept",
+			reporter.CDIConfigIssuesKey: "scratchSpaceStorageClass \"missing-sc\" does not exist, imports and uploads " +
+				"needing scratch space never complete",
+		},
+		expectedErr: checkup.ErrCDIConfigIssues,
+	},
+	"cdiNoHonorWFFC": {
+		clientConfig: clientConfig{cdiNoHonorWFFC: true},
+		expectedResults: map[string]string{
+			reporter.CDIConfigKey: "scratchSpaceStorageClass: test-sc\nfilesystemOverhead: global 0.055, test-sc 0.1\n" +
+				"featureGates: none\n" +
+				"podResourceRequirements: requests cpu=100m memory=60M, limits cpu=750m memory=600M\n" +
+				"dataVolumeTTLSeconds: unset, completed DataVolumes are kept",
+			reporter.CDIConfigIssuesKey: "featureGate HonorWaitForFirstConsumer is disabled, so DataVolumes on " +
+				"WaitForFirstConsumer storage classes (test-sc) are bound by CDI pods to nodes VMs may not be scheduled on",
+		},
+		expectedErr: checkup.ErrCDIConfigIssues,
+	},
+	"cdiQuotaExceeded": {
+		clientConfig: clientConfig{cdiQuotaExceeded: true},
+		expectedResults: map[string]string{
+			reporter.CDIConfigIssuesKey: "ResourceQuota target-ns/compute has 500m limits.cpu left, while CDI pods need 750m",
+		},
+		expectedErr: checkup.ErrCDIConfigIssues,
+	},
+	"resourceQuotasForbidden": {
+		clientConfig: clientConfig{resourceQuotasForbidden: true},
+		expectedResults: map[string]string{
+			reporter.CDIConfigKey: "scratchSpaceStorageClass: test-sc\nfilesystemOverhead: global 0.055, test-sc 0.1\n" +
+				"featureGates: HonorWaitForFirstConsumer\n" +
+				"podResourceRequirements: requests cpu=100m memory=60M, limits cpu=750m memory=600M\n" +
+				"resourceQuotas of namespace \"target-ns\" not checked: resourcequotas is forbidden: no RBAC permission\n" +
+				"resourceQuotas of namespace \"openshift-virtualization-os-images\" not checked: " +
+				"resourcequotas is forbidden: no RBAC permission\n" +
+				"dataVolumeTTLSeconds: unset, completed DataVolumes are kept",
+		},
+		expectedErr: "",
+	},
+	"hcoMissingSC": {
+		clientConfig: clientConfig{hcoMissingSC: true},
+		expectedResults: map[string]string{
+			reporter.HyperConvergedKey: "scratchSpaceStorageClass: test-sc\n" +
+				"storageImport.insecureRegistries: registry.local:5000\n" +
+				"enableCommonBootImageImport: true\n" +
+				"dataImportCronTemplate centos-stream9-image-cron: schedule \"0 */12 * * *\", storageClassName missing-sc\n" +
+				"featureGates: nonRoot=true, withHostPassthroughCPU=false",
+			reporter.HyperConvergedIssuesKey: "dataImportCronTemplate centos-stream9-image-cron storageClassName \"missing-sc\" does not exist",
+		},
+		expectedErr: checkup.ErrHyperConvergedIssues,
+	},
 	"dataSourceNotReady": {
 		clientConfig: clientConfig{dataSourceNotReady: true, expectNoVMI: true},
 		expectedResults: map[string]string{reporter.GoldenImagesNotUpToDateKey: testNamespace + "/" + testDIC,
-			reporter.VMBootFromGoldenImageKey: checkup.MessageSkipNoGoldenImage,
-			reporter.ConcurrentVMBootKey:      checkup.MessageSkipNoGoldenImage,
+			reporter.GoldenImageClonePermissionKey:    checkup.MessageSkipNoGoldenImage,
+			reporter.GoldenImagesStorageClassDriftKey: checkup.MessageSkipNoGoldenImage,
+			reporter.VMBootFromGoldenImageKey:         checkup.MessageSkipNoGoldenImage,
+			reporter.ConcurrentVMBootKey:              checkup.MessageSkipNoGoldenImage,
+			reporter.PodIOBaselineKey: "PVC \"checkup-io-baseline-pvc\" bound\n" +
+				"Pod I/O baseline on storage class \"test-sc\": write 224 MB/s, read 512 MB/s\n" +
+				"VM I/O baseline: " + checkup.MessageSkipNoGoldenImage,
 		},
 		expectedErr: checkup.ErrGoldenImagesNotUpToDate,
 	},
 	"dicNoDataSource": {
 		clientConfig: clientConfig{dicNoDataSource: true, expectNoVMI: true},
 		expectedResults: map[string]string{reporter.GoldenImagesNoDataSourceKey: testNamespace + "/" + testDIC,
-			reporter.VMBootFromGoldenImageKey: checkup.MessageSkipNoGoldenImage,
-			reporter.ConcurrentVMBootKey:      checkup.MessageSkipNoGoldenImage,
+			reporter.GoldenImageClonePermissionKey:    checkup.MessageSkipNoGoldenImage,
+			reporter.GoldenImagesStorageClassDriftKey: checkup.MessageSkipNoGoldenImage,
+			reporter.VMBootFromGoldenImageKey:         checkup.MessageSkipNoGoldenImage,
+			reporter.ConcurrentVMBootKey:              checkup.MessageSkipNoGoldenImage,
+			reporter.PodIOBaselineKey: "PVC \"checkup-io-baseline-pvc\" bound\n" +
+				"Pod I/O baseline on storage class \"test-sc\": write 224 MB/s, read 512 MB/s\n" +
+				"VM I/O baseline: " + checkup.MessageSkipNoGoldenImage,
 		},
 		expectedErr: checkup.ErrGoldenImageNoDataSource,
 	},
 	"vmisWithUnsetEfsSC": {
-		clientConfig:    clientConfig{unsetEfsStorageClass: true},
-		expectedResults: map[string]string{reporter.VMsWithUnsetEfsStorageClassKey: testNamespace + "/" + testVMIName},
-		expectedErr:     checkup.ErrVMsWithUnsetEfsStorageClass,
+		clientConfig: clientConfig{unsetEfsStorageClass: true},
+		expectedResults: map[string]string{reporter.VMsWithUnsetEfsStorageClassKey: testNamespace + "/" + testVMIName,
+			reporter.RemediationsKey: "# Finding: VMs use EFS storage class test-sc-unset-efs whose uid and gid are not set\n" +
+				"# storage class parameters are immutable, create test-sc-unset-efs-virt with the uid and gid " +
+				"of the qemu user and use it for the VM disks\n" +
+				"# Apply: kubectl create -f storageclass-test-sc-unset-efs-virt.yaml\n" +
+				"apiVersion: storage.k8s.io/v1\nkind: StorageClass\nmetadata:\n  name: test-sc-unset-efs-virt\n" +
+				"parameters:\n  gid: \"107\"\n  uid: \"107\"\nprovisioner: efs.csi.aws.com\n"},
+		expectedErr: checkup.ErrVMsWithUnsetEfsStorageClass,
+	},
+	"vmisWithNonVirtRbdSC": {
+		clientConfig: clientConfig{nonVirtRbdStorageClass: true},
+		expectedResults: map[string]string{reporter.VMsWithNonVirtRbdStorageClassKey: testNamespace + "/" + testVMIName,
+			reporter.RemediationsKey: "# Finding: storage class test-sc is the default storage class for VM disks " +
+				"instead of test-sc-ceph-rbd-virtualization\n" +
+				"# unset test-sc as the default storage class for VM disks before making " +
+				"test-sc-ceph-rbd-virtualization the default\n" +
+				"# Apply: kubectl patch storageclass test-sc --type merge --patch-file storageclass-test-sc.yaml\n" +
+				"metadata:\n  annotations:\n    storageclass.kubevirt.io/is-default-virt-class: \"false\"\n" +
+				"---\n" +
+				"# Finding: VMs use an RBD storage class without the krbd:rxbounce map option\n" +
+				"# make test-sc-ceph-rbd-virtualization the default storage class for VM disks, and recreate or storage " +
+				"migrate the disks of the VMs reported in vmsWithNonVirtRbdStorageClass to it\n" +
+				"# Apply: kubectl patch storageclass test-sc-ceph-rbd-virtualization --type merge " +
+				"--patch-file storageclass-test-sc-ceph-rbd-virtualization.yaml\n" +
+				"metadata:\n  annotations:\n    storageclass.kubevirt.io/is-default-virt-class: \"true\"\n"},
+		expectedErr: "",
 	},
 	"dvCloneFallback": {
 		clientConfig:    clientConfig{cloneFallback: true},
@@ -181,11 +607,301 @@ var tests = map[string]struct {
 		expectedResults: map[string]string{reporter.VMLiveMigrationKey: "failed waiting for VMI \"%s\" migration completed: migration failed"},
 		expectedErr:     "migration failed",
 	},
-	"skipMigrationOnSingleNode": {
-		clientConfig:    clientConfig{singleNode: true},
-		expectedResults: map[string]string{reporter.VMLiveMigrationKey: "Skip check - single node"},
+	"hotplugDisabled": {
+		clientConfig: clientConfig{hotplugDisabled: true},
+		expectedResults: map[string]string{
+			reporter.KubeVirtConfigKey: "featureGates: Snapshot, VMExport, VolumeMigration, VolumesUpdateStrategy, VMPersistentState\n" +
+				"enabled storage features: snapshot, export, volume migration, persistent VM state\n" +
+				"disabled storage features: hotplug volumes (HotplugVolumes), expand disks (ExpandDisks)\n" +
+				"vmStateStorageClass: test-sc\n" +
+				"migrations: bandwidthPerMigration 64Mi, parallelMigrationsPerCluster 5, parallelOutboundMigrationsPerNode unset, " +
+				"completionTimeoutPerGiB unset, progressTimeout unset, allowAutoConverge unset\n" +
+				"vmRolloutStrategy: LiveUpdate",
+			reporter.VMHotplugVolumeKey: "Skip check - KubeVirt feature gate HotplugVolumes is disabled",
+		},
+	},
+	"storageMigrationFails": {
+		clientConfig: clientConfig{failStorageMigration: true},
+		expectedResults: map[string]string{
+			reporter.VMStorageMigrationKey: "failed waiting for VMI \"%s\" storage migration completed: storage migration failed",
+		},
+		expectedErr: "storage migration failed",
+	},
+	"volumeMigrationDisabled": {
+		clientConfig: clientConfig{volumeMigrationDisabled: true},
+		expectedResults: map[string]string{
+			reporter.KubeVirtConfigKey: "featureGates: HotplugVolumes, Snapshot, VMExport, VolumesUpdateStrategy, VMPersistentState\n" +
+				"enabled storage features: hotplug volumes, snapshot, export, persistent VM state\n" +
+				"disabled storage features: expand disks (ExpandDisks), volume migration (VolumeMigration)\n" +
+				"vmStateStorageClass: test-sc\n" +
+				"migrations: bandwidthPerMigration 64Mi, parallelMigrationsPerCluster 5, parallelOutboundMigrationsPerNode unset, " +
+				"completionTimeoutPerGiB unset, progressTimeout unset, allowAutoConverge unset\n" +
+				"vmRolloutStrategy: LiveUpdate",
+			reporter.VMStorageMigrationKey: "Skip check - KubeVirt feature gate VolumeMigration is disabled",
+		},
+	},
+	"volumesUpdateStrategyDisabled": {
+		clientConfig: clientConfig{volumesUpdateStrategyDisabled: true},
+		expectedResults: map[string]string{
+			reporter.KubeVirtConfigKey: "featureGates: HotplugVolumes, Snapshot, VMExport, VolumeMigration, VMPersistentState\n" +
+				"enabled storage features: hotplug volumes, snapshot, export, volume migration, persistent VM state\n" +
+				"disabled storage features: expand disks (ExpandDisks)\n" +
+				"vmStateStorageClass: test-sc\n" +
+				"migrations: bandwidthPerMigration 64Mi, parallelMigrationsPerCluster 5, parallelOutboundMigrationsPerNode unset, " +
+				"completionTimeoutPerGiB unset, progressTimeout unset, allowAutoConverge unset\n" +
+				"vmRolloutStrategy: LiveUpdate",
+			reporter.VMStorageMigrationKey: "Skip check - KubeVirt feature gate VolumesUpdateStrategy is disabled",
+		},
+	},
+	"vmRolloutStrategyStage": {
+		clientConfig: clientConfig{vmRolloutStrategyStage: true},
+		expectedResults: map[string]string{
+			reporter.KubeVirtConfigKey: "featureGates: HotplugVolumes, Snapshot, VMExport, VolumeMigration, VolumesUpdateStrategy, " +
+				"VMPersistentState\n" +
+				"enabled storage features: hotplug volumes, snapshot, export, volume migration, persistent VM state\n" +
+				"disabled storage features: expand disks (ExpandDisks)\n" +
+				"vmStateStorageClass: test-sc\n" +
+				"migrations: bandwidthPerMigration 64Mi, parallelMigrationsPerCluster 5, parallelOutboundMigrationsPerNode unset, " +
+				"completionTimeoutPerGiB unset, progressTimeout unset, allowAutoConverge unset\n" +
+				"vmRolloutStrategy: Stage",
+			reporter.VMStorageMigrationKey: "Skip check - KubeVirt vmRolloutStrategy is Stage, not LiveUpdate",
+		},
+	},
+	"storageMigrationRejected": {
+		clientConfig: clientConfig{rejectStorageMigration: true},
+		expectedResults: map[string]string{
+			reporter.VMStorageMigrationKey: checkup.ErrStorageMigrationRejected +
+				": admission webhook denied the request: volume migration is not supported",
+		},
+		expectedErr: checkup.ErrStorageMigrationRejected,
+	},
+	"exportBadImage": {
+		clientConfig: clientConfig{exportBadImage: true},
+		expectedResults: map[string]string{
+			reporter.VMExportKey: "VirtualMachineExport \"checkup-export\" of VM \"%s\" ready, " +
+				"service \"virt-export-checkup-export\"\n" +
+				"VirtualMachineExport failed: volume \"disk\": no MBR boot signature nor qcow2 magic in the image header\n" +
+				"VMI \"%[1]s\" running again",
+		},
+		expectedErr: checkup.ErrExportFailed,
+	},
+	"unhealthyStorage": {
+		clientConfig: clientConfig{unhealthyStorage: true},
+		expectedResults: map[string]string{
+			reporter.UnhealthyStorageObjectsKey: "test-sc: PVC vm-ns/pending-pvc Pending for 2h0m0s, " +
+				"DataVolume vm-ns/importing-dv ImportInProgress for 3d, PV released-pv Released, was bound to vm-ns/released-disk, for 3d\n" +
+				"test-sc-wffc: PVC vm-ns/wffc-selected-pvc Pending for 2h0m0s\n" +
+				"test-sc2: PVC vm-ns/terminating-pvc Terminating for 30m0s (finalizers kubernetes.io/pvc-protection), " +
+				"DataVolume vm-ns/failed-dv Failed for 3d",
+		},
+		expectedErr: checkup.ErrUnhealthyStorageObjects,
+	},
+	"vaAttachError": {
+		clientConfig: clientConfig{vaAttachError: true},
+		expectedResults: map[string]string{
+			reporter.VolumeAttachmentIssuesKey: "VolumeAttachment csi-pv-test-pvc (PV pv-test-pvc, node node-0) " +
+				"attach error: Multi-Attach error for volume pv-test-pvc",
+		},
+		expectedErr: checkup.ErrVolumeAttachmentIssues,
+	},
+	"vaMultiAttach": {
+		clientConfig: clientConfig{vaMultiAttach: true},
+		expectedResults: map[string]string{
+			reporter.VolumeAttachmentIssuesKey: "Pod target-ns/virt-launcher-test-vmi-abcde: Multi-Attach error for volume " +
+				"\"pv-test-pvc\" Volume is already exclusively attached to one node and can't be attached to another",
+		},
+		expectedErr: checkup.ErrVolumeAttachmentIssues,
+	},
+	"vaStale": {
+		clientConfig: clientConfig{vaStale: true},
+		expectedResults: map[string]string{
+			reporter.VolumeAttachmentIssuesKey: "VolumeAttachment csi-pv-test-pvc (PV pv-test-pvc, node node-1) is stale, " +
+				"used by VMI target-ns/test-vmi on node node-0",
+			reporter.VolumeAttachLimitsKey: "node-0 test-sc: 1 attached (0 by hotplug attachment pods), limit 39, headroom 97%%\n" +
+				"node-1 test-sc: 1 attached (0 by hotplug attachment pods), no limit\n" +
+				"room for 38 more VMs like \"%s\" within the volume attach limits, no limit on node-1",
+		},
+		expectedErr: checkup.ErrVolumeAttachmentIssues,
+	},
+	"vaStaleDeleting": {
+		clientConfig: clientConfig{vaStaleDeleting: true},
+		expectedResults: map[string]string{
+			reporter.VolumeAttachLimitsKey: "node-0 test-sc: 1 attached (0 by hotplug attachment pods), limit 39, headroom 97%%\n" +
+				"node-1 test-sc: 0 attached (0 by hotplug attachment pods), no limit\n" +
+				"room for 38 more VMs like \"%s\" within the volume attach limits, no limit on node-1",
+		},
+		expectedErr: "",
+	},
+	"vaStaleMigrated": {
+		clientConfig: clientConfig{vaStaleMigrated: true},
+		expectedResults: map[string]string{
+			reporter.VolumeAttachLimitsKey: "node-0 test-sc: 1 attached (0 by hotplug attachment pods), limit 39, headroom 97%%\n" +
+				"node-1 test-sc: 1 attached (0 by hotplug attachment pods), no limit\n" +
+				"room for 38 more VMs like \"%s\" within the volume attach limits, no limit on node-1",
+		},
+		expectedErr: "",
+	},
+	"hotplugAttachmentPod": {
+		clientConfig: clientConfig{hotplugAttachmentPod: true},
+		expectedResults: map[string]string{
+			reporter.VolumeAttachLimitsKey: "node-0 test-sc: 3 attached (1 by hotplug attachment pods), limit 39, " +
+				"headroom 92%%\nnode-1 test-sc: 0 attached (0 by hotplug attachment pods), no limit\n" +
+				"room for 36 more VMs like \"%s\" within the volume attach limits, no limit on node-1",
+		},
+		expectedErr: "",
+	},
+	"attachLimitLow": {
+		clientConfig: clientConfig{attachLimitLow: true},
+		expectedResults: map[string]string{
+			reporter.CSINodeCoverageKey: testScName + ": registered on 2/2 schedulable nodes\n" +
+				"node-0 allocatable volumes: test-sc=2\nnode-1 allocatable volumes: test-sc=unlimited",
+			reporter.VolumeAttachLimitsKey: "node-0 test-sc: 2 attached (0 by hotplug attachment pods), limit 2, " +
+				"headroom 0%%\nnode-1 test-sc: 0 attached (0 by hotplug attachment pods), no limit\n" +
+				"room for 0 more VMs like \"%s\" within the volume attach limits, no limit on node-1",
+			reporter.VolumeAttachLimitWarningsKey: "node-0 test-sc: headroom 0% is below 20%",
+		},
+		expectedErr: "",
+	},
+	"concurrentVMBootLatency": {
+		checkupConfig: func(cfg *config.Config) { cfg.NumOfVMs = 3 },
+		expectedResults: map[string]string{
+			reporter.ConcurrentVMBootLatencyKey: "time to DV ready: min 5s, p50 5s, p90 5s, p99 5s, max 5s (3 VMs)\n" +
+				"time to scheduled: min 10s, p50 10s, p90 10s, p99 10s, max 10s (3 VMs)\n" +
+				"time to running: min 20s, p50 20s, p90 20s, p99 20s, max 20s (3 VMs)\n" +
+				"time to agent connected: min 40s, p50 40s, p90 40s, p99 40s, max 40s (3 VMs)\n" +
+				"node distribution: node-0=3",
+		},
+		expectedErr: "",
+	},
+	"concurrentVMBootTimingUnknown": {
+		clientConfig:  clientConfig{bootTimingLookupFailure: true},
+		checkupConfig: func(cfg *config.Config) { cfg.NumOfVMs = 2 },
+		expectedResults: map[string]string{
+			reporter.ConcurrentVMBootLatencyKey: "time to DV ready: unknown\n" +
+				"time to scheduled: min 10s, p50 10s, p90 10s, p99 10s, max 10s (2 VMs)\n" +
+				"time to running: min 20s, p50 20s, p90 20s, p99 20s, max 20s (2 VMs)\n" +
+				"time to agent connected: min 40s, p50 40s, p90 40s, p99 40s, max 40s (2 VMs)\n" +
+				"node distribution: node-0=2",
+		},
+		expectedErr: "",
+	},
+	"concurrentVMBootThresholdExceeded": {
+		checkupConfig: func(cfg *config.Config) {
+			cfg.NumOfVMs = 2
+			cfg.BootP90Threshold = 30 * time.Second
+			cfg.BootP99Threshold = time.Minute
+		},
+		expectedResults: map[string]string{
+			reporter.ConcurrentVMBootLatencyKey: "time to DV ready: min 5s, p50 5s, p90 5s, p99 5s, max 5s (2 VMs)\n" +
+				"time to scheduled: min 10s, p50 10s, p90 10s, p99 10s, max 10s (2 VMs)\n" +
+				"time to running: min 20s, p50 20s, p90 20s, p99 20s, max 20s (2 VMs)\n" +
+				"time to agent connected: min 40s, p50 40s, p90 40s, p99 40s, max 40s (2 VMs)\n" +
+				"node distribution: node-0=2\n" + checkup.ErrBootTimeThresholdExceeded + ": p90 40s exceeds 30s",
+		},
+		expectedErr: checkup.ErrBootTimeThresholdExceeded,
+	},
+	"concurrentVMBootRamp": {
+		checkupConfig: func(cfg *config.Config) {
+			cfg.NumOfVMs = 4
+			cfg.BootRamp = true
+			cfg.BootRampInitialVMs = 1
+			cfg.BootP90Threshold = time.Minute
+		},
+		expectedResults: map[string]string{
+			reporter.ConcurrentVMBootKey: "largest concurrent boot wave meeting the SLO: 4 VMs",
+			reporter.ConcurrentVMBootLatencyKey: "time to DV ready: min 5s, p50 5s, p90 5s, p99 5s, max 5s (4 VMs)\n" +
+				"time to scheduled: min 10s, p50 10s, p90 10s, p99 10s, max 10s (4 VMs)\n" +
+				"time to running: min 20s, p50 20s, p90 20s, p99 20s, max 20s (4 VMs)\n" +
+				"time to agent connected: min 40s, p50 40s, p90 40s, p99 40s, max 40s (4 VMs)\n" +
+				"node distribution: node-0=4",
+			reporter.ConcurrentVMBootRampKey: "wave of 1 VMs: 0 failed, time to agent connected: " +
+				"min 40s, p50 40s, p90 40s, p99 40s, max 40s (1 VMs)\n" +
+				"wave of 2 VMs: 0 failed, time to agent connected: min 40s, p50 40s, p90 40s, p99 40s, max 40s (2 VMs)\n" +
+				"wave of 4 VMs: 0 failed, time to agent connected: min 40s, p50 40s, p90 40s, p99 40s, max 40s (4 VMs)",
+		},
+		expectedErr: "",
+	},
+	"concurrentVMBootRampSaturated": {
+		checkupConfig: func(cfg *config.Config) {
+			cfg.NumOfVMs = 4
+			cfg.BootRamp = true
+			cfg.BootRampInitialVMs = 2
+			cfg.BootP90Threshold = 30 * time.Second
+		},
+		expectedResults: map[string]string{
+			reporter.ConcurrentVMBootKey: checkup.ErrNoBootRampWaveMetSLO,
+			reporter.ConcurrentVMBootRampKey: "wave of 2 VMs: 0 failed, time to agent connected: " +
+				"min 40s, p50 40s, p90 40s, p99 40s, max 40s (2 VMs)\n" +
+				checkup.ErrBootTimeThresholdExceeded + ": p90 40s exceeds 30s",
+		},
+		expectedErr: checkup.ErrNoBootRampWaveMetSLO,
+	},
+	"migrationAllNodes": {
+		checkupConfig: func(cfg *config.Config) { cfg.MigrationAllNodes = true },
+		expectedResults: map[string]string{
+			reporter.VMLiveMigrationKey:       "2 of 2 targeted live migrations of VMI \"%s\" succeeded",
+			reporter.VMLiveMigrationMatrixKey: "node-0 -> node-1: 4s\nnode-1 -> node-0: 4s",
+		},
+		expectedErr: "",
+	},
+	"migrationNodeSelectors": {
+		checkupConfig: func(cfg *config.Config) {
+			cfg.MigrationSourceNodeSelector = corev1.LabelTopologyZone + "=zone-b"
+			cfg.MigrationTargetNodeSelector = corev1.LabelTopologyZone + "=zone-a"
+		},
+		expectedResults: map[string]string{
+			reporter.VMLiveMigrationKey:       "2 of 2 targeted live migrations of VMI \"%s\" succeeded",
+			reporter.VMLiveMigrationMatrixKey: "node-0 -> node-1 (reposition): 4s\nnode-1 -> node-0: 4s",
+		},
+		expectedErr: "",
+	},
+	"migrationNoNodePair": {
+		checkupConfig: func(cfg *config.Config) {
+			cfg.MigrationSourceNodeSelector = corev1.LabelTopologyZone + "=zone-a"
+			cfg.MigrationTargetNodeSelector = corev1.LabelTopologyZone + "=zone-a"
+		},
+		expectedResults: map[string]string{reporter.VMLiveMigrationKey: checkup.MessageSkipNoMigrationNodePair},
 		expectedErr:     "",
 	},
+	"migrationIgnoreNodeSelector": {
+		clientConfig:  clientConfig{migrationIgnoreNodeSelector: true},
+		checkupConfig: func(cfg *config.Config) { cfg.MigrationAllNodes = true },
+		expectedResults: map[string]string{
+			reporter.VMLiveMigrationKey: "0 of 2 targeted live migrations of VMI \"%s\" succeeded",
+			reporter.VMLiveMigrationMatrixKey: "node-0 -> node-1: completed in 4s, but landed on node node-0\n" +
+				"node-1 -> node-0: skipped, VMI is on node node-0",
+		},
+		expectedErr: checkup.ErrTargetedLiveMigrationFailed,
+	},
+	"vmiNotMigratable": {
+		clientConfig:  clientConfig{vmiNotMigratable: true},
+		checkupConfig: func(cfg *config.Config) { cfg.TargetStorageClass = testScName },
+		expectedResults: map[string]string{
+			reporter.VMStorageMigrationKey: "VMI \"%s\" storage migration completed\nvolume migrated from storage class " +
+				"\"test-sc2\" to \"test-sc\" in 42s, VMI \"%[1]s\" still running\n" +
+				"method: updateVolumesStrategy Migration, live migration mode PreCopy",
+			reporter.VMLiveMigrationKey: "cannot migrate VMI: PVC is not shared, live migration requires that all PVCs " +
+				"must be shared (using ReadWriteMany access mode)",
+			reporter.VMLiveMigrationAdviceKey: "VMI \"%s\" volume storage class \"test-sc2\" (provisioner \"test-sc\") " +
+				"does not provide ReadWriteMany\n" +
+				"use storage class \"test-sc\" of the same provisioner, its StorageProfile advertises ReadWriteMany Filesystem: " +
+				"set spec.param.storageClass to \"test-sc\" and create the VM disks on it\n" +
+				"storage class \"test-sc-nfs\" of the same provisioner does not advertise ReadWriteMany; if its backend " +
+				"provides it (e.g. NFS rather than iSCSI), patch its StorageProfile: kubectl patch storageprofile test-sc-nfs " +
+				"--type merge -p '{\"spec\":{\"claimPropertySets\":[{\"accessModes\":[\"ReadWriteMany\"],\"volumeMode\":\"Filesystem\"}]}}'",
+		},
+		expectedErr: "",
+	},
+	"skipMigrationOnSingleNode": {
+		clientConfig: clientConfig{singleNode: true},
+		expectedResults: map[string]string{reporter.VMLiveMigrationKey: "Skip check - single node",
+			reporter.VMStorageMigrationKey: "Skip check - single node",
+			reporter.CSINodeCoverageKey:    testScName + ": registered on 1/1 schedulable nodes\nnode-0 allocatable volumes: test-sc=39",
+			reporter.VolumeAttachLimitsKey: "node-0 test-sc: 2 attached (0 by hotplug attachment pods), limit 39, headroom 94%%\n" +
+				"node-1 test-sc: 0 attached (0 by hotplug attachment pods), no limit\n" +
+				"room for 37 more VMs like \"%s\" within the volume attach limits"},
+		expectedErr: "",
+	},
 }
 
 func TestCheckupShouldReturnErrorWhen(t *testing.T) {
@@ -193,6 +909,9 @@ func TestCheckupShouldReturnErrorWhen(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			testClient := newClientStub(tc.clientConfig)
 			testConfig := newTestConfig()
+			if tc.checkupConfig != nil {
+				tc.checkupConfig(&testConfig)
+			}
 
 			testCheckup := checkup.New(testClient, testNamespace, testConfig)
 
@@ -220,6 +939,150 @@ func TestCheckupShouldReturnErrorWhen(t *testing.T) {
 	}
 }
 
+func TestCheckupShouldRunRestrictedPods(t *testing.T) {
+	testClient := newClientStub(clientConfig{})
+	testCheckup := checkup.New(testClient, testNamespace, newTestConfig())
+
+	assert.NoError(t, testCheckup.Setup(context.Background()))
+	assert.NoError(t, testCheckup.Run(context.Background()))
+
+	assert.NotNil(t, testClient.ioBaselinePod)
+	assert.Equal(t, int64(900), *testClient.ioBaselinePod.Spec.SecurityContext.FSGroup)
+	assert.NotNil(t, testClient.imageServerPod)
+	for _, pod := range []*corev1.Pod{testClient.ioBaselinePod, testClient.imageServerPod} {
+		assert.True(t, *pod.Spec.SecurityContext.RunAsNonRoot)
+		assert.Equal(t, corev1.SeccompProfileTypeRuntimeDefault, pod.Spec.SecurityContext.SeccompProfile.Type)
+		assert.False(t, *pod.Spec.Containers[0].SecurityContext.AllowPrivilegeEscalation)
+		assert.Equal(t, []corev1.Capability{"ALL"}, pod.Spec.Containers[0].SecurityContext.Capabilities.Drop)
+	}
+}
+
+func TestCheckupShouldWriteRemediationConfigMap(t *testing.T) {
+	const cmName = "storage-checkup-remediations"
+	testClient := newClientStub(clientConfig{spIncomplete: true})
+	testConfig := newTestConfig()
+	testConfig.RemediationConfigMap = cmName
+
+	testCheckup := checkup.New(testClient, testNamespace, testConfig)
+
+	assert.NoError(t, testCheckup.Setup(context.Background()))
+	assert.ErrorContains(t, testCheckup.Run(context.Background()), checkup.ErrEmptyClaimPropertySets)
+
+	cm, exists := testClient.createdConfigMaps[objectFullName(testNamespace, cmName)]
+	assert.True(t, exists)
+	assert.Equal(t, map[string]string{"storageprofile-test-sc.yaml": testCheckup.Results().Remediations}, cm.Data)
+	assert.Contains(t, cm.Data["storageprofile-test-sc.yaml"], "kubectl patch storageprofile test-sc --type merge")
+}
+
+func TestCheckupShouldReportConcurrentBootFailures(t *testing.T) {
+	testClient := newClientStub(clientConfig{concurrentBootAgentNotConnected: true})
+	testConfig := newTestConfig()
+	testConfig.NumOfVMs = 2
+
+	testCheckup := checkup.New(testClient, testNamespace, testConfig)
+
+	assert.NoError(t, testCheckup.Setup(context.Background()))
+	assert.ErrorContains(t, testCheckup.Run(context.Background()), checkup.ErrBootFailedOnSomeVMs)
+
+	results := testCheckup.Results()
+	assert.Equal(t, checkup.ErrBootFailedOnSomeVMs, results.ConcurrentVMBoot)
+	assert.Empty(t, results.ConcurrentVMBootLatency)
+	failures := strings.Split(results.ConcurrentVMBootFailures, "\n")
+	assert.Len(t, failures, 2)
+	for _, failure := range failures {
+		assert.Regexp(t, "^"+checkup.VMIUnderTestNamePrefix+"-[a-z0-9]{5}: stalled at guest agent; "+
+			"DV [a-z0-9-]+-dv Succeeded: Ready=True; DV [a-z0-9-]+-dv-blank Succeeded: Ready=True; "+
+			"VMI Running: Ready=True, LiveMigratable=True, AgentConnected=False \\(GuestAgentNotConnected\\)$", failure)
+	}
+}
+
+func TestCheckupShouldReportConcurrentBootFailuresOfEachRampWave(t *testing.T) {
+	testClient := newClientStub(clientConfig{concurrentBootAgentNotConnected: true})
+	testConfig := newTestConfig()
+	testConfig.NumOfVMs = 2
+	testConfig.BootRamp = true
+	testConfig.BootRampInitialVMs = 1
+	testConfig.BootRampMaxFailedVMs = 1
+	testConfig.DiagnosticsConfigMap = testDiagnosticsCM
+
+	testCheckup := checkup.New(testClient, testNamespace, testConfig)
+
+	assert.NoError(t, testCheckup.Setup(context.Background()))
+	assert.NoError(t, testCheckup.Run(context.Background()))
+	// The failed VMs of each wave are deleted before the next one, not kept for the diagnostics bundle, leaving only the
+	// VM under test to the teardown
+	assert.Len(t, testClient.createdVMs, 1)
+
+	results := testCheckup.Results()
+	assert.Equal(t, "largest concurrent boot wave meeting the SLO: 1 VMs", results.ConcurrentVMBoot)
+	failures := strings.Split(results.ConcurrentVMBootFailures, "\n")
+	assert.Len(t, failures, 3)
+	for i, waveSize := range []int{1, 2, 2} {
+		assert.Regexp(t, fmt.Sprintf("^wave of %d VMs: %s-[a-z0-9]{5}: stalled at guest agent; ", waveSize,
+			checkup.VMIUnderTestNamePrefix), failures[i])
+	}
+}
+
+func TestCheckupShouldWriteDiagnosticsBundle(t *testing.T) {
+	cmName := testDiagnosticsCM
+	testClient := newClientStub(clientConfig{spIncomplete: true})
+	testConfig := newTestConfig()
+	testConfig.DiagnosticsConfigMap = cmName
+	testConfig.DiagnosticsDir = t.TempDir()
+
+	testCheckup := checkup.New(testClient, testNamespace, testConfig)
+
+	assert.NoError(t, testCheckup.Setup(context.Background()))
+	assert.ErrorContains(t, testCheckup.Run(context.Background()), checkup.ErrEmptyClaimPropertySets)
+
+	cm, exists := testClient.createdConfigMaps[objectFullName(testNamespace, cmName)]
+	assert.True(t, exists)
+	gzipReader, err := gzip.NewReader(bytes.NewReader(cm.BinaryData[checkup.DiagnosticsBundleKey]))
+	assert.NoError(t, err)
+	files := map[string]string{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		content, err := io.ReadAll(tarReader)
+		assert.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+
+	assert.Equal(t, "2024-01-01T00:00:00Z Warning DataVolume/checkup-pvc Pending: PVC checkup-pvc Pending (x3)\n",
+		files["events.txt"])
+	assert.Equal(t, "virt-launcher-"+testVMIName+" compute log\n", files["logs/virt-launcher-"+testVMIName+"/compute.log"])
+	assert.Contains(t, files["virtualmachineinstances/"+testVMIName+".yaml"], "nodeName: node-0")
+	assert.Contains(t, files["storageclasses/"+testScName+".yaml"], "provisioner: "+testScName)
+	assert.Contains(t, files, "storageprofiles/"+testScName+".yaml")
+	assert.Equal(t, fmt.Sprintf("diagnostics bundle of %d files written to directory %s\n"+
+		"diagnostics bundle of %d files written to ConfigMap %s/%s", len(files), testConfig.DiagnosticsDir, len(files),
+		testNamespace, cmName), testCheckup.Results().Diagnostics)
+
+	dirFile, err := os.ReadFile(filepath.Join(testConfig.DiagnosticsDir, "events.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, files["events.txt"], string(dirFile))
+}
+
+func TestCheckupShouldKeepFailedObjectsForDiagnosticsUntilTeardown(t *testing.T) {
+	testClient := newClientStub(clientConfig{failIOBaselinePod: true})
+	testConfig := newTestConfig()
+	testConfig.DiagnosticsConfigMap = testDiagnosticsCM
+
+	testCheckup := checkup.New(testClient, testNamespace, testConfig)
+
+	assert.NoError(t, testCheckup.Setup(context.Background()))
+	assert.ErrorContains(t, testCheckup.Run(context.Background()), checkup.ErrPodIOBaselineFailed)
+
+	ioBaselinePodFullName := objectFullName(testNamespace, testClient.ioBaselinePod.Name)
+	assert.Contains(t, testClient.createdPods, ioBaselinePodFullName)
+	assert.NoError(t, testCheckup.Teardown(context.Background()))
+	assert.NotContains(t, testClient.createdPods, ioBaselinePodFullName)
+}
+
 func checkOwnerRef(t *testing.T, testClient *clientStub) {
 	vmiUnderTestName := testClient.VMIName(checkup.VMIUnderTestNamePrefix)
 	vmFullName := objectFullName(testNamespace, vmiUnderTestName)
@@ -248,6 +1111,11 @@ func fullExpectedResults(vmiUnderTestName string, expectedResults map[string]str
 func expectedResultsNoVMI(expectedResults map[string]string) {
 	expectedResults[reporter.VMHotplugVolumeKey] = checkup.MessageSkipNoVMI
 	expectedResults[reporter.VMLiveMigrationKey] = checkup.MessageSkipNoVMI
+	expectedResults[reporter.VMStorageMigrationKey] = checkup.MessageSkipNoVMI
+	expectedResults[reporter.VMExportKey] = checkup.MessageSkipNoVMI
+	expectedResults[reporter.VolumeAttachmentsKey] = "1 VolumeAttachments of VMI volumes checked"
+	expectedResults[reporter.VolumeAttachLimitsKey] = "node-0 test-sc: 1 attached (0 by hotplug attachment pods), limit 39, " +
+		"headroom 97%\nnode-1 test-sc: 0 attached (0 by hotplug attachment pods), no limit"
 	expectedResults[reporter.VMVolumeCloneKey] = ""
 }
 
@@ -262,23 +1130,97 @@ func successfulRunResults(vmiUnderTestName string) map[string]string {
 		reporter.KubeVirtVersionKey: "",
 
 		// Storage information
-		reporter.DefaultStorageClassKey:                       testScName,
-		reporter.PVCBoundKey:                                  "PVC \"checkup-pvc\" bound",
+		reporter.DefaultStorageClassKey: testScName,
+		reporter.PVCBoundKey:            "PVC \"checkup-pvc\" bound",
+		reporter.PodIOBaselineKey: "PVC \"checkup-io-baseline-pvc\" bound\n" +
+			"Pod I/O baseline on storage class \"test-sc\": write 224 MB/s, read 512 MB/s\n" +
+			"VMI \"checkup-io-baseline-vm\" powered off after the I/O workload\n" +
+			"VM I/O baseline on storage class \"test-sc\": write 112 MB/s, read 384 MB/s\n" +
+			"VM/pod throughput ratio: write 0.50, read 0.75",
+		reporter.CDIUploadKey: uploadProxyResult + "\n" +
+			"DataVolume \"checkup-upload\" upload succeeded\nVMI \"checkup-upload-vm\" ready",
+		reporter.CDIImportKey: "Image server: http://10.0.0.1:8080\n" +
+			"DataVolume \"checkup-import-raw\" imported from disk.img\n" +
+			"DataVolume \"checkup-import-qcow2\" imported from disk.qcow2\n" +
+			"DataVolume \"checkup-import-gz\" imported from disk.img.gz\n" +
+			"VMI \"checkup-import-vm\" ready",
+		reporter.CDIConfigKey: "scratchSpaceStorageClass: test-sc\nfilesystemOverhead: global 0.055, test-sc 0.1\n" +
+			"featureGates: HonorWaitForFirstConsumer\n" +
+			"podResourceRequirements: requests cpu=100m memory=60M, limits cpu=750m memory=600M\n" +
+			"dataVolumeTTLSeconds: unset, completed DataVolumes are kept",
+		reporter.CDIConfigIssuesKey: "",
+		reporter.HyperConvergedKey: "scratchSpaceStorageClass: test-sc\n" +
+			"storageImport.insecureRegistries: registry.local:5000\n" +
+			"enableCommonBootImageImport: true\n" +
+			"dataImportCronTemplate centos-stream9-image-cron: schedule \"0 */12 * * *\", storageClassName test-sc\n" +
+			"featureGates: nonRoot=true, withHostPassthroughCPU=false",
+		reporter.HyperConvergedIssuesKey: "",
+		reporter.KubeVirtConfigKey: "featureGates: HotplugVolumes, Snapshot, VMExport, VolumeMigration, VolumesUpdateStrategy, " +
+			"VMPersistentState\n" +
+			"enabled storage features: hotplug volumes, snapshot, export, volume migration, persistent VM state\n" +
+			"disabled storage features: expand disks (ExpandDisks)\n" +
+			"vmStateStorageClass: test-sc\n" +
+			"migrations: bandwidthPerMigration 64Mi, parallelMigrationsPerCluster 5, parallelOutboundMigrationsPerNode unset, " +
+			"completionTimeoutPerGiB unset, progressTimeout unset, allowAutoConverge unset\n" +
+			"vmRolloutStrategy: LiveUpdate",
 		reporter.StorageProfilesWithEmptyClaimPropertySetsKey: "",
 		reporter.StorageProfilesWithSpecClaimPropertySetsKey:  "",
 		reporter.StorageProfilesWithSmartCloneKey:             testScName,
 		reporter.StorageProfilesWithRWXKey:                    testScName,
-		reporter.StorageProfileMissingVolumeSnapshotClassKey:  "",
-		reporter.GoldenImagesNotUpToDateKey:                   "",
-		reporter.GoldenImagesNoDataSourceKey:                  "",
-		reporter.VMsWithNonVirtRbdStorageClassKey:             "",
-		reporter.VMsWithUnsetEfsStorageClassKey:               "",
-		reporter.VMBootFromGoldenImageKey:                     fmt.Sprintf("VMI %q successfully booted", vmiUnderTestName),
-		reporter.VMVolumeCloneKey:                             "DV cloneType: \"\"",
-		reporter.VMLiveMigrationKey:                           fmt.Sprintf("VMI %q migration completed", vmiUnderTestName),
+		reporter.CSIDriverCapabilitiesKey: testScName + ": attachRequired=true, fsGroupPolicy=File, " +
+			"volumeLifecycleModes=Persistent, podInfoOnMount=false, requiresRepublish=false, registeredNodes=2",
+		reporter.StorageProfilesCSIDriverMismatchKey:         "",
+		reporter.StorageProfilesFSGroupPolicyKey:             "",
+		reporter.StorageProfileMissingVolumeSnapshotClassKey: "",
+		reporter.StorageProfilesNoDefaultSnapshotClassKey:    "",
+		reporter.StorageProfilesInvalidSnapshotClassKey:      "",
+		reporter.VolumeSnapshotClassesMultipleDefaultKey:     "",
+		reporter.VolumeSnapshotClassesRetainPolicyKey:        "",
+		reporter.VolumeSnapshotRoundTripKey: testScName + ": snapshot ready in 0s, restore bound in 0s, " +
+			"restoreSize 100Mi",
+		reporter.GoldenImagesNotUpToDateKey:       "",
+		reporter.GoldenImagesNoDataSourceKey:      "",
+		reporter.GoldenImagesOutdatedKey:          "",
+		reporter.DataImportCronsMissedScheduleKey: "",
+		reporter.DataImportCronsExcessImportsKey:  "",
+		reporter.GoldenImagesStorageClassDriftKey: "",
+		reporter.GoldenImageClonePermissionKey: fmt.Sprintf("service account %q may clone from namespace %q\n"+
+			"service account %q may clone from namespace %q", testNamespace+"/"+testPodSA, testNamespace,
+			testNamespace+"/default", testNamespace),
+		reporter.VMsWithNonVirtRbdStorageClassKey: "",
+		reporter.VMsWithUnsetEfsStorageClassKey:   "",
+		reporter.UnhealthyStorageObjectsKey:       "",
+		reporter.CSINodeCoverageKey: testScName + ": registered on 2/2 schedulable nodes\n" +
+			"node-0 allocatable volumes: test-sc=39\nnode-1 allocatable volumes: test-sc=unlimited",
+		reporter.CSINodeCoverageIssuesKey: "",
+		reporter.VolumeAttachmentsKey: fmt.Sprintf("2 VolumeAttachments of VMI volumes checked\n"+
+			"VolumeAttachment csi-pv-%s-dv (PV pv-%s-dv, node node-0) of VMI %q attached in 3s",
+			vmiUnderTestName, vmiUnderTestName, vmiUnderTestName),
+		reporter.VolumeAttachmentIssuesKey: "",
+		reporter.VolumeAttachLimitsKey: fmt.Sprintf("node-0 test-sc: 2 attached (0 by hotplug attachment pods), limit 39, "+
+			"headroom 94%%\nnode-1 test-sc: 0 attached (0 by hotplug attachment pods), no limit\n"+
+			"room for 37 more VMs like %q within the volume attach limits, no limit on node-1", vmiUnderTestName),
+		reporter.VolumeAttachLimitWarningsKey: "",
+		reporter.VMBootFromGoldenImageKey:     fmt.Sprintf("VMI %q successfully booted", vmiUnderTestName),
+		reporter.VMVolumeCloneKey:             "DV cloneType: \"\"",
+		reporter.VMLiveMigrationKey:           fmt.Sprintf("VMI %q migration completed", vmiUnderTestName),
+		reporter.VMLiveMigrationMatrixKey:     "",
+		reporter.VMLiveMigrationAdviceKey:     "",
+		reporter.RemediationsKey:              "",
+		reporter.DiagnosticsKey:               "",
 		reporter.VMHotplugVolumeKey: fmt.Sprintf("VMI %q hotplug volume ready\nVMI %q hotplug volume removed",
 			vmiUnderTestName, vmiUnderTestName),
-		reporter.ConcurrentVMBootKey: "Boot completed on all VMs on time",
+		reporter.VMStorageMigrationKey: fmt.Sprintf("VMI %q storage migration completed\n"+
+			"volume migrated from storage class \"test-sc\" to \"test-sc2\" in 42s, VMI %q still running\n"+
+			"method: updateVolumesStrategy Migration, live migration mode PreCopy", vmiUnderTestName, vmiUnderTestName),
+		reporter.VMExportKey: fmt.Sprintf("VirtualMachineExport \"checkup-export\" of VM %q ready, "+
+			"service \"virt-export-checkup-export\"\n"+
+			"Downloaded 1048576 bytes of volume \"disk\" in raw format, image header: raw, MBR boot signature\n"+
+			"VMI %q running again", vmiUnderTestName, vmiUnderTestName),
+		reporter.ConcurrentVMBootKey:         "Boot completed on all VMs on time",
+		reporter.ConcurrentVMBootLatencyKey:  "",
+		reporter.ConcurrentVMBootRampKey:     "",
+		reporter.ConcurrentVMBootFailuresKey: "",
 	}
 }
 
@@ -292,10 +1234,58 @@ type clientConfig struct {
 	multipleDefaultStorageClasses     bool
 	multipleDefaultVirtStorageClasses bool
 	failPvcBound                      bool
+	failIOBaselinePod                 bool
+	failIOBaselineVM                  bool
+	resourceQuotasForbidden           bool
+	csiNodesForbidden                 bool
 	unsetEfsStorageClass              bool
+	nonVirtRbdStorageClass            bool
+	bootTimingLookupFailure           bool
 	spIncomplete                      bool
 	noVolumeSnapshotClasses           bool
+	noDefaultVolumeSnapshotClass      bool
+	multipleDefaultVolumeSnapshotCls  bool
+	retainVolumeSnapshotClass         bool
+	spInvalidSnapshotClass            bool
+	failVolumeSnapshot                bool
+	uploadProxyUnknownCA              bool
+	uploadProxyServiceMissing         bool
+	failUploadDv                      bool
+	failImportDv                      bool
+	cloneNotAllowed                   bool
+	csiDriverNotRegistered            bool
+	csiNodeMissingDriver              bool
+	defaultScNotCSI                   bool
+	csiDriverFsGroupPolicyRWO         bool
 	dicNoDataSource                   bool
+	goldenImageSnapshot               bool
+	goldenImageOutdated               bool
+	dicMissedSchedule                 bool
+	dicInvalidSchedule                bool
+	dicExcessImports                  bool
+	goldenImageOtherSC                bool
+	cdiMissingScratchSC               bool
+	cdiNoHonorWFFC                    bool
+	cdiQuotaExceeded                  bool
+	hotplugDisabled                   bool
+	hcoMissingSC                      bool
+	volumeMigrationDisabled           bool
+	volumesUpdateStrategyDisabled     bool
+	vmRolloutStrategyStage            bool
+	rejectStorageMigration            bool
+	failStorageMigration              bool
+	exportBadImage                    bool
+	unhealthyStorage                  bool
+	vaAttachError                     bool
+	vaStale                           bool
+	vaStaleDeleting                   bool
+	vaStaleMigrated                   bool
+	vaMultiAttach                     bool
+	hotplugAttachmentPod              bool
+	attachLimitLow                    bool
+	migrationIgnoreNodeSelector       bool
+	vmiNotMigratable                  bool
+	concurrentBootAgentNotConnected   bool
 	dataSourceNotReady                bool
 	expectNoVMI                       bool
 	cloneFallback                     bool
@@ -304,8 +1294,17 @@ type clientConfig struct {
 }
 
 type clientStub struct {
+	// mutex protects the VMs and VMIs concurrently booted
+	mutex             sync.Mutex
 	createdVMs        map[string]*kvcorev1.VirtualMachine
 	createdVMIs       map[string]*kvcorev1.VirtualMachineInstance
+	stoppedVMIs       map[string]*kvcorev1.VirtualMachineInstance
+	createdPods       map[string]*corev1.Pod
+	ioBaselinePod     *corev1.Pod
+	createdSnapshots  map[string]*snapshotv1.VolumeSnapshot
+	imageServerPod    *corev1.Pod
+	createdConfigMaps map[string]*corev1.ConfigMap
+	dataVolumePolls   map[string]int
 	vmCreationFailure error
 	vmDeletionFailure error
 	vmiGetFailure     error
@@ -314,9 +1313,14 @@ type clientStub struct {
 
 func newClientStub(clientConfig clientConfig) *clientStub {
 	return &clientStub{
-		createdVMs:   map[string]*kvcorev1.VirtualMachine{},
-		createdVMIs:  map[string]*kvcorev1.VirtualMachineInstance{},
-		clientConfig: clientConfig,
+		createdVMs:        map[string]*kvcorev1.VirtualMachine{},
+		createdVMIs:       map[string]*kvcorev1.VirtualMachineInstance{},
+		stoppedVMIs:       map[string]*kvcorev1.VirtualMachineInstance{},
+		createdPods:       map[string]*corev1.Pod{},
+		createdSnapshots:  map[string]*snapshotv1.VolumeSnapshot{},
+		createdConfigMaps: map[string]*corev1.ConfigMap{},
+		dataVolumePolls:   map[string]int{},
+		clientConfig:      clientConfig,
 	}
 }
 
@@ -326,7 +1330,11 @@ func (cs *clientStub) CreateVirtualMachine(ctx context.Context, namespace string
 		return nil, cs.vmCreationFailure
 	}
 
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
 	vm.Namespace = namespace
+	vm.CreationTimestamp = metav1.Now()
 	vmFullName := objectFullName(vm.Namespace, vm.Name)
 	cs.createdVMs[vmFullName] = vm
 
@@ -344,33 +1352,113 @@ func (cs *clientStub) CreateVirtualMachine(ctx context.Context, namespace string
 					Status: corev1.ConditionTrue,
 				},
 				{
-					Type:   kvcorev1.VirtualMachineInstanceAgentConnected,
-					Status: corev1.ConditionTrue,
+					Type:               kvcorev1.VirtualMachineInstanceAgentConnected,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(vm.CreationTimestamp.Add(40 * time.Second)),
 				},
 			},
+			PhaseTransitionTimestamps: []kvcorev1.VirtualMachineInstancePhaseTransitionTimestamp{
+				{Phase: kvcorev1.Scheduled, PhaseTransitionTimestamp: metav1.NewTime(vm.CreationTimestamp.Add(10 * time.Second))},
+				{Phase: kvcorev1.Running, PhaseTransitionTimestamp: metav1.NewTime(vm.CreationTimestamp.Add(20 * time.Second))},
+			},
 		},
 	}
 
 	vmi.Name = vm.Name
 	vmi.Namespace = namespace
+	vmi.Status.Phase = kvcorev1.Running
+	// the I/O baseline VM runs its workload once and powers off
+	if vm.Spec.RunStrategy != nil && *vm.Spec.RunStrategy == kvcorev1.RunStrategyRerunOnFailure {
+		vmi.Status.Phase = kvcorev1.Succeeded
+		if cs.failIOBaselineVM {
+			vmi.Status.Phase = kvcorev1.Failed
+		}
+	}
+	vmi.Status.NodeName = "node-0"
+	if cs.vmiNotMigratable {
+		vmi.Status.Conditions[1].Status = corev1.ConditionFalse
+		vmi.Status.Conditions[1].Reason = kvcorev1.VirtualMachineInstanceReasonDisksNotMigratable
+		vmi.Status.Conditions[1].Message = "cannot migrate VMI: PVC is not shared, live migration requires that all PVCs " +
+			"must be shared (using ReadWriteMany access mode)"
+	}
+	// the concurrently booted VMs are the ones having a blank DataVolume
+	if cs.concurrentBootAgentNotConnected && len(vm.Spec.DataVolumeTemplates) > 1 {
+		vmi.Status.Conditions[2].Status = corev1.ConditionFalse
+		vmi.Status.Conditions[2].Reason = "GuestAgentNotConnected"
+	}
 	cs.createdVMIs[vmFullName] = vmi
 
 	return vm, nil
 }
 
+// PatchVirtualMachine stops or starts the VM, or migrates the VMI to the patched DataVolume otherwise
+func (cs *clientStub) PatchVirtualMachine(ctx context.Context, namespace, name string, patch []byte) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	vmiFullName := objectFullName(namespace, name)
+	if _, exist := cs.createdVMs[vmiFullName]; !exist {
+		return errors.NewNotFound(schema.GroupResource{Group: "kubevirt.io", Resource: "virtualmachines"}, name)
+	}
+
+	var ops []struct {
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return err
+	}
+	if len(ops) == 1 && ops[0].Path == "/spec/runStrategy" {
+		if ops[0].Value == string(kvcorev1.RunStrategyHalted) {
+			cs.stoppedVMIs[vmiFullName] = cs.createdVMIs[vmiFullName]
+			delete(cs.createdVMIs, vmiFullName)
+		} else if vmi, stopped := cs.stoppedVMIs[vmiFullName]; stopped {
+			cs.createdVMIs[vmiFullName] = vmi
+			delete(cs.stoppedVMIs, vmiFullName)
+		}
+		return nil
+	}
+
+	if cs.rejectStorageMigration {
+		return errors.NewBadRequest("admission webhook denied the request: volume migration is not supported")
+	}
+	vmi, exist := cs.createdVMIs[vmiFullName]
+	if !exist {
+		return errors.NewNotFound(schema.GroupResource{Group: "kubevirt.io", Resource: "virtualmachineinstances"}, name)
+	}
+	for _, op := range ops {
+		var i int
+		if _, err := fmt.Sscanf(op.Path, "/spec/template/spec/volumes/%d/dataVolume/name", &i); err == nil {
+			vmi.Spec.Volumes[i].DataVolume = &kvcorev1.DataVolumeSource{Name: op.Value.(string)}
+		}
+	}
+
+	start := metav1.NewTime(time.Now())
+	end := metav1.NewTime(start.Add(42 * time.Second))
+	vmi.Status.MigrationState = &kvcorev1.VirtualMachineInstanceMigrationState{
+		MigrationUID:   "storage-migration",
+		Mode:           kvcorev1.MigrationPreCopy,
+		StartTimestamp: &start,
+		EndTimestamp:   &end,
+		Completed:      !cs.failStorageMigration,
+		Failed:         cs.failStorageMigration,
+	}
+
+	return nil
+}
+
 func (cs *clientStub) DeleteVirtualMachine(ctx context.Context, namespace, name string) error {
 	if cs.vmDeletionFailure != nil {
 		return cs.vmDeletionFailure
 	}
 
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
 	vmFullName := objectFullName(namespace, name)
 	if _, exist := cs.createdVMs[vmFullName]; !exist {
 		return errors.NewNotFound(schema.GroupResource{Group: "kubevirt.io", Resource: "virtualmachines"}, name)
 	}
-	if _, exist := cs.createdVMIs[vmFullName]; !exist {
-		return errors.NewNotFound(schema.GroupResource{Group: "kubevirt.io", Resource: "virtualmachineinstances"}, name)
-	}
-
 	if !cs.skipDeletion {
 		delete(cs.createdVMs, vmFullName)
 		delete(cs.createdVMIs, vmFullName)
@@ -384,6 +1472,9 @@ func (cs *clientStub) GetVirtualMachineInstance(ctx context.Context, namespace,
 		return nil, cs.vmiGetFailure
 	}
 
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
 	vmiFullName := objectFullName(namespace, name)
 	vmi, exist := cs.createdVMIs[vmiFullName]
 	if !exist {
@@ -414,6 +1505,32 @@ func (cs *clientStub) CreateVirtualMachineInstanceMigration(ctx context.Context,
 	return vmim, nil
 }
 
+func (cs *clientStub) CreateUnstructuredVirtualMachineInstanceMigration(ctx context.Context, namespace string,
+	vmim *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	name, _, _ := unstructured.NestedString(vmim.Object, "spec", "vmiName")
+	vmi, exist := cs.createdVMIs[objectFullName(namespace, name)]
+	if !exist {
+		return nil, errors.NewNotFound(schema.GroupResource{Group: "kubevirt.io", Resource: "virtualmachineinstances"}, name)
+	}
+	target, _, _ := unstructured.NestedString(vmim.Object, "spec", "addedNodeSelector", corev1.LabelHostname)
+	if cs.migrationIgnoreNodeSelector {
+		target = vmi.Status.NodeName
+	}
+	start := metav1.Now()
+	end := metav1.NewTime(start.Add(4 * time.Second))
+	vmi.Status.MigrationState = &kvcorev1.VirtualMachineInstanceMigrationState{
+		MigrationUID:   types.UID(vmim.GetName()),
+		SourceNode:     vmi.Status.NodeName,
+		TargetNode:     target,
+		StartTimestamp: &start,
+		EndTimestamp:   &end,
+		Completed:      true,
+	}
+	vmi.Status.NodeName = target
+
+	return vmim, nil
+}
+
 func (cs *clientStub) AddVirtualMachineInstanceVolume(ctx context.Context, namespace, name string,
 	addVolumeOptions *kvcorev1.AddVolumeOptions) error {
 	vmiFullName := objectFullName(namespace, name)
@@ -457,10 +1574,116 @@ func (cs *clientStub) DeleteDataVolume(ctx context.Context, namespace, name stri
 	return nil
 }
 
+// GetDataVolume reports an upload DataVolume as UploadReady on the first poll and as done afterwards, and a VM DataVolume
+// as ready 5 seconds after the VM creation
+func (cs *clientStub) GetDataVolume(ctx context.Context, namespace, name string) (*cdiv1.DataVolume, error) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	dv := &cdiv1.DataVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status:     cdiv1.DataVolumeStatus{Phase: cdiv1.Succeeded},
+	}
+	vmName := strings.TrimSuffix(strings.TrimSuffix(name, "-blank"), "-dv")
+	if vm, exist := cs.createdVMs[objectFullName(namespace, vmName)]; exist {
+		dv.Status.Conditions = []cdiv1.DataVolumeCondition{{Type: cdiv1.DataVolumeReady, Status: corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(vm.CreationTimestamp.Add(5 * time.Second))}}
+	}
+	if cs.bootTimingLookupFailure && strings.HasPrefix(name, checkup.VMIUnderTestNamePrefix) {
+		return nil, errors.NewServiceUnavailable("etcdserver: request timed out")
+	}
+	dvFullName := objectFullName(namespace, name)
+	cs.dataVolumePolls[dvFullName]++
+	if name == "checkup-import-qcow2" && cs.failImportDv {
+		dv.Status.Phase = cdiv1.Failed
+		dv.Status.Conditions = []cdiv1.DataVolumeCondition{
+			{Type: cdiv1.DataVolumeRunning, Status: corev1.ConditionFalse, Message: "Unable to process data"},
+		}
+	}
+	if name != "checkup-upload" {
+		return dv, nil
+	}
+	if cs.dataVolumePolls[dvFullName] == 1 {
+		dv.Status.Phase = cdiv1.UploadReady
+	} else if cs.failUploadDv {
+		dv.Status.Phase = cdiv1.Failed
+		dv.Status.Conditions = []cdiv1.DataVolumeCondition{
+			{Type: cdiv1.DataVolumeRunning, Status: corev1.ConditionFalse, Message: "Upload failed"},
+		}
+	}
+	return dv, nil
+}
+
+func (cs *clientStub) CreateUploadTokenRequest(ctx context.Context, namespace string, request *cdiuploadv1.UploadTokenRequest) (
+	*cdiuploadv1.UploadTokenRequest, error) {
+	request.Status.Token = testToken
+	return request, nil
+}
+
+func (cs *clientStub) CreateSubjectAccessReview(ctx context.Context, sar *authv1.SubjectAccessReview) (
+	*authv1.SubjectAccessReview, error) {
+	if cs.cloneNotAllowed && sar.Spec.User == "system:serviceaccount:"+testNamespace+":default" {
+		sar.Status.Reason = "no RBAC policy matched"
+		return sar, nil
+	}
+	sar.Status.Allowed = true
+	return sar, nil
+}
+
 func (cs *clientStub) DeletePersistentVolumeClaim(ctx context.Context, namespace, name string) error {
 	return nil
 }
 
+func (cs *clientStub) CreatePod(ctx context.Context, namespace string, pod *corev1.Pod) (*corev1.Pod, error) {
+	pod.Namespace = namespace
+	pod.Status.Phase = corev1.PodSucceeded
+	if pod.Name == "checkup-io-baseline" {
+		cs.ioBaselinePod = pod
+		if cs.failIOBaselinePod {
+			pod.Status.Phase = corev1.PodFailed
+		}
+	}
+	if args := pod.Spec.Containers[0].Args; len(args) != 0 && args[0] == imageserver.Command {
+		cs.imageServerPod = pod
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.PodIP = testPodIP
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	}
+	cs.createdPods[objectFullName(namespace, pod.Name)] = pod
+	return pod, nil
+}
+
+func (cs *clientStub) DeletePod(ctx context.Context, namespace, name string) error {
+	podFullName := objectFullName(namespace, name)
+	if _, exist := cs.createdPods[podFullName]; !exist {
+		return errors.NewNotFound(schema.GroupResource{Resource: "pods"}, name)
+	}
+	delete(cs.createdPods, podFullName)
+	return nil
+}
+
+func (cs *clientStub) CreateVolumeSnapshot(ctx context.Context, namespace string, snapshot *snapshotv1.VolumeSnapshot) (
+	*snapshotv1.VolumeSnapshot, error) {
+	ready := !cs.failVolumeSnapshot
+	restoreSize := resource.MustParse("100Mi")
+	snapshot.Status = &snapshotv1.VolumeSnapshotStatus{ReadyToUse: &ready, RestoreSize: &restoreSize}
+	if cs.failVolumeSnapshot {
+		msg := "Failed to check and update snapshot content"
+		snapshot.Status.Error = &snapshotv1.VolumeSnapshotError{Message: &msg}
+	}
+	cs.createdSnapshots[objectFullName(namespace, snapshot.Name)] = snapshot
+	return snapshot, nil
+}
+
+func (cs *clientStub) DeleteVolumeSnapshot(ctx context.Context, namespace, name string) error {
+	snapFullName := objectFullName(namespace, name)
+	if _, exist := cs.createdSnapshots[snapFullName]; !exist {
+		return errors.NewNotFound(schema.GroupResource{Group: "snapshot.storage.k8s.io", Resource: "volumesnapshots"}, name)
+	}
+	delete(cs.createdSnapshots, snapFullName)
+	return nil
+}
+
 func (cs *clientStub) ListNodes(ctx context.Context) (*corev1.NodeList, error) {
 	nodeList := &corev1.NodeList{}
 	itemCount := 2
@@ -471,6 +1694,10 @@ func (cs *clientStub) ListNodes(ctx context.Context) (*corev1.NodeList, error) {
 		nodeList.Items = append(nodeList.Items, corev1.Node{
 			ObjectMeta: metav1.ObjectMeta{
 				Name: fmt.Sprintf("node-%d", i),
+				Labels: map[string]string{
+					kvcorev1.NodeSchedulable: checkup.StrTrue,
+					corev1.LabelTopologyZone: fmt.Sprintf("zone-%c", 'a'+i),
+				},
 			},
 		})
 	}
@@ -505,6 +1732,7 @@ func (cs *clientStub) ListStorageClasses(ctx context.Context) (*storagev1.Storag
 						checkup.AnnDefaultStorageClass:     checkup.StrFalse,
 					},
 				},
+				Provisioner: testScName,
 			},
 			{
 				ObjectMeta: metav1.ObjectMeta{
@@ -517,6 +1745,13 @@ func (cs *clientStub) ListStorageClasses(ctx context.Context) (*storagev1.Storag
 			},
 		},
 	}
+	if cs.defaultScNotCSI {
+		scList.Items[0].Provisioner = "example.com/external-provisioner"
+	}
+	if cs.cdiNoHonorWFFC {
+		wffc := storagev1.VolumeBindingWaitForFirstConsumer
+		scList.Items[0].VolumeBindingMode = &wffc
+	}
 	if cs.onlyDefaultStorageClass || cs.noDefaultStorageClass {
 		scList.Items[0].Annotations[checkup.AnnDefaultVirtStorageClass] = checkup.StrFalse
 	}
@@ -529,6 +1764,32 @@ func (cs *clientStub) ListStorageClasses(ctx context.Context) (*storagev1.Storag
 	if cs.multipleDefaultVirtStorageClasses {
 		scList.Items[1].Annotations[checkup.AnnDefaultVirtStorageClass] = checkup.StrTrue
 	}
+	if cs.unhealthyStorage {
+		wffc := storagev1.VolumeBindingWaitForFirstConsumer
+		scList.Items = append(scList.Items, storagev1.StorageClass{
+			ObjectMeta:        metav1.ObjectMeta{Name: testWFFCScName},
+			Provisioner:       testScName,
+			VolumeBindingMode: &wffc,
+		})
+	}
+	if cs.vmiNotMigratable {
+		scList.Items[1].Provisioner = testScName
+		scList.Items = append(scList.Items, storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "test-sc-nfs"},
+			Provisioner: testScName,
+			Parameters:  map[string]string{"csi.storage.k8s.io/fstype": "nfs"},
+		})
+	}
+	if cs.nonVirtRbdStorageClass {
+		scList.Items = append(scList.Items, storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "test-sc-ceph-rbd"},
+			Provisioner: testRbdProvisioner,
+		}, storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "test-sc-ceph-rbd-virtualization"},
+			Provisioner: testRbdProvisioner,
+			Parameters:  map[string]string{"mounter": "rbd", "mapOptions": "krbd:rxbounce"},
+		})
+	}
 	if cs.unsetEfsStorageClass {
 		scList.Items = append(scList.Items, storagev1.StorageClass{
 			ObjectMeta: metav1.ObjectMeta{
@@ -550,12 +1811,17 @@ func (cs *clientStub) ListStorageProfiles(ctx context.Context) (*cdiv1.StoragePr
 					Name: testScName,
 				},
 				Status: cdiv1.StorageProfileStatus{
+					StorageClass:      &testScName,
 					Provisioner:       &testScName,
 					ClaimPropertySets: []cdiv1.ClaimPropertySet{{AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}}},
 				},
 			},
 		},
 	}
+	if cs.spInvalidSnapshotClass {
+		vscName := "missing-vsc"
+		spList.Items[0].Spec.SnapshotClass = &vscName
+	}
 	if cs.spIncomplete {
 		spList.Items[0].Status.ClaimPropertySets = []cdiv1.ClaimPropertySet{}
 		spList.Items[0].Spec.ClaimPropertySets = []cdiv1.ClaimPropertySet{
@@ -565,6 +1831,61 @@ func (cs *clientStub) ListStorageProfiles(ctx context.Context) (*cdiv1.StoragePr
 	return spList, nil
 }
 
+func (cs *clientStub) ListCSIDrivers(ctx context.Context) (*storagev1.CSIDriverList, error) {
+	fsGroupPolicy := storagev1.FileFSGroupPolicy
+	if cs.csiDriverFsGroupPolicyRWO {
+		fsGroupPolicy = storagev1.ReadWriteOnceWithFSTypeFSGroupPolicy
+	}
+	attachRequired := true
+	driverList := &storagev1.CSIDriverList{
+		Items: []storagev1.CSIDriver{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testScName,
+				},
+				Spec: storagev1.CSIDriverSpec{
+					AttachRequired:       &attachRequired,
+					FSGroupPolicy:        &fsGroupPolicy,
+					VolumeLifecycleModes: []storagev1.VolumeLifecycleMode{storagev1.VolumeLifecyclePersistent},
+				},
+			},
+		},
+	}
+	return driverList, nil
+}
+
+func (cs *clientStub) ListCSINodes(ctx context.Context) (*storagev1.CSINodeList, error) {
+	if cs.csiNodesForbidden {
+		return nil, errors.NewForbidden(schema.GroupResource{Group: "storage.k8s.io", Resource: "csinodes"}, "",
+			fmt.Errorf("no RBAC permission"))
+	}
+	csiNodeList := &storagev1.CSINodeList{}
+	if cs.csiDriverNotRegistered {
+		return csiNodeList, nil
+	}
+	allocatable := int32(39)
+	if cs.attachLimitLow {
+		allocatable = 2
+	}
+	for i := 0; i < 2; i++ {
+		csiNode := storagev1.CSINode{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("node-%d", i),
+			},
+			Spec: storagev1.CSINodeSpec{
+				Drivers: []storagev1.CSINodeDriver{{Name: testScName, NodeID: fmt.Sprintf("node-%d", i)}},
+			},
+		}
+		if i == 0 {
+			csiNode.Spec.Drivers[0].Allocatable = &storagev1.VolumeNodeResources{Count: &allocatable}
+		} else if cs.csiNodeMissingDriver {
+			csiNode.Spec.Drivers = nil
+		}
+		csiNodeList.Items = append(csiNodeList.Items, csiNode)
+	}
+	return csiNodeList, nil
+}
+
 func (cs *clientStub) ListVolumeSnapshotClasses(ctx context.Context) (*snapshotv1.VolumeSnapshotClassList, error) {
 	if cs.noVolumeSnapshotClasses {
 		return &snapshotv1.VolumeSnapshotClassList{}, nil
@@ -573,12 +1894,25 @@ func (cs *clientStub) ListVolumeSnapshotClasses(ctx context.Context) (*snapshotv
 		Items: []snapshotv1.VolumeSnapshotClass{
 			{
 				ObjectMeta: metav1.ObjectMeta{
-					Name: testScName,
+					Name:        testScName,
+					Annotations: map[string]string{checkup.AnnDefaultSnapshotClass: "true"},
 				},
-				Driver: testScName,
+				Driver:         testScName,
+				DeletionPolicy: snapshotv1.VolumeSnapshotContentDelete,
 			},
 		},
 	}
+	if cs.noDefaultVolumeSnapshotClass {
+		vscList.Items[0].Annotations = nil
+	}
+	if cs.multipleDefaultVolumeSnapshotCls {
+		vsc := vscList.Items[0].DeepCopy()
+		vsc.Name = testScName + "-2"
+		vscList.Items = append(vscList.Items, *vsc)
+	}
+	if cs.retainVolumeSnapshotClass {
+		vscList.Items[0].DeletionPolicy = snapshotv1.VolumeSnapshotContentRetain
+	}
 	return vscList, nil
 }
 
@@ -587,6 +1921,19 @@ func (cs *clientStub) ListDataImportCrons(ctx context.Context, namespace string)
 		return &cdiv1.DataImportCronList{}, nil
 	}
 
+	lastImport := time.Now().Add(-time.Hour)
+	if cs.goldenImageOutdated {
+		lastImport = time.Now().Add(-40 * 24 * time.Hour)
+	}
+	lastExecution := time.Now()
+	if cs.dicMissedSchedule {
+		lastExecution = time.Now().Add(-5 * 24 * time.Hour)
+	}
+	schedule := "0 */12 * * *"
+	if cs.dicInvalidSchedule {
+		schedule = "@every 12h"
+	}
+
 	dicList := &cdiv1.DataImportCronList{
 		Items: []cdiv1.DataImportCron{
 			{
@@ -594,7 +1941,23 @@ func (cs *clientStub) ListDataImportCrons(ctx context.Context, namespace string)
 					Name:      testDIC,
 					Namespace: testNamespace,
 				},
+				Spec: cdiv1.DataImportCronSpec{
+					Schedule:          schedule,
+					ManagedDataSource: "test-das",
+					Template: cdiv1.DataVolume{
+						Spec: cdiv1.DataVolumeSpec{
+							Source: &cdiv1.DataVolumeSource{
+								Registry: &cdiv1.DataVolumeSourceRegistry{
+									URL: &testDICURL,
+								},
+							},
+						},
+					},
+				},
 				Status: cdiv1.DataImportCronStatus{
+					CurrentImports:         []cdiv1.ImportStatus{{Digest: "sha256:1234"}},
+					LastImportTimestamp:    &metav1.Time{Time: lastImport},
+					LastExecutionTimestamp: &metav1.Time{Time: lastExecution},
 					Conditions: []cdiv1.DataImportCronCondition{
 						{
 							Type: cdiv1.DataImportCronUpToDate,
@@ -610,6 +1973,240 @@ func (cs *clientStub) ListDataImportCrons(ctx context.Context, namespace string)
 	return dicList, nil
 }
 
+// ListServices returns the cdi-uploadproxy service, owned by the CDI CR and routed to the upload proxy stand-in
+func (cs *clientStub) ListServices(ctx context.Context, namespace, labelSelector string) (*corev1.ServiceList, error) {
+	svcs := &corev1.ServiceList{}
+	if labelSelector != "cdi.kubevirt.io=cdi-uploadproxy" || cs.uploadProxyServiceMissing {
+		return svcs, nil
+	}
+	addr := uploadProxy.Listener.Addr().(*net.TCPAddr)
+	svcs.Items = append(svcs.Items, corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "cdi-uploadproxy",
+			Namespace:       testCDINamespace,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "CDI", Name: "cdi", UID: types.UID(testCDIUID)}},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: addr.IP.String(),
+			Ports:     []corev1.ServicePort{{Port: int32(addr.Port)}},
+		},
+	})
+	return svcs, nil
+}
+
+func (cs *clientStub) ListPersistentVolumeClaims(ctx context.Context, namespace, labelSelector string) (
+	*corev1.PersistentVolumeClaimList, error) {
+	if labelSelector == "" {
+		return cs.unhealthyPersistentVolumeClaims(), nil
+	}
+	imports := 1
+	if cs.dicExcessImports {
+		imports = 5
+	}
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	for i := 0; i < imports; i++ {
+		pvcs.Items = append(pvcs.Items, corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", testDIC, i), Namespace: namespace},
+		})
+	}
+	return pvcs, nil
+}
+
+// unhealthyPersistentVolumeClaims returns a PVC stuck Pending, a PVC stuck Terminating and a WaitForFirstConsumer PVC
+// stuck Pending on its selected node in testVMNamespace. A WaitForFirstConsumer PVC with no selected node, and a
+// Pending PVC in a namespace with no VMs are ignored.
+func (cs *clientStub) unhealthyPersistentVolumeClaims() *corev1.PersistentVolumeClaimList {
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if !cs.unhealthyStorage {
+		return pvcs
+	}
+	created := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	deleted := metav1.NewTime(time.Now().Add(-30 * time.Minute))
+	pvcs.Items = []corev1.PersistentVolumeClaim{{
+		ObjectMeta: metav1.ObjectMeta{Name: "pending-pvc", Namespace: testVMNamespace, CreationTimestamp: created},
+		Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &testScName},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "terminating-pvc", Namespace: testVMNamespace, CreationTimestamp: created,
+			DeletionTimestamp: &deleted, Finalizers: []string{"kubernetes.io/pvc-protection"}},
+		Spec:   corev1.PersistentVolumeClaimSpec{StorageClassName: &testScName2},
+		Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "wffc-selected-pvc", Namespace: testVMNamespace, CreationTimestamp: created,
+			Annotations: map[string]string{"volume.kubernetes.io/selected-node": testNode}},
+		Spec:   corev1.PersistentVolumeClaimSpec{StorageClassName: &testWFFCScName},
+		Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "wffc-unconsumed-pvc", Namespace: testVMNamespace, CreationTimestamp: created},
+		Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &testWFFCScName},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "pending-pvc", Namespace: "other-ns", CreationTimestamp: created},
+		Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &testScName},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "importing-dv", Namespace: testVMNamespace, CreationTimestamp: created},
+		Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &testScName},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+	}}
+	return pvcs
+}
+
+func (cs *clientStub) ListVirtualMachines(ctx context.Context, namespace string) (*kvcorev1.VirtualMachineList, error) {
+	vms := &kvcorev1.VirtualMachineList{}
+	if cs.unhealthyStorage {
+		vms.Items = append(vms.Items, kvcorev1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: testVMNamespace},
+			Spec: kvcorev1.VirtualMachineSpec{Template: &kvcorev1.VirtualMachineInstanceTemplateSpec{
+				Spec: kvcorev1.VirtualMachineInstanceSpec{Volumes: []kvcorev1.Volume{{
+					Name:         "disk",
+					VolumeSource: kvcorev1.VolumeSource{DataVolume: &kvcorev1.DataVolumeSource{Name: "released-disk"}},
+				}}},
+			}},
+		})
+	}
+	return vms, nil
+}
+
+func (cs *clientStub) ListDataVolumes(ctx context.Context, namespace string) (*cdiv1.DataVolumeList, error) {
+	dvs := &cdiv1.DataVolumeList{}
+	if !cs.unhealthyStorage {
+		return dvs, nil
+	}
+	created := metav1.NewTime(time.Now().Add(-3 * 24 * time.Hour))
+	dvs.Items = []cdiv1.DataVolume{{
+		ObjectMeta: metav1.ObjectMeta{Name: "importing-dv", Namespace: testVMNamespace, CreationTimestamp: created},
+		Status:     cdiv1.DataVolumeStatus{Phase: cdiv1.ImportInProgress},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "failed-dv", Namespace: testVMNamespace, CreationTimestamp: created},
+		Spec:       cdiv1.DataVolumeSpec{Storage: &cdiv1.StorageSpec{StorageClassName: &testScName2}},
+		Status:     cdiv1.DataVolumeStatus{Phase: cdiv1.Failed},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "cloning-dv", Namespace: testVMNamespace, CreationTimestamp: metav1.Now()},
+		Status:     cdiv1.DataVolumeStatus{Phase: cdiv1.CloneInProgress},
+	}}
+	return dvs, nil
+}
+
+func (cs *clientStub) ListPersistentVolumes(ctx context.Context) (*corev1.PersistentVolumeList, error) {
+	pvs := &corev1.PersistentVolumeList{}
+	if !cs.unhealthyStorage {
+		return pvs, nil
+	}
+	created := metav1.NewTime(time.Now().Add(-3 * 24 * time.Hour))
+	pvs.Items = []corev1.PersistentVolume{{
+		ObjectMeta: metav1.ObjectMeta{Name: "released-pv", CreationTimestamp: created},
+		Spec: corev1.PersistentVolumeSpec{
+			StorageClassName: testScName,
+			ClaimRef:         &corev1.ObjectReference{Namespace: testVMNamespace, Name: "released-disk"},
+		},
+		Status: corev1.PersistentVolumeStatus{Phase: corev1.VolumeReleased},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Name: "other-pv", CreationTimestamp: created},
+		Spec: corev1.PersistentVolumeSpec{
+			StorageClassName: testScName,
+			ClaimRef:         &corev1.ObjectReference{Namespace: "other-ns", Name: "other-pvc"},
+		},
+		Status: corev1.PersistentVolumeStatus{Phase: corev1.VolumeReleased},
+	}}
+	return pvs, nil
+}
+
+// ListVolumeAttachments returns the attachments of test-pvc and of the DataVolumes of the created VMIs, all attached
+// to node-0 3 seconds after their creation
+func (cs *clientStub) ListVolumeAttachments(ctx context.Context) (*storagev1.VolumeAttachmentList, error) {
+	pvNames := []string{"pv-test-pvc"}
+	for _, vmi := range cs.createdVMIs {
+		for _, vol := range vmi.Spec.Volumes {
+			if vol.DataVolume != nil {
+				pvNames = append(pvNames, "pv-"+vol.DataVolume.Name)
+			}
+		}
+	}
+	sort.Strings(pvNames)
+
+	created := metav1.NewTime(time.Now().Add(-time.Minute))
+	attached := metav1.NewTime(created.Add(3 * time.Second))
+	vas := &storagev1.VolumeAttachmentList{}
+	for i := range pvNames {
+		va := storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "csi-" + pvNames[i],
+				CreationTimestamp: created,
+				ManagedFields:     []metav1.ManagedFieldsEntry{{Subresource: "status", Time: &attached}},
+			},
+			Spec: storagev1.VolumeAttachmentSpec{
+				Attacher: testScName,
+				NodeName: "node-0",
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvNames[i]},
+			},
+			Status: storagev1.VolumeAttachmentStatus{Attached: true},
+		}
+		if pvNames[i] == "pv-test-pvc" && cs.vaAttachError {
+			va.Status.AttachError = &storagev1.VolumeError{Message: "Multi-Attach error for volume pv-test-pvc"}
+		}
+		if pvNames[i] == "pv-test-pvc" && (cs.vaStale || cs.vaStaleDeleting || cs.vaStaleMigrated) {
+			va.Spec.NodeName = "node-1"
+		}
+		if pvNames[i] == "pv-test-pvc" && cs.vaStaleDeleting {
+			deleted := metav1.NewTime(time.Now())
+			va.DeletionTimestamp = &deleted
+		}
+		vas.Items = append(vas.Items, va)
+	}
+	return vas, nil
+}
+
+func (cs *clientStub) ListVolumeSnapshots(ctx context.Context, namespace, labelSelector string) (
+	*snapshotv1.VolumeSnapshotList, error) {
+	return &snapshotv1.VolumeSnapshotList{}, nil
+}
+
+func (cs *clientStub) ListPods(ctx context.Context, namespace, labelSelector string) (*corev1.PodList, error) {
+	pods := &corev1.PodList{}
+	if labelSelector == "kubevirt.io=virt-launcher" {
+		pods.Items = append(pods.Items, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "virt-launcher-" + testVMIName, Namespace: namespace},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "compute"}}},
+		})
+		return pods, nil
+	}
+	if cs.hotplugAttachmentPod {
+		pods.Items = append(pods.Items, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "hp-volume-test", Namespace: testNamespace},
+			Spec: corev1.PodSpec{
+				NodeName: "node-0",
+				Volumes: []corev1.Volume{{Name: "hp-pvc", VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "hp-pvc"},
+				}}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		})
+	}
+	return pods, nil
+}
+
+func (cs *clientStub) ListResourceQuotas(ctx context.Context, namespace string) (*corev1.ResourceQuotaList, error) {
+	if cs.resourceQuotasForbidden {
+		return nil, errors.NewForbidden(schema.GroupResource{Resource: "resourcequotas"}, "", fmt.Errorf("no RBAC permission"))
+	}
+	quotas := &corev1.ResourceQuotaList{}
+	if cs.cdiQuotaExceeded && namespace == testNamespace {
+		quotas.Items = append(quotas.Items, corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: namespace},
+			Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{
+				corev1.ResourceLimitsCPU:                resource.MustParse("1"),
+				corev1.ResourceRequestsStorage:          resource.MustParse("1Ti"),
+				corev1.ResourceRequestsEphemeralStorage: resource.MustParse("1Gi"),
+			}},
+			Status: corev1.ResourceQuotaStatus{Used: corev1.ResourceList{
+				corev1.ResourceLimitsCPU: resource.MustParse("500m"),
+			}},
+		})
+	}
+	return quotas, nil
+}
+
 func (cs *clientStub) ListVirtualMachinesInstances(ctx context.Context, namespace string) (*kvcorev1.VirtualMachineInstanceList, error) {
 	vmiList := &kvcorev1.VirtualMachineInstanceList{
 		Items: []kvcorev1.VirtualMachineInstance{
@@ -633,7 +2230,8 @@ func (cs *clientStub) ListVirtualMachinesInstances(ctx context.Context, namespac
 					},
 				},
 				Status: kvcorev1.VirtualMachineInstanceStatus{
-					Phase: kvcorev1.Running,
+					Phase:    kvcorev1.Running,
+					NodeName: "node-0",
 					Conditions: []kvcorev1.VirtualMachineInstanceCondition{
 						{
 							Type:   kvcorev1.VirtualMachineInstanceReady,
@@ -648,6 +2246,15 @@ func (cs *clientStub) ListVirtualMachinesInstances(ctx context.Context, namespac
 			},
 		},
 	}
+	if cs.vaStaleMigrated {
+		end := metav1.NewTime(time.Now().Add(-time.Minute))
+		vmiList.Items[0].Status.MigrationState = &kvcorev1.VirtualMachineInstanceMigrationState{
+			SourceNode:   "node-1",
+			TargetNode:   "node-0",
+			EndTimestamp: &end,
+			Completed:    true,
+		}
+	}
 
 	return vmiList, nil
 }
@@ -661,6 +2268,103 @@ func (cs *clientStub) GetNamespace(ctx context.Context, name string) (*corev1.Na
 	return ns, nil
 }
 
+func (cs *clientStub) GetConfigMap(ctx context.Context, namespace, name string) (*corev1.ConfigMap, error) {
+	if name != "cdi-uploadproxy-signer-bundle" || cs.uploadProxyUnknownCA {
+		return nil, errors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+	}
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: uploadProxy.Certificate().Raw})
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       map[string]string{"ca-bundle.crt": string(caBundle)},
+	}, nil
+}
+
+func (cs *clientStub) CreateConfigMap(ctx context.Context, namespace string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	cmFullName := objectFullName(namespace, cm.Name)
+	if _, exist := cs.createdConfigMaps[cmFullName]; exist {
+		return nil, errors.NewAlreadyExists(schema.GroupResource{Resource: "configmaps"}, cm.Name)
+	}
+	cs.createdConfigMaps[cmFullName] = cm
+	return cm, nil
+}
+
+func (cs *clientStub) UpdateConfigMap(ctx context.Context, namespace string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	cs.createdConfigMaps[objectFullName(namespace, cm.Name)] = cm
+	return cm, nil
+}
+
+func (cs *clientStub) GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
+	if name == testPodName {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: corev1.PodSpec{
+				ServiceAccountName: testPodSA,
+				Containers:         []corev1.Container{{Name: "storage-checkup", Image: testPodImage}},
+			},
+		}
+		return pod, nil
+	}
+
+	pod, exist := cs.createdPods[objectFullName(namespace, name)]
+	if !exist {
+		return nil, errors.NewNotFound(schema.GroupResource{Resource: "pods"}, name)
+	}
+	return pod, nil
+}
+
+func (cs *clientStub) ListVirtualMachineInstanceMigrations(ctx context.Context, namespace string) (
+	*kvcorev1.VirtualMachineInstanceMigrationList, error) {
+	return &kvcorev1.VirtualMachineInstanceMigrationList{}, nil
+}
+
+func (cs *clientStub) ListEvents(ctx context.Context, namespace string) (*corev1.EventList, error) {
+	if cs.vaMultiAttach {
+		event := corev1.Event{
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: testNamespace, Name: "virt-launcher-test-vmi-abcde"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "FailedAttachVolume",
+			Message: "Multi-Attach error for volume \"pv-test-pvc\" Volume is already exclusively attached to one node " +
+				"and can't be attached to another",
+			LastTimestamp: metav1.NewTime(time.Now().Add(-5 * time.Minute)),
+		}
+		otherPod := event
+		otherPod.InvolvedObject.Name = "test-pod"
+		oldEvent := event
+		oldEvent.InvolvedObject.Name = "virt-launcher-old-vmi-fghij"
+		oldEvent.LastTimestamp = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+		return &corev1.EventList{Items: []corev1.Event{event, event, otherPod, oldEvent}}, nil
+	}
+	return &corev1.EventList{Items: []corev1.Event{{
+		InvolvedObject: corev1.ObjectReference{Kind: "DataVolume", Name: "checkup-pvc"},
+		Type:           corev1.EventTypeWarning,
+		Reason:         "Pending",
+		Message:        "PVC checkup-pvc Pending",
+		LastTimestamp:  metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Count:          3,
+	}, {
+		InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: "node-0"},
+		Type:           corev1.EventTypeNormal,
+		Reason:         "NodeReady",
+	}}}, nil
+}
+
+// GetPodLogs returns the I/O baseline pod dd output, or a log line naming the requested pod container
+func (cs *clientStub) GetPodLogs(ctx context.Context, namespace, name, container string, tailLines int64) (string, error) {
+	if container != "" {
+		return fmt.Sprintf("%s %s log\n", name, container), nil
+	}
+	if name == "checkup-io-baseline-reader" {
+		return "write: 268435456 bytes (268 MB, 256 MiB) copied, 2.39674 s, 112 MB/s\n" +
+			"read: 268435456 bytes (268 MB, 256 MiB) copied, 0.699051 s, 384 MB/s\n", nil
+	}
+	logs := "write: 268435456 bytes (268 MB, 256 MiB) copied, 1.19837 s, 224 MB/s\n" +
+		"read: 268435456 bytes (268 MB, 256 MiB) copied, 0.524288 s, 512 MB/s\n"
+	return logs, nil
+}
+
 func (cs *clientStub) GetPersistentVolumeClaim(ctx context.Context, namespace, name string) (*corev1.PersistentVolumeClaim, error) {
 	blockMode := corev1.PersistentVolumeBlock
 	pvc := &corev1.PersistentVolumeClaim{
@@ -671,9 +2375,11 @@ func (cs *clientStub) GetPersistentVolumeClaim(ctx context.Context, namespace, n
 		Spec: corev1.PersistentVolumeClaimSpec{
 			VolumeMode:       &blockMode,
 			StorageClassName: &testScName,
+			VolumeName:       "pv-" + name,
 		},
 		Status: corev1.PersistentVolumeClaimStatus{
 			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			Capacity:    corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Mi")},
 		},
 	}
 
@@ -683,6 +2389,14 @@ func (cs *clientStub) GetPersistentVolumeClaim(ctx context.Context, namespace, n
 		pvc.Status.Phase = corev1.ClaimBound
 	}
 
+	if cs.goldenImageOtherSC && name == "test-pvc" {
+		pvc.Spec.StorageClassName = &testScName2
+	}
+
+	if cs.vmiNotMigratable && strings.HasSuffix(name, "-dv") {
+		pvc.Spec.StorageClassName = &testScName2
+	}
+
 	if cs.cloneFallback {
 		pvc.Annotations = map[string]string{
 			"cdi.kubevirt.io/cloneType":           "host-assisted",
@@ -709,14 +2423,44 @@ func (cs *clientStub) GetPersistentVolume(ctx context.Context, name string) (*co
 		}
 		pv.Spec.StorageClassName = "test-sc-unset-efs"
 	}
+	if cs.nonVirtRbdStorageClass {
+		pv.Spec.PersistentVolumeSource = corev1.PersistentVolumeSource{
+			CSI: &corev1.CSIPersistentVolumeSource{
+				Driver: testRbdProvisioner,
+			},
+		}
+		pv.Spec.StorageClassName = "test-sc-ceph-rbd"
+	}
 
 	return pv, nil
 }
 
 func (cs *clientStub) GetVolumeSnapshot(ctx context.Context, namespace, name string) (*snapshotv1.VolumeSnapshot, error) {
+	if snapshot, exist := cs.createdSnapshots[objectFullName(namespace, name)]; exist {
+		return snapshot, nil
+	}
+	if name == testGoldenImageSnapshot {
+		contentName := testGoldenImageSnapshot + "-content"
+		ready := true
+		return &snapshotv1.VolumeSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status: &snapshotv1.VolumeSnapshotStatus{
+				BoundVolumeSnapshotContentName: &contentName,
+				ReadyToUse:                     &ready,
+			},
+		}, nil
+	}
 	return nil, nil
 }
 
+// GetVolumeSnapshotContent returns the content of the golden image snapshot, which has no VolumeSnapshotClass
+func (cs *clientStub) GetVolumeSnapshotContent(ctx context.Context, name string) (*snapshotv1.VolumeSnapshotContent, error) {
+	return &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       snapshotv1.VolumeSnapshotContentSpec{Driver: testScName},
+	}, nil
+}
+
 func (cs *clientStub) GetCSIDriver(ctx context.Context, name string) (*storagev1.CSIDriver, error) {
 	return nil, nil
 }
@@ -749,12 +2493,45 @@ func (cs *clientStub) GetDataSource(ctx context.Context, namespace, name string)
 	if cs.dicNoDataSource {
 		das.Spec.Source = cdiv1.DataSourceSource{}
 	}
+	if cs.goldenImageSnapshot {
+		das.Spec.Source = cdiv1.DataSourceSource{
+			Snapshot: &cdiv1.DataVolumeSourceSnapshot{Name: testGoldenImageSnapshot, Namespace: testNamespace},
+		}
+	}
 	if cs.dataSourceNotReady {
 		das.Status.Conditions[0].Status = corev1.ConditionFalse
 	}
 	return das, nil
 }
 
+func (cs *clientStub) GetCDIConfig(ctx context.Context) (*cdiv1.CDIConfig, error) {
+	cdiConfig := &cdiv1.CDIConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "config"},
+		Status: cdiv1.CDIConfigStatus{
+			UploadProxyURL:           &testUploadProxyRoute,
+			ScratchSpaceStorageClass: testScName,
+			FilesystemOverhead: &cdiv1.FilesystemOverhead{
+				Global:       "0.055",
+				StorageClass: map[string]cdiv1.Percent{testScName: "0.1"},
+			},
+			DefaultPodResourceRequirements: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("60M"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("750m"),
+					corev1.ResourceMemory: resource.MustParse("600M"),
+				},
+			},
+		},
+	}
+	if cs.cdiMissingScratchSC {
+		cdiConfig.Status.ScratchSpaceStorageClass = "missing-sc"
+	}
+	return cdiConfig, nil
+}
+
 func (cs *clientStub) GetClusterVersion(ctx context.Context, name string) (*configv1.ClusterVersion, error) {
 	ver := &configv1.ClusterVersion{
 		Status: configv1.ClusterVersionStatus{
@@ -791,22 +2568,155 @@ func (cs *clientStub) GetKubeVirt(ctx context.Context, namespace, name string) (
 	return kv, nil
 }
 
+func (cs *clientStub) ListKubeVirts(ctx context.Context) (*kvcorev1.KubeVirtList, error) {
+	bandwidth := resource.MustParse("64Mi")
+	parallelMigrations := uint32(5)
+	kv := kvcorev1.KubeVirt{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubevirt", Namespace: "kubevirt"},
+		Spec: kvcorev1.KubeVirtSpec{
+			Configuration: kvcorev1.KubeVirtConfiguration{
+				DeveloperConfiguration: &kvcorev1.DeveloperConfiguration{
+					FeatureGates: []string{checkup.FeatureGateHotplugVolumes, checkup.FeatureGateSnapshot, checkup.FeatureGateVMExport,
+						checkup.FeatureGateVolumeMigration, checkup.FeatureGateVolumesUpdateStrategy, checkup.FeatureGateVMPersistentState},
+				},
+				VMStateStorageClass: testScName,
+				MigrationConfiguration: &kvcorev1.MigrationConfiguration{
+					BandwidthPerMigration:        &bandwidth,
+					ParallelMigrationsPerCluster: &parallelMigrations,
+				},
+			},
+		},
+	}
+	if cs.hotplugDisabled {
+		kv.Spec.Configuration.DeveloperConfiguration.FeatureGates = []string{checkup.FeatureGateSnapshot, checkup.FeatureGateVMExport,
+			checkup.FeatureGateVolumeMigration, checkup.FeatureGateVolumesUpdateStrategy, checkup.FeatureGateVMPersistentState}
+	}
+	if cs.volumeMigrationDisabled {
+		kv.Spec.Configuration.DeveloperConfiguration.FeatureGates = []string{checkup.FeatureGateHotplugVolumes,
+			checkup.FeatureGateSnapshot, checkup.FeatureGateVMExport, checkup.FeatureGateVolumesUpdateStrategy,
+			checkup.FeatureGateVMPersistentState}
+	}
+	if cs.volumesUpdateStrategyDisabled {
+		kv.Spec.Configuration.DeveloperConfiguration.FeatureGates = []string{checkup.FeatureGateHotplugVolumes,
+			checkup.FeatureGateSnapshot, checkup.FeatureGateVMExport, checkup.FeatureGateVolumeMigration,
+			checkup.FeatureGateVMPersistentState}
+	}
+	return &kvcorev1.KubeVirtList{Items: []kvcorev1.KubeVirt{kv}}, nil
+}
+
+func (cs *clientStub) GetUnstructuredKubeVirt(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	configuration := map[string]interface{}{"vmRolloutStrategy": checkup.VMRolloutStrategyLiveUpdate}
+	if cs.vmRolloutStrategyStage {
+		configuration["vmRolloutStrategy"] = "Stage"
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kubevirt.io/v1",
+		"kind":       "KubeVirt",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"spec":       map[string]interface{}{"configuration": configuration},
+	}}, nil
+}
+
 func (cs *clientStub) GetKubernetesVersion() (string, error) {
 	return "v1.28.2", nil
 }
 
+func (cs *clientStub) GetHyperConverged(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	templateSc := testScName
+	if cs.hcoMissingSC {
+		templateSc = "missing-sc"
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "hco.kubevirt.io/v1beta1",
+		"kind":       "HyperConverged",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"spec": map[string]interface{}{
+			"scratchSpaceStorageClass": testScName,
+			"storageImport": map[string]interface{}{
+				"insecureRegistries": []interface{}{"registry.local:5000"},
+			},
+			"dataImportCronTemplates": []interface{}{
+				map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "centos-stream9-image-cron"},
+					"spec": map[string]interface{}{
+						"schedule": "0 */12 * * *",
+						"template": map[string]interface{}{
+							"spec": map[string]interface{}{
+								"storage": map[string]interface{}{"storageClassName": templateSc},
+							},
+						},
+					},
+				},
+			},
+			"featureGates": map[string]interface{}{"nonRoot": true, "withHostPassthroughCPU": false},
+		},
+	}}, nil
+}
+
+func (cs *clientStub) CreateVirtualMachineExport(ctx context.Context, namespace string,
+	export *exportv1.VirtualMachineExport) (*exportv1.VirtualMachineExport, error) {
+	return export, nil
+}
+
+func (cs *clientStub) GetVirtualMachineExport(ctx context.Context, namespace, name string) (*exportv1.VirtualMachineExport, error) {
+	image := "disk.img"
+	if cs.exportBadImage {
+		image = "bad.img"
+	}
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: exportServer.Certificate().Raw})
+	return &exportv1.VirtualMachineExport{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status: &exportv1.VirtualMachineExportStatus{
+			Phase:       exportv1.Ready,
+			ServiceName: "virt-export-" + name,
+			Links: &exportv1.VirtualMachineExportLinks{
+				Internal: &exportv1.VirtualMachineExportLink{
+					Cert: string(cert),
+					Volumes: []exportv1.VirtualMachineExportVolume{{
+						Name: "disk",
+						Formats: []exportv1.VirtualMachineExportVolumeFormat{
+							{Format: exportv1.KubeVirtGz, Url: exportServer.URL + "/volumes/disk.img.gz"},
+							{Format: exportv1.KubeVirtRaw, Url: exportServer.URL + "/volumes/" + image},
+						},
+					}},
+				},
+			},
+		},
+	}, nil
+}
+
+func (cs *clientStub) DeleteVirtualMachineExport(ctx context.Context, namespace, name string) error {
+	return nil
+}
+
+func (cs *clientStub) CreateSecret(ctx context.Context, namespace string, secret *corev1.Secret) (*corev1.Secret, error) {
+	return secret, nil
+}
+
+func (cs *clientStub) DeleteSecret(ctx context.Context, namespace, name string) error {
+	return nil
+}
+
 func (cs *clientStub) ListCDIs(ctx context.Context) (*cdiv1.CDIList, error) {
 	cdis := &cdiv1.CDIList{
 		Items: []cdiv1.CDI{
 			{
 				ObjectMeta: metav1.ObjectMeta{
+					Name: "cdi",
+					UID:  types.UID(testCDIUID),
 					Labels: map[string]string{
 						"app.kubernetes.io/version": testCNVVersion,
 					},
 				},
+				Spec: cdiv1.CDISpec{
+					Config: &cdiv1.CDIConfigSpec{FeatureGates: []string{"HonorWaitForFirstConsumer"}},
+				},
 			},
 		},
 	}
+	if cs.cdiNoHonorWFFC {
+		cdis.Items[0].Spec.Config.FeatureGates = nil
+	}
 
 	return cdis, nil
 }
@@ -821,13 +2731,49 @@ func (cs *clientStub) VMIName(namePrefix string) string {
 	return ""
 }
 
+func goldenImageOnOtherSC(sc, targetSc string) string {
+	return fmt.Sprintf("%s: PVC target-ns/test-pvc is on storage class %q rather than %q, so VM disks are cloned "+
+		"host-assisted across storage classes. Recommendation: set spec.template.spec.storage.storageClassName of the "+
+		"DataImportCron to %q, or leave it unset to use the default virt storage class", testDICDataSource, sc, targetSc,
+		targetSc)
+}
+
 func newTestConfig() config.Config {
 	return config.Config{
-		PodName: testPodName,
-		PodUID:  testPodUID,
+		PodName:            testPodName,
+		PodUID:             testPodUID,
+		VMITimeout:         time.Second,
+		GoldenImageMaxAge:  30 * 24 * time.Hour,
+		TargetStorageClass: testScName2,
+
+		AttachHeadroomPercent: config.AttachHeadroomPercentDefault,
 	}
 }
 
 func objectFullName(namespace, name string) string {
 	return fmt.Sprintf("%s/%s", namespace, name)
 }
+
+// newServiceCertificate returns a self-signed certificate issued for the in-cluster name of a service
+func newServiceCertificate(dnsName string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: dnsName},
+		DNSNames:              []string{dnsName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
@@ -0,0 +1,138 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	authv1 "k8s.io/api/authorization/v1"
+
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+const (
+	// vmCloneServiceAccount is the service account CDI authorizes the clones of VM DataVolumeTemplates for
+	vmCloneServiceAccount  = "default"
+	cloneSourceResource    = "datavolumes"
+	cloneSourceSubresource = "source"
+
+	ErrGoldenImageClonePermission = "there are service accounts not permitted to clone from the golden image namespace"
+)
+
+type serviceAccountRef struct {
+	namespace string
+	name      string
+}
+
+// checkGoldenImageClonePermission checks with SubjectAccessReviews whether the service accounts involved in booting VMs
+// from the golden image may clone from its namespace, since CDI otherwise rejects the clone and the VM boot just
+// times out
+func (c *Checkup) checkGoldenImageClonePermission(ctx context.Context, errStr *string) error {
+	log.Print("checkGoldenImageClonePermission")
+
+	sourceNamespace := ""
+	if c.goldenImagePvc != nil {
+		sourceNamespace = c.goldenImagePvc.Namespace
+	} else if c.goldenImageSnap != nil {
+		sourceNamespace = c.goldenImageSnap.Namespace
+	}
+	if sourceNamespace == "" {
+		log.Print(MessageSkipNoGoldenImage)
+		c.results.GoldenImageClonePermission = MessageSkipNoGoldenImage
+		return nil
+	}
+
+	serviceAccounts, err := c.getCloneServiceAccounts(ctx)
+	if err != nil {
+		return err
+	}
+
+	denied := false
+	for _, sa := range serviceAccounts {
+		sar, err := c.client.CreateSubjectAccessReview(ctx, newCloneSubjectAccessReview(sa, sourceNamespace))
+		if err != nil {
+			return fmt.Errorf("failed to create SubjectAccessReview: %w", err)
+		}
+
+		res := fmt.Sprintf("service account %q may clone from namespace %q", sa, sourceNamespace)
+		if !sar.Status.Allowed {
+			denied = true
+			res = fmt.Sprintf("service account %q may not clone from namespace %q: missing rule apiGroups: [%q], "+
+				"resources: [%q], verbs: [%q] in namespace %q", sa, sourceNamespace,
+				cdiv1.SchemeGroupVersion.Group, cloneSourceResource+"/"+cloneSourceSubresource, "create", sourceNamespace)
+			if reason := sar.Status.Reason; reason != "" {
+				res += fmt.Sprintf(" (%s)", reason)
+			}
+		}
+		log.Print(res)
+		appendSep(&c.results.GoldenImageClonePermission, res)
+	}
+
+	if denied {
+		appendSep(errStr, ErrGoldenImageClonePermission)
+	}
+
+	return nil
+}
+
+func (sa serviceAccountRef) String() string {
+	return sa.namespace + "/" + sa.name
+}
+
+// getCloneServiceAccounts returns the checkup service account, the one the checkup VMs clone with and the tenant one
+func (c *Checkup) getCloneServiceAccounts(ctx context.Context) ([]serviceAccountRef, error) {
+	checkupPod, err := c.client.GetPod(ctx, c.namespace, c.checkupConfig.PodName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkup pod: %w", err)
+	}
+	checkupSA := checkupPod.Spec.ServiceAccountName
+	if checkupSA == "" {
+		checkupSA = vmCloneServiceAccount
+	}
+
+	serviceAccounts := []serviceAccountRef{{namespace: c.namespace, name: checkupSA}}
+	if checkupSA != vmCloneServiceAccount {
+		serviceAccounts = append(serviceAccounts, serviceAccountRef{namespace: c.namespace, name: vmCloneServiceAccount})
+	}
+	if name := c.checkupConfig.TenantServiceAccountName; name != "" {
+		serviceAccounts = append(serviceAccounts,
+			serviceAccountRef{namespace: c.checkupConfig.TenantServiceAccountNamespace, name: name})
+	}
+
+	return serviceAccounts, nil
+}
+
+func newCloneSubjectAccessReview(sa serviceAccountRef, sourceNamespace string) *authv1.SubjectAccessReview {
+	return &authv1.SubjectAccessReview{
+		Spec: authv1.SubjectAccessReviewSpec{
+			User:   fmt.Sprintf("system:serviceaccount:%s:%s", sa.namespace, sa.name),
+			Groups: []string{"system:serviceaccounts", "system:serviceaccounts:" + sa.namespace},
+			ResourceAttributes: &authv1.ResourceAttributes{
+				Namespace:   sourceNamespace,
+				Verb:        "create",
+				Group:       cdiv1.SchemeGroupVersion.Group,
+				Resource:    cloneSourceResource,
+				Subresource: cloneSourceSubresource,
+			},
+		},
+	}
+}
@@ -0,0 +1,203 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+const (
+	ErrStorageProfileCSIDriverMismatch = "there are StorageProfiles inconsistent with their CSIDriver capabilities"
+)
+
+// RWOOnlyProvisioners is a hash of provisioners which are known to support the ReadWriteOnce access mode only. Others
+// can be configured, as well as storage classes whose backend supports it only.
+var RWOOnlyProvisioners = map[string]struct{}{
+	"ebs.csi.aws.com":       {},
+	"pd.csi.storage.gke.io": {},
+	"disk.csi.azure.com":    {},
+	"topolvm.io":            {},
+	"rancher.io/local-path": {},
+}
+
+// checkCSIDrivers reports the CSIDriver capabilities and cross-references them with the StorageProfiles
+func (c *Checkup) checkCSIDrivers(ctx context.Context, sps *cdiv1.StorageProfileList, errStr *string) error {
+	log.Print("checkCSIDrivers")
+
+	drivers, err := c.client.ListCSIDrivers(ctx)
+	if err != nil {
+		return err
+	}
+	csiNodes, err := c.client.ListCSINodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	registeredNodes := map[string]int{}
+	for i := range csiNodes.Items {
+		for _, driver := range csiNodes.Items[i].Spec.Drivers {
+			registeredNodes[driver.Name]++
+		}
+	}
+
+	driverByName := map[string]*storagev1.CSIDriver{}
+	capabilities := ""
+	for i := range drivers.Items {
+		driver := &drivers.Items[i]
+		driverByName[driver.Name] = driver
+		appendSep(&capabilities, fmt.Sprintf("%s: %s, registeredNodes=%d", driver.Name, csiDriverCapabilities(driver),
+			registeredNodes[driver.Name]))
+	}
+	c.results.CSIDriverCapabilities = capabilities
+
+	var mismatches string
+	for i := range sps.Items {
+		sp := &sps.Items[i]
+		provisioner := sp.Status.Provisioner
+		if provisioner == nil || unsupportedProvisioner(*provisioner) {
+			continue
+		}
+		driver := driverByName[*provisioner]
+		rwoOnlyReason := c.rwoOnlyReason(sp.Name, *provisioner)
+		for _, mismatch := range storageProfileDriverMismatches(sp, driver, registeredNodes[*provisioner], rwoOnlyReason) {
+			appendSep(&mismatches, sp.Name+": "+mismatch)
+		}
+		if driver != nil {
+			if warning := storageProfileFSGroupPolicyWarning(sp, driver); warning != "" {
+				appendSep(&c.results.StorageProfilesFSGroupPolicy, sp.Name+": "+warning)
+			}
+		}
+	}
+
+	if mismatches != "" {
+		appendSep(&c.results.StorageProfilesCSIDriverMismatch, mismatches)
+		appendSep(errStr, ErrStorageProfileCSIDriverMismatch)
+	}
+
+	return nil
+}
+
+func csiDriverCapabilities(driver *storagev1.CSIDriver) string {
+	spec := &driver.Spec
+	fsGroupPolicy := ""
+	if spec.FSGroupPolicy != nil {
+		fsGroupPolicy = string(*spec.FSGroupPolicy)
+	}
+	var modes []string
+	for _, mode := range spec.VolumeLifecycleModes {
+		modes = append(modes, string(mode))
+	}
+	return fmt.Sprintf("attachRequired=%t, fsGroupPolicy=%s, volumeLifecycleModes=%s, podInfoOnMount=%t, requiresRepublish=%t",
+		boolValue(spec.AttachRequired), fsGroupPolicy, strings.Join(modes, "|"), boolValue(spec.PodInfoOnMount),
+		boolValue(spec.RequiresRepublish))
+}
+
+// rwoOnlyReason returns why the storage class supports the ReadWriteOnce access mode only, when its provisioner is known
+// or configured to, or the class itself is configured to, e.g. the iSCSI class of a backend also serving NFS
+func (c *Checkup) rwoOnlyReason(scName, provisioner string) string {
+	if c.rwoOnlyProvisioner(provisioner) {
+		return fmt.Sprintf("provisioner %q supports ReadWriteOnce only", provisioner)
+	}
+	if contains(c.checkupConfig.RWOOnlyStorageClasses, scName) {
+		return fmt.Sprintf("storage class %q is configured as supporting ReadWriteOnce only", scName)
+	}
+	return ""
+}
+
+func (c *Checkup) rwoOnlyProvisioner(provisioner string) bool {
+	_, known := RWOOnlyProvisioners[provisioner]
+	return known || contains(c.checkupConfig.RWOOnlyProvisioners, provisioner)
+}
+
+// storageProfileDriverMismatches returns the StorageProfile settings the CSI driver or the ReadWriteOnce only storage
+// cannot satisfy
+func storageProfileDriverMismatches(sp *cdiv1.StorageProfile, driver *storagev1.CSIDriver, registeredNodes int,
+	rwoOnlyReason string) []string {
+	var mismatches []string
+	provisioner := *sp.Status.Provisioner
+
+	if driver == nil {
+		if strategy := sp.Status.CloneStrategy; strategy != nil && *strategy == cdiv1.CloneStrategyCsiClone {
+			mismatches = append(mismatches, fmt.Sprintf("cloneStrategy %s but there is no CSIDriver %q", *strategy, provisioner))
+		}
+	} else {
+		if registeredNodes == 0 {
+			mismatches = append(mismatches, fmt.Sprintf("CSIDriver %q is not registered on any node", provisioner))
+		}
+		if modes := driver.Spec.VolumeLifecycleModes; len(modes) != 0 && !containsLifecycleMode(modes, storagev1.VolumeLifecyclePersistent) {
+			mismatches = append(mismatches, fmt.Sprintf("CSIDriver %q does not support Persistent volumes", provisioner))
+		}
+	}
+
+	if rwoOnlyReason != "" {
+		for _, cpSet := range sp.Status.ClaimPropertySets {
+			if hasRWX([]cdiv1.ClaimPropertySet{cpSet}) {
+				mismatches = append(mismatches, fmt.Sprintf("advertises ReadWriteMany %s but %s",
+					volumeModeName(cpSet.VolumeMode), rwoOnlyReason))
+			}
+		}
+	}
+
+	return mismatches
+}
+
+// storageProfileFSGroupPolicyWarning returns the StorageProfile ReadWriteMany Filesystem claimPropertySets the CSIDriver
+// fsGroupPolicy does not apply fsGroup ownership to, which usually works but is easily misread
+func storageProfileFSGroupPolicyWarning(sp *cdiv1.StorageProfile, driver *storagev1.CSIDriver) string {
+	policy := driver.Spec.FSGroupPolicy
+	if policy == nil || *policy != storagev1.ReadWriteOnceWithFSTypeFSGroupPolicy {
+		return ""
+	}
+	for _, cpSet := range sp.Status.ClaimPropertySets {
+		if hasRWX([]cdiv1.ClaimPropertySet{cpSet}) && volumeModeName(cpSet.VolumeMode) == string(corev1.PersistentVolumeFilesystem) {
+			return fmt.Sprintf("advertises ReadWriteMany Filesystem but CSIDriver fsGroupPolicy %s "+
+				"skips fsGroup ownership changes on ReadWriteMany volumes", *policy)
+		}
+	}
+	return ""
+}
+
+func containsLifecycleMode(modes []storagev1.VolumeLifecycleMode, mode storagev1.VolumeLifecycleMode) bool {
+	for _, m := range modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func volumeModeName(volumeMode *corev1.PersistentVolumeMode) string {
+	if volumeMode == nil {
+		return string(corev1.PersistentVolumeFilesystem)
+	}
+	return string(*volumeMode)
+}
+
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
@@ -0,0 +1,174 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	ErrNodesWithoutDefaultCSIDriver = "there are schedulable nodes without the CSI node plugin of the default storage class"
+	MessageSkipNoSchedulableNodes   = "Skip check - no schedulable nodes"
+)
+
+// checkCSINodeCoverage compares the CSINode registrations of the provisioners in use with the KubeVirt schedulable nodes
+func (c *Checkup) checkCSINodeCoverage(ctx context.Context, scs *storagev1.StorageClassList, errStr *string) error {
+	log.Print("checkCSINodeCoverage")
+
+	nodes, err := c.client.ListNodes(ctx)
+	if err != nil {
+		return err
+	}
+	schedulable := schedulableNodeNames(nodes)
+	if len(schedulable) == 0 {
+		log.Print(MessageSkipNoSchedulableNodes)
+		c.results.CSINodeCoverage = MessageSkipNoSchedulableNodes
+		return nil
+	}
+
+	drivers, err := c.client.ListCSIDrivers(ctx)
+	if err != nil {
+		return err
+	}
+	csiNodes, err := c.client.ListCSINodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	nodeDrivers := map[string]map[string]*storagev1.CSINodeDriver{}
+	registered := map[string]bool{}
+	for i := range csiNodes.Items {
+		csiNode := &csiNodes.Items[i]
+		nodeDrivers[csiNode.Name] = map[string]*storagev1.CSINodeDriver{}
+		for j := range csiNode.Spec.Drivers {
+			driver := &csiNode.Spec.Drivers[j]
+			nodeDrivers[csiNode.Name][driver.Name] = driver
+			registered[driver.Name] = true
+		}
+	}
+	for i := range drivers.Items {
+		registered[drivers.Items[i].Name] = true
+	}
+
+	provisioners := csiProvisionersInUse(scs, registered)
+	coverage := ""
+	for _, provisioner := range provisioners {
+		var missing []string
+		for _, node := range schedulable {
+			if nodeDrivers[node][provisioner] == nil {
+				missing = append(missing, node)
+			}
+		}
+		line := fmt.Sprintf("%s: registered on %d/%d schedulable nodes", provisioner, len(schedulable)-len(missing), len(schedulable))
+		if len(missing) != 0 {
+			line += ", missing on " + strings.Join(missing, ", ")
+		}
+		appendSep(&coverage, line)
+	}
+	for _, node := range schedulable {
+		if len(provisioners) != 0 {
+			appendSep(&coverage, fmt.Sprintf("%s allocatable volumes: %s", node, allocatableVolumes(nodeDrivers[node], provisioners)))
+		}
+	}
+	c.results.CSINodeCoverage = coverage
+
+	c.checkDefaultStorageClassNodeCoverage(scs, registered, schedulable, nodeDrivers, errStr)
+
+	return nil
+}
+
+// checkDefaultStorageClassNodeCoverage reports the schedulable nodes where a VM using the default storage class could never
+// start, unless its provisioner is not a known CSI driver (e.g. an in-tree or external provisioner)
+func (c *Checkup) checkDefaultStorageClassNodeCoverage(scs *storagev1.StorageClassList, csiDrivers map[string]bool,
+	schedulable []string, nodeDrivers map[string]map[string]*storagev1.CSINodeDriver, errStr *string) {
+	provisioner := ""
+	for i := range scs.Items {
+		if scs.Items[i].Name == c.defaultStorageClass {
+			provisioner = scs.Items[i].Provisioner
+		}
+	}
+	if !csiDrivers[provisioner] {
+		return
+	}
+
+	issues := ""
+	for _, node := range schedulable {
+		if nodeDrivers[node][provisioner] == nil {
+			appendSep(&issues, fmt.Sprintf("%s: no CSI node plugin of %q, VMs using default storage class %q can never start",
+				node, provisioner, c.defaultStorageClass))
+		}
+	}
+	if issues != "" {
+		c.results.CSINodeCoverageIssues = issues
+		appendSep(errStr, ErrNodesWithoutDefaultCSIDriver)
+	}
+}
+
+func schedulableNodeNames(nodes *corev1.NodeList) []string {
+	var names []string
+	for i := range nodes.Items {
+		if nodes.Items[i].Labels[kvcorev1.NodeSchedulable] == StrTrue {
+			names = append(names, nodes.Items[i].Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// csiProvisionersInUse returns the sorted provisioners of the storage classes which are known CSI drivers
+func csiProvisionersInUse(scs *storagev1.StorageClassList, csiDrivers map[string]bool) []string {
+	var provisioners []string
+	seen := map[string]bool{}
+	for i := range scs.Items {
+		provisioner := scs.Items[i].Provisioner
+		if !csiDrivers[provisioner] || seen[provisioner] {
+			continue
+		}
+		seen[provisioner] = true
+		provisioners = append(provisioners, provisioner)
+	}
+	sort.Strings(provisioners)
+	return provisioners
+}
+
+func allocatableVolumes(drivers map[string]*storagev1.CSINodeDriver, provisioners []string) string {
+	var counts []string
+	for _, provisioner := range provisioners {
+		driver := drivers[provisioner]
+		switch {
+		case driver == nil:
+			counts = append(counts, provisioner+"=none")
+		case driver.Allocatable == nil || driver.Allocatable.Count == nil:
+			counts = append(counts, provisioner+"=unlimited")
+		default:
+			counts = append(counts, fmt.Sprintf("%s=%d", provisioner, *driver.Allocatable.Count))
+		}
+	}
+	return strings.Join(counts, ", ")
+}
@@ -0,0 +1,140 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+
+	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/cron"
+)
+
+const (
+	// LabelDataImportCron is set by CDI on the PVCs and VolumeSnapshots a DataImportCron imports
+	LabelDataImportCron = "cdi.kubevirt.io/dataImportCron"
+	// importsToKeepDefault is the number of imports CDI retains when importsToKeep is not set
+	importsToKeepDefault = 3
+	// dataImportCronScheduleGrace is how late a DataImportCron execution may be before it is reported
+	dataImportCronScheduleGrace = time.Hour
+
+	ErrGoldenImagesOutdated          = "there are golden images whose last import is older than the configured max age"
+	ErrDataImportCronsMissedSchedule = "there are DataImportCrons which missed their schedule"
+
+	MessageScheduleNotEvaluated = "schedule not evaluated"
+)
+
+// checkDataImportCronHistory checks the import age, the schedule and the retained imports of the DataImportCron
+func (c *Checkup) checkDataImportCronHistory(ctx context.Context, dic *cdiv1.DataImportCron, cs *goldenImagesCheckState) error {
+	now := time.Now()
+
+	if maxAge := c.checkupConfig.GoldenImageMaxAge; maxAge > 0 {
+		if ts := dic.Status.LastImportTimestamp; ts != nil && now.Sub(ts.Time) > maxAge {
+			appendSep(&cs.outdatedDics, fmt.Sprintf("%s: last import %s ago, older than %s", dataImportCronRef(dic),
+				formatAge(now.Sub(ts.Time)), formatAge(maxAge)))
+		}
+	}
+
+	if missed, err := dataImportCronMissedSchedule(dic, now); err != nil {
+		appendSep(&cs.unevaluatedScheduleDics, fmt.Sprintf("%s: %s: %v", dataImportCronRef(dic),
+			MessageScheduleNotEvaluated, err))
+	} else if missed != "" {
+		appendSep(&cs.missedScheduleDics, fmt.Sprintf("%s: %s", dataImportCronRef(dic), missed))
+	}
+
+	selector := LabelDataImportCron + "=" + dic.Name
+	pvcs, err := c.client.ListPersistentVolumeClaims(ctx, dic.Namespace, selector)
+	if err != nil {
+		return err
+	}
+	snaps, err := c.client.ListVolumeSnapshots(ctx, dic.Namespace, selector)
+	if err != nil {
+		return err
+	}
+	importsToKeep := int32(importsToKeepDefault)
+	if dic.Spec.ImportsToKeep != nil {
+		importsToKeep = *dic.Spec.ImportsToKeep
+	}
+	if len(pvcs.Items) > int(importsToKeep) || len(snaps.Items) > int(importsToKeep) {
+		res := fmt.Sprintf("%s: %d PVCs and %d VolumeSnapshots retained, importsToKeep %d", dataImportCronRef(dic),
+			len(pvcs.Items), len(snaps.Items), importsToKeep)
+		if gc := dic.Spec.GarbageCollect; gc != nil && *gc == cdiv1.DataImportCronGarbageCollectNever {
+			res += ", garbageCollect Never"
+		}
+		appendSep(&cs.excessImportsDics, res)
+	}
+
+	return nil
+}
+
+// dataImportCronMissedSchedule returns why the DataImportCron missed its schedule, or an empty string if it did not.
+// An error is returned when the schedule cannot be parsed.
+func dataImportCronMissedSchedule(dic *cdiv1.DataImportCron, now time.Time) (string, error) {
+	if dic.Spec.Schedule == "" {
+		return "", nil
+	}
+	schedule, err := cron.Parse(dic.Spec.Schedule)
+	if err != nil {
+		return "", err
+	}
+
+	lastExecution := dic.CreationTimestamp.Time
+	if ts := dic.Status.LastExecutionTimestamp; ts != nil {
+		lastExecution = ts.Time
+	}
+	if lastExecution.IsZero() {
+		return "", nil
+	}
+
+	due := schedule.Next(lastExecution.UTC())
+	if due.IsZero() || now.Sub(due) <= dataImportCronScheduleGrace {
+		return "", nil
+	}
+	return fmt.Sprintf("schedule %q was due %s ago, last execution %s ago", dic.Spec.Schedule,
+		formatAge(now.Sub(due)), formatAge(now.Sub(lastExecution))), nil
+}
+
+// dataImportCronRef returns the DataImportCron name along with its source URL and the digest of its last import
+func dataImportCronRef(dic *cdiv1.DataImportCron) string {
+	source := "unknown"
+	if src := dic.Spec.Template.Spec.Source; src != nil && src.Registry != nil {
+		if src.Registry.URL != nil {
+			source = *src.Registry.URL
+		} else if src.Registry.ImageStream != nil {
+			source = "imagestream " + *src.Registry.ImageStream
+		}
+	}
+	digest := "unknown"
+	if len(dic.Status.CurrentImports) != 0 && dic.Status.CurrentImports[0].Digest != "" {
+		digest = dic.Status.CurrentImports[0].Digest
+	}
+	return fmt.Sprintf("%s/%s (source %s, digest %s)", dic.Namespace, dic.Name, source, digest)
+}
+
+// formatAge returns the duration in days when it is at least two days, and rounded to the minute otherwise
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d >= 2*day {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.Round(time.Minute).String()
+}
@@ -0,0 +1,350 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DiagnosticsBundleKey is the key of the compressed tar archive in the diagnostics ConfigMap binaryData
+	DiagnosticsBundleKey = "diagnostics.tar.gz"
+
+	diagnosticsTimeout      = time.Minute
+	diagnosticsLogTailLines = 200
+	// ConfigMaps are limited to 1MiB, leave some room for the object metadata
+	maxDiagnosticsBundleSize = 1000 * 1024
+
+	virtLauncherPodSelector = "kubevirt.io=virt-launcher"
+	cdiWorkerPodSelector    = "app=containerized-data-importer"
+)
+
+// diagnosticsEventKinds are the kinds of the involved objects whose events are collected
+var diagnosticsEventKinds = []string{
+	"DataVolume", "PersistentVolumeClaim", "VirtualMachine", "VirtualMachineInstance", "VirtualMachineInstanceMigration", "Pod",
+}
+
+// diagnosticsBundle maps the bundle file paths to their content, and lists what could not be collected
+type diagnosticsBundle struct {
+	files  map[string][]byte
+	errors []string
+}
+
+func (b *diagnosticsBundle) addYAML(dir, name string, obj interface{}) {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		b.addError(fmt.Sprintf("%s/%s", dir, name), err)
+		return
+	}
+	b.files[fmt.Sprintf("%s/%s.yaml", dir, name)] = data
+}
+
+func (b *diagnosticsBundle) addError(what string, err error) {
+	b.errors = append(b.errors, fmt.Sprintf("%s: %s", what, err))
+}
+
+// failedSince returns whether a check appended an error to errStr since failedSince was called
+func failedSince(errStr *string) func() bool {
+	errLen := len(*errStr)
+	return func() bool {
+		return len(*errStr) != errLen
+	}
+}
+
+// deleteUnlessFailed runs the deletion of an object created by a check, unless the check failed and a diagnostics
+// bundle is collected, as it is only collected at the end of the run. The deletion is then left to the teardown.
+func (c *Checkup) deleteUnlessFailed(ctx context.Context, failed func() bool, deletion func(ctx context.Context)) {
+	if !failed() || (c.checkupConfig.DiagnosticsConfigMap == "" && c.checkupConfig.DiagnosticsDir == "") {
+		deletion(ctx)
+		return
+	}
+	c.keptObjectsMutex.Lock()
+	defer c.keptObjectsMutex.Unlock()
+	c.keptObjectDeletions = append(c.keptObjectDeletions, deletion)
+}
+
+func (c *Checkup) deleteKeptObjects(ctx context.Context) {
+	c.keptObjectsMutex.Lock()
+	defer c.keptObjectsMutex.Unlock()
+	for _, deletion := range c.keptObjectDeletions {
+		deletion(ctx)
+	}
+	c.keptObjectDeletions = nil
+}
+
+// collectDiagnostics writes a bundle of the checkup namespace DataVolumes, PVCs, VMs, VMIs and VMIMs with their events,
+// the virt-launcher and CDI worker pod logs and the relevant storage classes and StorageProfiles, before the teardown
+// deletes them. It uses its own context, as the run context may have expired.
+func (c *Checkup) collectDiagnostics() {
+	if c.checkupConfig.DiagnosticsConfigMap == "" && c.checkupConfig.DiagnosticsDir == "" {
+		return
+	}
+	log.Print("collectDiagnostics")
+
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticsTimeout)
+	defer cancel()
+
+	bundle := &diagnosticsBundle{files: map[string][]byte{}}
+	storageClasses := c.collectStorageObjects(ctx, bundle)
+	c.collectVirtObjects(ctx, bundle)
+	c.collectEvents(ctx, bundle)
+	c.collectPodLogs(ctx, bundle)
+	c.collectStorageClasses(ctx, bundle, storageClasses)
+	if len(bundle.errors) > 0 {
+		bundle.files["errors.txt"] = []byte(strings.Join(bundle.errors, "\n") + "\n")
+	}
+
+	var res string
+	if dir := c.checkupConfig.DiagnosticsDir; dir != "" {
+		if err := writeDiagnosticsDir(dir, bundle.files); err != nil {
+			appendSep(&res, fmt.Sprintf("failed to write diagnostics bundle to directory %s: %s", dir, err))
+		} else {
+			appendSep(&res, fmt.Sprintf("diagnostics bundle of %d files written to directory %s", len(bundle.files), dir))
+		}
+	}
+	if cmName := c.checkupConfig.DiagnosticsConfigMap; cmName != "" {
+		if err := c.writeDiagnosticsConfigMap(ctx, cmName, bundle.files); err != nil {
+			appendSep(&res, fmt.Sprintf("failed to write diagnostics bundle to ConfigMap %s/%s: %s", c.namespace, cmName, err))
+		} else {
+			appendSep(&res, fmt.Sprintf("diagnostics bundle of %d files written to ConfigMap %s/%s", len(bundle.files),
+				c.namespace, cmName))
+		}
+	}
+	log.Print(res)
+	c.results.Diagnostics = res
+}
+
+// collectStorageObjects adds the DataVolumes and PVCs, returning the storage classes they use
+func (c *Checkup) collectStorageObjects(ctx context.Context, bundle *diagnosticsBundle) map[string]struct{} {
+	storageClasses := map[string]struct{}{}
+	for _, sc := range []string{c.defaultStorageClass, c.checkupConfig.StorageClass} {
+		if sc != "" {
+			storageClasses[sc] = struct{}{}
+		}
+	}
+
+	if dvs, err := c.client.ListDataVolumes(ctx, c.namespace); err != nil {
+		bundle.addError("datavolumes", err)
+	} else {
+		for i := range dvs.Items {
+			dv := dvs.Items[i]
+			dv.ManagedFields = nil
+			bundle.addYAML("datavolumes", dv.Name, &dv)
+		}
+	}
+
+	if pvcs, err := c.client.ListPersistentVolumeClaims(ctx, c.namespace, ""); err != nil {
+		bundle.addError("persistentvolumeclaims", err)
+	} else {
+		for i := range pvcs.Items {
+			pvc := pvcs.Items[i]
+			pvc.ManagedFields = nil
+			bundle.addYAML("persistentvolumeclaims", pvc.Name, &pvc)
+			if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName != "" {
+				storageClasses[*pvc.Spec.StorageClassName] = struct{}{}
+			}
+		}
+	}
+
+	return storageClasses
+}
+
+func (c *Checkup) collectVirtObjects(ctx context.Context, bundle *diagnosticsBundle) {
+	if vms, err := c.client.ListVirtualMachines(ctx, c.namespace); err != nil {
+		bundle.addError("virtualmachines", err)
+	} else {
+		for i := range vms.Items {
+			vm := vms.Items[i]
+			vm.ManagedFields = nil
+			bundle.addYAML("virtualmachines", vm.Name, &vm)
+		}
+	}
+
+	if vmis, err := c.client.ListVirtualMachinesInstances(ctx, c.namespace); err != nil {
+		bundle.addError("virtualmachineinstances", err)
+	} else {
+		for i := range vmis.Items {
+			vmi := vmis.Items[i]
+			vmi.ManagedFields = nil
+			bundle.addYAML("virtualmachineinstances", vmi.Name, &vmi)
+		}
+	}
+
+	if vmims, err := c.client.ListVirtualMachineInstanceMigrations(ctx, c.namespace); err != nil {
+		bundle.addError("virtualmachineinstancemigrations", err)
+	} else {
+		for i := range vmims.Items {
+			vmim := vmims.Items[i]
+			vmim.ManagedFields = nil
+			bundle.addYAML("virtualmachineinstancemigrations", vmim.Name, &vmim)
+		}
+	}
+}
+
+// collectEvents adds the events of the involved objects, including the ones already deleted, in time order
+func (c *Checkup) collectEvents(ctx context.Context, bundle *diagnosticsBundle) {
+	events, err := c.client.ListEvents(ctx, c.namespace)
+	if err != nil {
+		bundle.addError("events", err)
+		return
+	}
+
+	var involved []corev1.Event
+	for i := range events.Items {
+		if contains(diagnosticsEventKinds, events.Items[i].InvolvedObject.Kind) {
+			involved = append(involved, events.Items[i])
+		}
+	}
+	sort.SliceStable(involved, func(i, j int) bool {
+		return eventTime(&involved[i]).Before(eventTime(&involved[j]))
+	})
+
+	var lines []string
+	for i := range involved {
+		event := &involved[i]
+		line := fmt.Sprintf("%s %s %s/%s %s: %s", eventTime(event).UTC().Format(time.RFC3339), event.Type,
+			event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Reason, event.Message)
+		if event.Count > 1 {
+			line += fmt.Sprintf(" (x%d)", event.Count)
+		}
+		lines = append(lines, line)
+	}
+	bundle.files["events.txt"] = []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// collectPodLogs adds the tail of the logs of each container of the virt-launcher and CDI importer, cloner and uploader
+// pods
+func (c *Checkup) collectPodLogs(ctx context.Context, bundle *diagnosticsBundle) {
+	for _, selector := range []string{virtLauncherPodSelector, cdiWorkerPodSelector} {
+		pods, err := c.client.ListPods(ctx, c.namespace, selector)
+		if err != nil {
+			bundle.addError("pods "+selector, err)
+			continue
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			for j := range pod.Spec.Containers {
+				container := pod.Spec.Containers[j].Name
+				path := fmt.Sprintf("logs/%s/%s.log", pod.Name, container)
+				logs, err := c.client.GetPodLogs(ctx, c.namespace, pod.Name, container, diagnosticsLogTailLines)
+				if err != nil {
+					bundle.addError(path, err)
+					continue
+				}
+				bundle.files[path] = []byte(logs)
+			}
+		}
+	}
+}
+
+// collectStorageClasses adds the storage classes used by the checkup and their StorageProfiles
+func (c *Checkup) collectStorageClasses(ctx context.Context, bundle *diagnosticsBundle, storageClasses map[string]struct{}) {
+	if scs, err := c.client.ListStorageClasses(ctx); err != nil {
+		bundle.addError("storageclasses", err)
+	} else {
+		for i := range scs.Items {
+			sc := scs.Items[i]
+			if _, exists := storageClasses[sc.Name]; exists {
+				sc.ManagedFields = nil
+				bundle.addYAML("storageclasses", sc.Name, &sc)
+			}
+		}
+	}
+
+	if sps, err := c.client.ListStorageProfiles(ctx); err != nil {
+		bundle.addError("storageprofiles", err)
+	} else {
+		for i := range sps.Items {
+			sp := sps.Items[i]
+			if _, exists := storageClasses[sp.Name]; exists {
+				sp.ManagedFields = nil
+				bundle.addYAML("storageprofiles", sp.Name, &sp)
+			}
+		}
+	}
+}
+
+func writeDiagnosticsDir(dir string, files map[string][]byte) error {
+	for _, name := range sortedKeys(files) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, files[name], 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Checkup) writeDiagnosticsConfigMap(ctx context.Context, name string, files map[string][]byte) error {
+	bundle, err := compressDiagnostics(files)
+	if err != nil {
+		return err
+	}
+	if len(bundle) > maxDiagnosticsBundleSize {
+		return fmt.Errorf("compressed bundle of %d bytes exceeds the ConfigMap size limit", len(bundle))
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: c.namespace},
+		BinaryData: map[string][]byte{DiagnosticsBundleKey: bundle},
+	}
+	log.Printf("Writing diagnostics bundle of %d bytes to ConfigMap %s/%s", len(bundle), c.namespace, name)
+	return c.createOrUpdateConfigMap(ctx, cm)
+}
+
+// compressDiagnostics returns the files as a gzip compressed tar archive
+func compressDiagnostics(files map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	modTime := time.Now()
+	for _, name := range sortedKeys(files) {
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name])), ModTime: modTime}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tarWriter.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,341 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+	exportv1 "kubevirt.io/api/export/v1alpha1"
+)
+
+const (
+	exportName            = "checkup-export"
+	exportTokenSecretName = "checkup-export-token"
+	exportTokenKey        = "token"
+	exportTokenHeader     = "x-kubevirt-export-token"
+	exportTokenLen        = 32
+	// exportDownloadSize is how much of the exported volume is downloaded, enough for any disk image header
+	exportDownloadSize = 4 * 1024 * 1024
+
+	ErrExportFailed = "VirtualMachineExport failed"
+)
+
+// checkVMExport stops the VM under test, exports it the way backup tools do, and downloads the beginning of its volume
+// over the internal export service URL to validate the disk image header. The VM is started again afterwards.
+func (c *Checkup) checkVMExport(ctx context.Context, errStr *string) error {
+	log.Print("checkVMExport")
+
+	if c.vmUnderTest == nil {
+		log.Print(MessageSkipNoVMI)
+		c.results.VMExport = MessageSkipNoVMI
+		return nil
+	}
+
+	if !c.isFeatureGateEnabled(FeatureGateVMExport) {
+		c.results.VMExport = fmt.Sprintf(MessageSkipFeatureGateDisabled, FeatureGateVMExport)
+		log.Print(c.results.VMExport)
+		return nil
+	}
+
+	vmName := c.vmUnderTest.Name
+	if err := c.setVirtualMachineRunStrategy(ctx, vmName, kvcorev1.RunStrategyHalted); err != nil {
+		return err
+	}
+	// Deferred first so the VM is started again only once the export holding its volume is deleted
+	defer c.restartVirtualMachine(ctx, vmName, errStr)
+	if err := c.waitForVMIDeleted(ctx, vmName); err != nil {
+		c.exportFailed(fmt.Errorf("VM %q not stopped: %w", vmName, err), errStr)
+		return nil
+	}
+
+	token := rand.String(exportTokenLen)
+	if _, err := c.client.CreateSecret(ctx, c.namespace, c.newExportTokenSecret(token)); err != nil {
+		return fmt.Errorf("failed to create export token secret: %w", err)
+	}
+	defer func() {
+		if err := c.client.DeleteSecret(ctx, c.namespace, exportTokenSecretName); ignoreNotFound(err) != nil {
+			log.Printf("failed to delete secret %q: %s", exportTokenSecretName, err)
+		}
+	}()
+
+	if _, err := c.client.CreateVirtualMachineExport(ctx, c.namespace, c.newVMExport(vmName)); err != nil {
+		return fmt.Errorf("failed to create VirtualMachineExport: %w", err)
+	}
+	defer func() {
+		if err := c.client.DeleteVirtualMachineExport(ctx, c.namespace, exportName); ignoreNotFound(err) != nil {
+			log.Printf("failed to delete VirtualMachineExport %q: %s", exportName, err)
+		}
+	}()
+
+	export, err := c.waitForVMExportReady(ctx, exportName)
+	if err != nil {
+		c.exportFailed(fmt.Errorf("VirtualMachineExport %q not ready: %w", exportName, err), errStr)
+		return nil
+	}
+	res := fmt.Sprintf("VirtualMachineExport %q of VM %q ready, service %q", exportName, vmName, export.Status.ServiceName)
+	log.Print(res)
+	appendSep(&c.results.VMExport, res)
+
+	res, err = downloadExportedVolume(ctx, export, token)
+	if err != nil {
+		c.exportFailed(err, errStr)
+		return nil
+	}
+	log.Print(res)
+	appendSep(&c.results.VMExport, res)
+
+	return nil
+}
+
+func (c *Checkup) exportFailed(err error, errStr *string) {
+	res := fmt.Sprintf("%s: %v", ErrExportFailed, err)
+	log.Print(res)
+	appendSep(&c.results.VMExport, res)
+	appendSep(errStr, ErrExportFailed)
+}
+
+func (c *Checkup) setVirtualMachineRunStrategy(ctx context.Context, vmName string, runStrategy kvcorev1.VirtualMachineRunStrategy) error {
+	patch, err := json.Marshal([]jsonPatchOp{{Op: "replace", Path: "/spec/runStrategy", Value: runStrategy}})
+	if err != nil {
+		return err
+	}
+	log.Printf("Setting VM %q runStrategy %s", vmName, runStrategy)
+	if err := c.client.PatchVirtualMachine(ctx, c.namespace, vmName, patch); err != nil {
+		return fmt.Errorf("failed to set VM runStrategy %s: %w", runStrategy, err)
+	}
+	return nil
+}
+
+// restartVirtualMachine starts the exported VM again, so the VM under test is left running as the other checks expect
+func (c *Checkup) restartVirtualMachine(ctx context.Context, vmName string, errStr *string) {
+	if err := c.setVirtualMachineRunStrategy(ctx, vmName, kvcorev1.RunStrategyAlways); err != nil {
+		c.exportFailed(err, errStr)
+		return
+	}
+	_ = c.waitForVMIStatus(ctx, vmName, "running again", &c.results.VMExport, errStr,
+		func(vmi *kvcorev1.VirtualMachineInstance) (done bool, err error) {
+			return vmi.Status.Phase == kvcorev1.Running, nil
+		})
+}
+
+func (c *Checkup) waitForVMIDeleted(ctx context.Context, vmName string) error {
+	conditionFn := func(ctx context.Context) (bool, error) {
+		_, err := c.client.GetVirtualMachineInstance(ctx, c.namespace, vmName)
+		if err != nil {
+			return ignoreNotFound(err) == nil, ignoreNotFound(err)
+		}
+		return false, nil
+	}
+
+	log.Printf("Waiting for VMI %q deleted", vmName)
+	return wait.PollImmediateWithContext(ctx, pollInterval, c.checkupConfig.VMITimeout, conditionFn)
+}
+
+func (c *Checkup) newExportTokenSecret(token string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: exportTokenSecretName,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       c.checkupConfig.PodName,
+				UID:        types.UID(c.checkupConfig.PodUID),
+			}},
+		},
+		StringData: map[string]string{exportTokenKey: token},
+	}
+}
+
+func (c *Checkup) newVMExport(vmName string) *exportv1.VirtualMachineExport {
+	apiGroup := kvcorev1.GroupVersion.Group
+	tokenSecretRef := exportTokenSecretName
+	return &exportv1.VirtualMachineExport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: exportName,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       c.checkupConfig.PodName,
+				UID:        types.UID(c.checkupConfig.PodUID),
+			}},
+		},
+		Spec: exportv1.VirtualMachineExportSpec{
+			Source: corev1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     kvcorev1.VirtualMachineGroupVersionKind.Kind,
+				Name:     vmName,
+			},
+			TokenSecretRef: &tokenSecretRef,
+		},
+	}
+}
+
+func (c *Checkup) waitForVMExportReady(ctx context.Context, name string) (*exportv1.VirtualMachineExport, error) {
+	var export *exportv1.VirtualMachineExport
+	conditionFn := func(ctx context.Context) (bool, error) {
+		var err error
+		export, err = c.client.GetVirtualMachineExport(ctx, c.namespace, name)
+		if err != nil {
+			return false, ignoreNotFound(err)
+		}
+		if export.Status == nil {
+			return false, nil
+		}
+		if export.Status.Phase == exportv1.Skipped || export.Status.Phase == exportv1.Terminated {
+			return false, fmt.Errorf("phase %s", export.Status.Phase)
+		}
+		return export.Status.Phase == exportv1.Ready, nil
+	}
+
+	log.Printf("Waiting for VirtualMachineExport %q ready", name)
+	if err := wait.PollImmediateWithContext(ctx, pollInterval, c.checkupConfig.VMITimeout, conditionFn); err != nil {
+		if errors.Is(err, wait.ErrWaitTimeout) && export != nil && export.Status != nil {
+			return nil, fmt.Errorf("%w, phase %s", err, export.Status.Phase)
+		}
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// downloadExportedVolume downloads the beginning of the first exported volume, decompressing it if only the gzip
+// format is available, and validates its disk image header
+func downloadExportedVolume(ctx context.Context, export *exportv1.VirtualMachineExport, token string) (string, error) {
+	links := export.Status.Links
+	if links == nil || links.Internal == nil || len(links.Internal.Volumes) == 0 {
+		return "", errors.New("no internal volume links")
+	}
+	volume := links.Internal.Volumes[0]
+	format := exportVolumeFormat(volume.Formats)
+	if format == nil {
+		return "", fmt.Errorf("volume %q has neither a raw nor a gzip format", volume.Name)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM([]byte(links.Internal.Cert)) {
+		log.Printf("no certificates found in VirtualMachineExport %q internal link", export.Name)
+	}
+
+	header, err := downloadImageHeader(ctx, format, token, &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12})
+	if err != nil {
+		return "", fmt.Errorf("volume %q download from %s: %w", volume.Name, format.Url, err)
+	}
+	imageType, err := validateImageHeader(header)
+	if err != nil {
+		return "", fmt.Errorf("volume %q: %w", volume.Name, err)
+	}
+
+	return fmt.Sprintf("Downloaded %d bytes of volume %q in %s format, image header: %s", len(header), volume.Name,
+		format.Format, imageType), nil
+}
+
+func exportVolumeFormat(formats []exportv1.VirtualMachineExportVolumeFormat) *exportv1.VirtualMachineExportVolumeFormat {
+	var gz *exportv1.VirtualMachineExportVolumeFormat
+	for i := range formats {
+		switch formats[i].Format {
+		case exportv1.KubeVirtRaw:
+			return &formats[i]
+		case exportv1.KubeVirtGz:
+			gz = &formats[i]
+		}
+	}
+	return gz
+}
+
+func downloadImageHeader(ctx context.Context, format *exportv1.VirtualMachineExportVolumeFormat, token string,
+	tlsConfig *tls.Config) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, format.Url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(exportTokenHeader, token)
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		Timeout:   uploadHTTPTimeout,
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		const maxBodyLen = 512
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodyLen))
+		return nil, fmt.Errorf("export server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var body io.Reader = resp.Body
+	if format.Format == exportv1.KubeVirtGz {
+		gzReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gzReader.Close()
+		body = gzReader
+	}
+
+	header, err := io.ReadAll(io.LimitReader(body, exportDownloadSize))
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	return header, nil
+}
+
+// validateImageHeader returns the image type the header belongs to. Exported volumes are raw, but a PVC may still
+// hold a qcow2 image, e.g. when it was not imported by CDI.
+func validateImageHeader(header []byte) (string, error) {
+	const (
+		mbrSignatureOffset = 510
+		sectorSize         = 512
+	)
+
+	if len(header) >= 4 && string(header[:4]) == "QFI\xfb" {
+		return "qcow2", nil
+	}
+	if len(header) < sectorSize {
+		return "", fmt.Errorf("image too short, %d bytes", len(header))
+	}
+	if header[mbrSignatureOffset] == 0x55 && header[mbrSignatureOffset+1] == 0xAA {
+		return "raw, MBR boot signature", nil
+	}
+	return "", errors.New("no MBR boot signature nor qcow2 magic in the image header")
+}
@@ -0,0 +1,159 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	corev1 "k8s.io/api/core/v1"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+// goldenImage is a ready golden image along with the DataImportCron managing it
+type goldenImage struct {
+	dic  *cdiv1.DataImportCron
+	pvc  *corev1.PersistentVolumeClaim
+	snap *snapshotv1.VolumeSnapshot
+}
+
+// checkGoldenImageStorageClassDrift reports golden images VM disks cannot be smart-cloned from to the default virt
+// storage class (or the configured one), either since they are on another storage class or since it lacks smart clone
+func (c *Checkup) checkGoldenImageStorageClassDrift(ctx context.Context, sps *cdiv1.StorageProfileList,
+	vscs *snapshotv1.VolumeSnapshotClassList) {
+	log.Print("checkGoldenImageStorageClassDrift")
+
+	targetSc := c.checkupConfig.StorageClass
+	if targetSc == "" {
+		targetSc = c.defaultStorageClass
+	}
+	if targetSc == "" {
+		log.Print(MessageSkipNoDefaultStorageClass)
+		c.results.GoldenImagesStorageClassDrift = MessageSkipNoDefaultStorageClass
+		return
+	}
+	if len(c.goldenImages) == 0 {
+		log.Print(MessageSkipNoGoldenImage)
+		c.results.GoldenImagesStorageClassDrift = MessageSkipNoGoldenImage
+		return
+	}
+
+	var targetSp *cdiv1.StorageProfile
+	for i := range sps.Items {
+		if sc := sps.Items[i].Status.StorageClass; sc != nil && *sc == targetSc {
+			targetSp = &sps.Items[i]
+			break
+		}
+	}
+	targetProvisioner := ""
+	targetSmartClone := false
+	if targetSp != nil && targetSp.Status.Provisioner != nil {
+		targetProvisioner = *targetSp.Status.Provisioner
+		targetSmartClone = c.hasSmartClone(ctx, targetSp, vscs)
+	}
+
+	for i := range c.goldenImages {
+		gi := &c.goldenImages[i]
+		res := ""
+		if gi.pvc != nil {
+			res = goldenImagePvcDrift(gi.pvc, targetSc, targetProvisioner, targetSmartClone)
+		} else if gi.snap != nil {
+			driver := c.goldenImageSnapshotDriver(ctx, gi.snap, vscs)
+			if driver == "" {
+				log.Printf("skipping VolumeSnapshot %s/%s, its CSI driver cannot be resolved", gi.snap.Namespace, gi.snap.Name)
+				continue
+			}
+			res = goldenImageSnapshotDrift(gi.snap, driver, targetSc, targetProvisioner)
+		}
+		if res == "" {
+			continue
+		}
+		res = fmt.Sprintf("%s/%s (DataSource %s): %s", gi.dic.Namespace, gi.dic.Name, gi.dic.Spec.ManagedDataSource, res)
+		log.Print(res)
+		appendSep(&c.results.GoldenImagesStorageClassDrift, res)
+	}
+}
+
+func goldenImagePvcDrift(pvc *corev1.PersistentVolumeClaim, targetSc, targetProvisioner string, targetSmartClone bool) string {
+	sc := ""
+	if pvc.Spec.StorageClassName != nil {
+		sc = *pvc.Spec.StorageClassName
+	}
+
+	if sc != targetSc {
+		return fmt.Sprintf("PVC %s/%s is on storage class %q rather than %q, so VM disks are cloned host-assisted "+
+			"across storage classes. Recommendation: set spec.template.spec.storage.storageClassName of the "+
+			"DataImportCron to %q, or leave it unset to use the default virt storage class",
+			pvc.Namespace, pvc.Name, sc, targetSc, targetSc)
+	}
+	if !targetSmartClone {
+		return fmt.Sprintf("storage class %q of PVC %s/%s has no smart clone, so VM disks are cloned host-assisted. "+
+			"Recommendation: add a VolumeSnapshotClass for %q, or set cloneStrategy csi-clone in its StorageProfile "+
+			"if the CSI driver supports volume cloning", sc, pvc.Namespace, pvc.Name, targetProvisioner)
+	}
+
+	return ""
+}
+
+// goldenImageSnapshotDriver returns the CSI driver of the snapshot VolumeSnapshotClass, of its bound
+// VolumeSnapshotContent, or of the single default VolumeSnapshotClass, or an empty string if none is found
+func (c *Checkup) goldenImageSnapshotDriver(ctx context.Context, snap *snapshotv1.VolumeSnapshot,
+	vscs *snapshotv1.VolumeSnapshotClassList) string {
+	if vscName := snap.Spec.VolumeSnapshotClassName; vscName != nil {
+		if vsc := getVolumeSnapshotClass(vscs, *vscName); vsc != nil {
+			return vsc.Driver
+		}
+	}
+
+	if snap.Status != nil && snap.Status.BoundVolumeSnapshotContentName != nil {
+		content, err := c.client.GetVolumeSnapshotContent(ctx, *snap.Status.BoundVolumeSnapshotContentName)
+		if err != nil {
+			log.Printf("failed to get VolumeSnapshotContent %q: %s", *snap.Status.BoundVolumeSnapshotContentName, err)
+		} else if content.Spec.Driver != "" {
+			return content.Spec.Driver
+		}
+	}
+
+	driver := ""
+	for i := range vscs.Items {
+		if vscs.Items[i].Annotations[AnnDefaultSnapshotClass] != StrTrue {
+			continue
+		}
+		if driver != "" {
+			return ""
+		}
+		driver = vscs.Items[i].Driver
+	}
+	return driver
+}
+
+func goldenImageSnapshotDrift(snap *snapshotv1.VolumeSnapshot, driver, targetSc, targetProvisioner string) string {
+	if driver != targetProvisioner {
+		return fmt.Sprintf("VolumeSnapshot %s/%s is of CSI driver %q while storage class %q is provisioned by %q, "+
+			"so VM disks are restored host-assisted. Recommendation: set spec.template.spec.storage.storageClassName "+
+			"of the DataImportCron to %q", snap.Namespace, snap.Name, driver, targetSc, targetProvisioner, targetSc)
+	}
+
+	return ""
+}
@@ -0,0 +1,138 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/platform"
+)
+
+const (
+	hyperConvergedName = "kubevirt-hyperconverged"
+
+	ErrHyperConvergedIssues       = "there are HyperConverged settings conflicting with the storage classes"
+	MessageSkipNotOpenShift       = "Skip check - not OpenShift"
+	MessageSkipNoHyperConvergedCR = "Skip check - no HyperConverged CR"
+)
+
+// checkHyperConverged reports the storage related settings of the HyperConverged CR, which the CDI and KubeVirt
+// configuration is derived from, and flags the ones referring to storage classes which do not exist
+func (c *Checkup) checkHyperConverged(ctx context.Context, scs *storagev1.StorageClassList, errStr *string) error {
+	log.Print("checkHyperConverged")
+
+	if c.platform != platform.OpenShift {
+		log.Print(MessageSkipNotOpenShift)
+		c.results.HyperConverged = MessageSkipNotOpenShift
+		return nil
+	}
+
+	// HCO deploys CDI in its own namespace
+	hcoNamespace, err := c.getCDINamespace(ctx)
+	if err != nil {
+		return err
+	}
+	hco, err := c.client.GetHyperConverged(ctx, hcoNamespace, hyperConvergedName)
+	if err != nil {
+		if ignoreNotFound(err) != nil {
+			return err
+		}
+		log.Print(MessageSkipNoHyperConvergedCR)
+		c.results.HyperConverged = MessageSkipNoHyperConvergedCR
+		return nil
+	}
+
+	var res, issues string
+
+	scratchSc, _, _ := unstructured.NestedString(hco.Object, "spec", "scratchSpaceStorageClass")
+	if scratchSc == "" {
+		appendSep(&res, "scratchSpaceStorageClass: unset")
+	} else {
+		appendSep(&res, "scratchSpaceStorageClass: "+scratchSc)
+		if !storageClassExists(scs, scratchSc) {
+			appendSep(&issues, fmt.Sprintf("scratchSpaceStorageClass %q does not exist", scratchSc))
+		}
+	}
+
+	registries, _, _ := unstructured.NestedStringSlice(hco.Object, "spec", "storageImport", "insecureRegistries")
+	appendSep(&res, "storageImport.insecureRegistries: "+joinOrNone(registries))
+
+	bootImageImport, found, _ := unstructured.NestedBool(hco.Object, "spec", "enableCommonBootImageImport")
+	if !found {
+		// HCO defaults to importing the common boot images
+		bootImageImport = true
+	}
+	appendSep(&res, fmt.Sprintf("enableCommonBootImageImport: %t", bootImageImport))
+
+	templates, _, _ := unstructured.NestedSlice(hco.Object, "spec", "dataImportCronTemplates")
+	if len(templates) == 0 {
+		appendSep(&res, "dataImportCronTemplates: none")
+	}
+	for _, t := range templates {
+		template, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(template, "metadata", "name")
+		schedule, _, _ := unstructured.NestedString(template, "spec", "schedule")
+		sc, _, _ := unstructured.NestedString(template, "spec", "template", "spec", "storage", "storageClassName")
+		if sc == "" {
+			appendSep(&res, fmt.Sprintf("dataImportCronTemplate %s: schedule %q, default storage class", name, schedule))
+			continue
+		}
+		appendSep(&res, fmt.Sprintf("dataImportCronTemplate %s: schedule %q, storageClassName %s", name, schedule, sc))
+		if !storageClassExists(scs, sc) {
+			appendSep(&issues, fmt.Sprintf("dataImportCronTemplate %s storageClassName %q does not exist", name, sc))
+		}
+	}
+
+	if bootImageImport && c.defaultStorageClass == "" {
+		appendSep(&issues, "enableCommonBootImageImport is set, but there is no default storage class to import "+
+			"the common boot images to")
+	}
+
+	featureGates, _, _ := unstructured.NestedMap(hco.Object, "spec", "featureGates")
+	appendSep(&res, "featureGates: "+formatFeatureGates(featureGates))
+
+	log.Print(res)
+	c.results.HyperConverged = res
+	if issues != "" {
+		log.Print(issues)
+		c.results.HyperConvergedIssues = issues
+		appendSep(errStr, ErrHyperConvergedIssues)
+	}
+
+	return nil
+}
+
+func formatFeatureGates(featureGates map[string]interface{}) string {
+	gates := make([]string, 0, len(featureGates))
+	for name, value := range featureGates {
+		gates = append(gates, fmt.Sprintf("%s=%v", name, value))
+	}
+	sort.Strings(gates)
+	return joinOrNone(gates)
+}
@@ -0,0 +1,209 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+
+	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/imageserver"
+)
+
+const (
+	imageServerPodName = "checkup-image-server"
+	importDvSize       = "100Mi"
+	importVMName       = "checkup-import-vm"
+
+	ErrImportFailed = "import from HTTP source failed"
+)
+
+type importSource struct {
+	dvName string
+	image  string
+}
+
+var importSources = []importSource{
+	{dvName: "checkup-import-raw", image: imageserver.RawImage},
+	{dvName: "checkup-import-qcow2", image: imageserver.Qcow2Image},
+	{dvName: "checkup-import-gz", image: imageserver.GzipImage},
+}
+
+// checkImport serves generated raw, qcow2 and gzip images from a pod running the checkup image, imports each of them
+// with a DataVolume http source and starts a VM on the raw one. It needs no golden image nor external network access.
+func (c *Checkup) checkImport(ctx context.Context, errStr *string) error {
+	log.Print("checkImport")
+
+	if c.defaultStorageClass == "" && c.checkupConfig.StorageClass == "" {
+		log.Print(MessageSkipNoDefaultStorageClass)
+		c.results.CDIImport = MessageSkipNoDefaultStorageClass
+		return nil
+	}
+
+	if !c.pvcBound {
+		log.Print(MessageSkipPVCNotBound)
+		c.results.CDIImport = MessageSkipPVCNotBound
+		return nil
+	}
+
+	checkupPod, err := c.client.GetPod(ctx, c.namespace, c.checkupConfig.PodName)
+	if err != nil {
+		return fmt.Errorf("failed to get checkup pod: %w", err)
+	}
+
+	checkFailed := failedSince(errStr)
+	if _, err := c.client.CreatePod(ctx, c.namespace, c.newImageServerPod(checkupPod.Spec.Containers[0].Image)); err != nil {
+		return fmt.Errorf("failed to create image server pod: %w", err)
+	}
+	defer c.deleteUnlessFailed(ctx, checkFailed, func(ctx context.Context) {
+		if err := c.client.DeletePod(ctx, c.namespace, imageServerPodName); ignoreNotFound(err) != nil {
+			log.Printf("failed to delete pod %q: %s", imageServerPodName, err)
+		}
+	})
+
+	podIP, err := c.waitForPodReady(ctx, imageServerPodName)
+	if err != nil {
+		c.importFailed(fmt.Errorf("image server pod %q: %w", imageServerPodName, err))
+		appendSep(errStr, ErrImportFailed)
+		return nil
+	}
+	serverURL := "http://" + net.JoinHostPort(podIP, strconv.Itoa(imageserver.Port))
+	res := fmt.Sprintf("Image server: %s", serverURL)
+	log.Print(res)
+	appendSep(&c.results.CDIImport, res)
+
+	for _, source := range importSources {
+		dv := c.newBlankDataVolume(source.dvName, importDvSize)
+		dv.Spec.Source = &cdiv1.DataVolumeSource{HTTP: &cdiv1.DataVolumeSourceHTTP{URL: serverURL + "/" + source.image}}
+		if _, err := c.client.CreateDataVolume(ctx, c.namespace, dv); err != nil {
+			return err
+		}
+		dvName := source.dvName
+		defer c.deleteUnlessFailed(ctx, checkFailed, func(ctx context.Context) {
+			c.deleteDataVolume(ctx, dvName)
+		})
+	}
+
+	failed := false
+	for _, source := range importSources {
+		if err := c.waitForDataVolumePhase(ctx, source.dvName, cdiv1.Succeeded); err != nil {
+			c.importFailed(fmt.Errorf("DataVolume %q from %s: %w", source.dvName, source.image, err))
+			failed = true
+			continue
+		}
+		res := fmt.Sprintf("DataVolume %q imported from %s", source.dvName, source.image)
+		log.Print(res)
+		appendSep(&c.results.CDIImport, res)
+	}
+	if failed {
+		appendSep(errStr, ErrImportFailed)
+		return nil
+	}
+
+	log.Printf("Creating VM %q", importVMName)
+	if _, err := c.client.CreateVirtualMachine(ctx, c.namespace,
+		newVMWithPVC(importVMName, importSources[0].dvName, c.checkupConfig)); err != nil {
+		return fmt.Errorf("failed to create VM: %w", err)
+	}
+	defer c.deleteUnlessFailed(ctx, checkFailed, func(ctx context.Context) {
+		if err := c.client.DeleteVirtualMachine(ctx, c.namespace, importVMName); ignoreNotFound(err) != nil {
+			log.Printf("failed to delete VM %q: %s", importVMName, err)
+		}
+	})
+
+	return c.waitForVMIReady(ctx, importVMName, &c.results.CDIImport, errStr)
+}
+
+func (c *Checkup) importFailed(err error) {
+	res := fmt.Sprintf("%s: %v", ErrImportFailed, err)
+	log.Print(res)
+	appendSep(&c.results.CDIImport, res)
+}
+
+// newImageServerPod returns a restricted pod serving the generated test images from the checkup image
+func (c *Checkup) newImageServerPod(image string) *corev1.Pod {
+	return restrictPod(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: imageServerPodName,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       c.checkupConfig.PodName,
+				UID:        types.UID(c.checkupConfig.PodUID),
+			}},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:  "image-server",
+				Image: image,
+				Args:  []string{imageserver.Command},
+				Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: imageserver.Port}},
+				ReadinessProbe: &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt(imageserver.Port)},
+					},
+				},
+			}},
+		},
+	})
+}
+
+// waitForPodReady waits for the pod to be ready and returns its IP
+func (c *Checkup) waitForPodReady(ctx context.Context, name string) (string, error) {
+	podIP := ""
+	conditionFn := func(ctx context.Context) (bool, error) {
+		pod, err := c.client.GetPod(ctx, c.namespace, name)
+		if err != nil {
+			return false, ignoreNotFound(err)
+		}
+		if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+			return false, fmt.Errorf("pod terminated with phase %s", pod.Status.Phase)
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue && pod.Status.PodIP != "" {
+				podIP = pod.Status.PodIP
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	log.Printf("Waiting for pod %q ready", name)
+	if err := wait.PollImmediateWithContext(ctx, pollInterval, c.checkupConfig.VMITimeout, conditionFn); err != nil {
+		if errors.Is(err, wait.ErrWaitTimeout) {
+			return "", errors.New("not ready")
+		}
+		return "", err
+	}
+
+	return podIP, nil
+}
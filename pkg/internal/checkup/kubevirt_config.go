@@ -0,0 +1,163 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	FeatureGateHotplugVolumes    = "HotplugVolumes"
+	FeatureGateSnapshot          = "Snapshot"
+	FeatureGateVMExport          = "VMExport"
+	FeatureGateExpandDisks       = "ExpandDisks"
+	FeatureGateVolumeMigration   = "VolumeMigration"
+	FeatureGateVMPersistentState = "VMPersistentState"
+	// FeatureGateVolumesUpdateStrategy enables the updateVolumesStrategy the storage migration relies on
+	FeatureGateVolumesUpdateStrategy = "VolumesUpdateStrategy"
+
+	VMRolloutStrategyLiveUpdate = "LiveUpdate"
+
+	MessageSkipNoKubeVirt = "Skip check - no KubeVirt CR"
+	// MessageSkipFeatureGateDisabled is formatted with the name of the disabled feature gate
+	MessageSkipFeatureGateDisabled = "Skip check - KubeVirt feature gate %s is disabled"
+	// MessageSkipNoLiveUpdateRollout is formatted with the configured vmRolloutStrategy
+	MessageSkipNoLiveUpdateRollout = "Skip check - KubeVirt vmRolloutStrategy is %s, not LiveUpdate"
+)
+
+// storageFeatures are the storage related KubeVirt features along with the feature gates enabling them
+var storageFeatures = []struct {
+	name        string
+	featureGate string
+}{
+	{name: "hotplug volumes", featureGate: FeatureGateHotplugVolumes},
+	{name: "snapshot", featureGate: FeatureGateSnapshot},
+	{name: "export", featureGate: FeatureGateVMExport},
+	{name: "expand disks", featureGate: FeatureGateExpandDisks},
+	{name: "volume migration", featureGate: FeatureGateVolumeMigration},
+	{name: "persistent VM state", featureGate: FeatureGateVMPersistentState},
+}
+
+// checkKubeVirtConfig reports the storage features enabled in the KubeVirt CR along with the related configuration,
+// so checks depending on disabled features are skipped rather than timing out
+func (c *Checkup) checkKubeVirtConfig(ctx context.Context) error {
+	log.Print("checkKubeVirtConfig")
+
+	kvs, err := c.client.ListKubeVirts(ctx)
+	if err != nil {
+		return err
+	}
+	if len(kvs.Items) == 0 {
+		log.Print(MessageSkipNoKubeVirt)
+		c.results.KubeVirtConfig = MessageSkipNoKubeVirt
+		return nil
+	}
+	c.kubeVirt = &kvs.Items[0]
+	kvConfig := &c.kubeVirt.Spec.Configuration
+
+	featureGates := "none"
+	if devConfig := kvConfig.DeveloperConfiguration; devConfig != nil && len(devConfig.FeatureGates) != 0 {
+		featureGates = strings.Join(devConfig.FeatureGates, ", ")
+	}
+	appendSep(&c.results.KubeVirtConfig, "featureGates: "+featureGates)
+
+	var enabled, disabled []string
+	for _, feature := range storageFeatures {
+		if c.isFeatureGateEnabled(feature.featureGate) {
+			enabled = append(enabled, feature.name)
+		} else {
+			disabled = append(disabled, fmt.Sprintf("%s (%s)", feature.name, feature.featureGate))
+		}
+	}
+	appendSep(&c.results.KubeVirtConfig, "enabled storage features: "+joinOrNone(enabled))
+	appendSep(&c.results.KubeVirtConfig, "disabled storage features: "+joinOrNone(disabled))
+
+	vmStateSc := kvConfig.VMStateStorageClass
+	if vmStateSc == "" {
+		vmStateSc = "unset, the default storage class is used"
+	}
+	appendSep(&c.results.KubeVirtConfig, "vmStateStorageClass: "+vmStateSc)
+	appendSep(&c.results.KubeVirtConfig, "migrations: "+formatMigrationConfiguration(kvConfig.MigrationConfiguration))
+
+	// vmRolloutStrategy is missing from the vendored KubeVirt API
+	kv, err := c.client.GetUnstructuredKubeVirt(ctx, c.kubeVirt.Namespace, c.kubeVirt.Name)
+	if err != nil {
+		return err
+	}
+	c.vmRolloutStrategy, _, _ = unstructured.NestedString(kv.Object, "spec", "configuration", "vmRolloutStrategy")
+	appendSep(&c.results.KubeVirtConfig, "vmRolloutStrategy: "+stringOrUnset(c.vmRolloutStrategy))
+
+	log.Print(c.results.KubeVirtConfig)
+	return nil
+}
+
+// isFeatureGateEnabled returns whether the feature gate is enabled, or true if the KubeVirt CR is unknown so the
+// dependent checks are still tried
+func (c *Checkup) isFeatureGateEnabled(featureGate string) bool {
+	if c.kubeVirt == nil {
+		return true
+	}
+	devConfig := c.kubeVirt.Spec.Configuration.DeveloperConfiguration
+	return devConfig != nil && contains(devConfig.FeatureGates, featureGate)
+}
+
+func formatMigrationConfiguration(migrations *kvcorev1.MigrationConfiguration) string {
+	if migrations == nil {
+		migrations = &kvcorev1.MigrationConfiguration{}
+	}
+
+	bandwidth := "unset"
+	if migrations.BandwidthPerMigration != nil {
+		bandwidth = migrations.BandwidthPerMigration.String()
+	}
+	return fmt.Sprintf("bandwidthPerMigration %s, parallelMigrationsPerCluster %s, "+
+		"parallelOutboundMigrationsPerNode %s, completionTimeoutPerGiB %s, progressTimeout %s, allowAutoConverge %s",
+		bandwidth, formatOptional(migrations.ParallelMigrationsPerCluster),
+		formatOptional(migrations.ParallelOutboundMigrationsPerNode), formatOptional(migrations.CompletionTimeoutPerGiB),
+		formatOptional(migrations.ProgressTimeout), formatOptional(migrations.AllowAutoConverge))
+}
+
+func formatOptional[T any](v *T) string {
+	if v == nil {
+		return "unset"
+	}
+	return fmt.Sprint(*v)
+}
+
+func stringOrUnset(s string) string {
+	if s == "" {
+		return "unset"
+	}
+	return s
+}
+
+func joinOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
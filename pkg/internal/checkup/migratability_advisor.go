@@ -0,0 +1,166 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+const fsTypeParam = "csi.storage.k8s.io/fstype"
+
+// adviseMigratability returns which storage class of the same provisioner to use for a VM not migratable due to its
+// storage access mode, or the StorageProfile patch making one advertise ReadWriteMany
+func (c *Checkup) adviseMigratability(ctx context.Context) (string, error) {
+	log.Print("adviseMigratability")
+
+	scName, err := c.vmUnderTestStorageClass(ctx)
+	if err != nil || scName == "" {
+		return "", err
+	}
+	scs, err := c.client.ListStorageClasses(ctx)
+	if err != nil {
+		return "", err
+	}
+	sps, err := c.client.ListStorageProfiles(ctx)
+	if err != nil {
+		return "", err
+	}
+	provisioner := storageClassProvisioner(scs, &scName)
+	if provisioner == "" {
+		return "", nil
+	}
+
+	advice := fmt.Sprintf("VMI %q volume storage class %q (provisioner %q) does not provide ReadWriteMany",
+		c.vmUnderTest.Name, scName, provisioner)
+	if c.rwoOnlyProvisioner(provisioner) {
+		appendSep(&advice, fmt.Sprintf("provisioner %q supports ReadWriteOnce only", provisioner))
+		appendSep(&advice, otherProvisionersRWXAdvice(scs, sps, provisioner))
+		return advice, nil
+	}
+
+	var ready, patchable []string
+	for i := range scs.Items {
+		sc := &scs.Items[i]
+		if sc.Provisioner != provisioner || sc.Name == scName || contains(c.checkupConfig.RWOOnlyStorageClasses, sc.Name) {
+			continue
+		}
+		if mode, ok := rwxVolumeMode(storageProfileByName(sps, sc.Name)); ok {
+			ready = append(ready, fmt.Sprintf("use storage class %q of the same provisioner, its StorageProfile advertises "+
+				"ReadWriteMany %s: set spec.param.storageClass to %q and create the VM disks on it", sc.Name, mode, sc.Name))
+		} else {
+			patchable = append(patchable, fmt.Sprintf("storage class %q of the same provisioner does not advertise "+
+				"ReadWriteMany; if its backend provides it (e.g. NFS rather than iSCSI), patch its StorageProfile: %s",
+				sc.Name, storageProfileRWXPatch(sc)))
+		}
+	}
+	sort.Strings(ready)
+	sort.Strings(patchable)
+	for _, line := range append(ready, patchable...) {
+		appendSep(&advice, line)
+	}
+	if len(ready) == 0 && len(patchable) == 0 {
+		appendSep(&advice, fmt.Sprintf("there is no other storage class of provisioner %q", provisioner))
+		appendSep(&advice, otherProvisionersRWXAdvice(scs, sps, provisioner))
+	}
+
+	return advice, nil
+}
+
+// vmUnderTestStorageClass returns the storage class of the first DataVolume of the VM under test
+func (c *Checkup) vmUnderTestStorageClass(ctx context.Context) (string, error) {
+	for _, vol := range c.vmUnderTest.Spec.Template.Spec.Volumes {
+		if vol.DataVolume == nil {
+			continue
+		}
+		pvc, err := c.client.GetPersistentVolumeClaim(ctx, c.namespace, vol.DataVolume.Name)
+		if err != nil {
+			return "", err
+		}
+		if pvc.Spec.StorageClassName != nil {
+			return *pvc.Spec.StorageClassName, nil
+		}
+	}
+	return "", nil
+}
+
+func otherProvisionersRWXAdvice(scs *storagev1.StorageClassList, sps *cdiv1.StorageProfileList, provisioner string) string {
+	var names []string
+	for i := range scs.Items {
+		sc := &scs.Items[i]
+		if _, ok := rwxVolumeMode(storageProfileByName(sps, sc.Name)); ok && sc.Provisioner != provisioner {
+			names = append(names, sc.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "no storage class advertises ReadWriteMany, the VM cannot be live migrated"
+	}
+	sort.Strings(names)
+	return "storage classes of other provisioners advertising ReadWriteMany: " + strings.Join(names, ", ")
+}
+
+// rwxVolumeMode returns the volume mode of the first StorageProfile claimPropertySet with ReadWriteMany
+func rwxVolumeMode(sp *cdiv1.StorageProfile) (string, bool) {
+	if sp == nil {
+		return "", false
+	}
+	for _, cpSet := range sp.Status.ClaimPropertySets {
+		if hasRWX([]cdiv1.ClaimPropertySet{cpSet}) {
+			return volumeModeName(cpSet.VolumeMode), true
+		}
+	}
+	return "", false
+}
+
+func storageProfileByName(sps *cdiv1.StorageProfileList, name string) *cdiv1.StorageProfile {
+	for i := range sps.Items {
+		if sps.Items[i].Name == name {
+			return &sps.Items[i]
+		}
+	}
+	return nil
+}
+
+// storageProfileRWXPatch returns the kubectl command making the StorageProfile advertise ReadWriteMany, Filesystem for
+// NFS storage classes and Block otherwise
+func storageProfileRWXPatch(sc *storagev1.StorageClass) string {
+	volumeMode := corev1.PersistentVolumeBlock
+	if strings.EqualFold(sc.Parameters[fsTypeParam], "nfs") {
+		volumeMode = corev1.PersistentVolumeFilesystem
+	}
+	patch, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"claimPropertySets": []cdiv1.ClaimPropertySet{{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+				VolumeMode:  &volumeMode,
+			}},
+		},
+	})
+	return fmt.Sprintf("kubectl patch storageprofile %s --type merge -p '%s'", sc.Name, patch)
+}
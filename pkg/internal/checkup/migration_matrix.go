@@ -0,0 +1,233 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	ErrTargetedLiveMigrationFailed = "some of the targeted live migrations failed"
+	MessageSkipNoMigrationNodePair = "Skip check - no schedulable node pair matches the migration node selectors"
+)
+
+// migrationHop is a live migration of the VM under test from one node to another
+type migrationHop struct {
+	source string
+	target string
+	// reposition is set for hops moving the VM to the source node of the next selected node pair
+	reposition bool
+}
+
+func (c *Checkup) isTargetedLiveMigration() bool {
+	return c.checkupConfig.MigrationAllNodes || c.checkupConfig.MigrationSourceNodeSelector != "" ||
+		c.checkupConfig.MigrationTargetNodeSelector != ""
+}
+
+// checkVMILiveMigrationMatrix live migrates the VM under test between the selected node pairs, or around all the
+// schedulable nodes, and reports the duration of each migration
+func (c *Checkup) checkVMILiveMigrationMatrix(ctx context.Context, vmi *kvcorev1.VirtualMachineInstance, nodes *corev1.NodeList,
+	errStr *string) error {
+	log.Print("checkVMILiveMigrationMatrix")
+
+	hops, err := c.getMigrationHops(vmi.Status.NodeName, nodes)
+	if err != nil {
+		return err
+	}
+	if len(hops) == 0 {
+		log.Print(MessageSkipNoMigrationNodePair)
+		c.results.VMLiveMigration = MessageSkipNoMigrationNodePair
+		return nil
+	}
+
+	hostnames := map[string]string{}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		hostnames[node.Name] = node.Name
+		if hostname, ok := node.Labels[corev1.LabelHostname]; ok {
+			hostnames[node.Name] = hostname
+		}
+	}
+
+	var matrix string
+	current := vmi.Status.NodeName
+	succeeded := 0
+	for i, hop := range hops {
+		desc := fmt.Sprintf("%s -> %s", hop.source, hop.target)
+		if hop.reposition {
+			desc += " (reposition)"
+		}
+		if hop.source != current {
+			appendSep(&matrix, fmt.Sprintf("%s: skipped, VMI is on node %s", desc, current))
+			continue
+		}
+		res, landed, err := c.migrateToNode(ctx, fmt.Sprintf("vmim-%d", i), hop.target, hostnames[hop.target])
+		if err != nil {
+			return err
+		}
+		appendSep(&matrix, desc+": "+res)
+		if landed != "" {
+			current = landed
+		}
+		if landed == hop.target {
+			succeeded++
+		}
+	}
+
+	res := fmt.Sprintf("%d of %d targeted live migrations of VMI %q succeeded", succeeded, len(hops), vmi.Name)
+	log.Print(res)
+	log.Print(matrix)
+	c.results.VMLiveMigration = res
+	c.results.VMLiveMigrationMatrix = matrix
+	if succeeded != len(hops) {
+		appendSep(errStr, ErrTargetedLiveMigrationFailed)
+	}
+
+	return nil
+}
+
+// getMigrationHops returns the live migrations visiting all the schedulable nodes in turn and back to the current node,
+// or covering all the node pairs matching the source and target node selectors
+func (c *Checkup) getMigrationHops(current string, nodes *corev1.NodeList) ([]migrationHop, error) {
+	if c.checkupConfig.MigrationAllNodes {
+		path := []string{current}
+		for _, node := range schedulableNodeNames(nodes) {
+			if node != current {
+				path = append(path, node)
+			}
+		}
+		if len(path) == 1 {
+			return nil, nil
+		}
+		path = append(path, current)
+		var hops []migrationHop
+		for i := 1; i < len(path); i++ {
+			hops = append(hops, migrationHop{source: path[i-1], target: path[i]})
+		}
+		return hops, nil
+	}
+
+	sources, err := matchingSchedulableNodes(nodes, c.checkupConfig.MigrationSourceNodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := matchingSchedulableNodes(nodes, c.checkupConfig.MigrationTargetNodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	var hops []migrationHop
+	for _, source := range sources {
+		for _, target := range targets {
+			if source == target {
+				continue
+			}
+			if current != source {
+				hops = append(hops, migrationHop{source: current, target: source, reposition: true})
+			}
+			hops = append(hops, migrationHop{source: source, target: target})
+			current = target
+		}
+	}
+	return hops, nil
+}
+
+// migrateToNode live migrates the VM under test to the given node, and returns the migration result along with the
+// node the VMI landed on
+func (c *Checkup) migrateToNode(ctx context.Context, name, node, hostname string) (res, landed string, err error) {
+	vmName := c.vmUnderTest.Name
+	vmi, err := c.client.GetVirtualMachineInstance(ctx, c.namespace, vmName)
+	if err != nil {
+		return "", "", err
+	}
+	var prevMigrationUID types.UID
+	if vmi.Status.MigrationState != nil {
+		prevMigrationUID = vmi.Status.MigrationState.MigrationUID
+	}
+
+	// The vendored KubeVirt API predates addedNodeSelector, hence the unstructured VMIM
+	vmim := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": kvcorev1.GroupVersion.String(),
+		"kind":       "VirtualMachineInstanceMigration",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"vmiName":           vmName,
+			"addedNodeSelector": map[string]interface{}{corev1.LabelHostname: hostname},
+		},
+	}}
+	log.Printf("Migrating VMI %q to node %s", vmName, node)
+	start := time.Now()
+	if _, err := c.client.CreateUnstructuredVirtualMachineInstanceMigration(ctx, c.namespace, vmim); err != nil {
+		return "", "", fmt.Errorf("failed to create VMI LiveMigration: %w", err)
+	}
+
+	var ms *kvcorev1.VirtualMachineInstanceMigrationState
+	conditionFn := func(ctx context.Context) (bool, error) {
+		vmi, err := c.client.GetVirtualMachineInstance(ctx, c.namespace, vmName)
+		if err != nil {
+			return false, ignoreNotFound(err)
+		}
+		if vmi.Status.MigrationState == nil || vmi.Status.MigrationState.MigrationUID == prevMigrationUID {
+			return false, nil
+		}
+		ms = vmi.Status.MigrationState
+		return ms.Completed || ms.Failed, nil
+	}
+	if err := wait.PollImmediateWithContext(ctx, pollInterval, c.checkupConfig.VMITimeout, conditionFn); err != nil {
+		return fmt.Sprintf("failed waiting for migration: %v", err), "", nil
+	}
+	if ms.Failed {
+		return "migration failed", "", nil
+	}
+
+	duration := migrationDuration(ms, time.Since(start))
+	if ms.TargetNode != node {
+		return fmt.Sprintf("completed in %s, but landed on node %s", duration, ms.TargetNode), ms.TargetNode, nil
+	}
+	return duration.String(), node, nil
+}
+
+// matchingSchedulableNodes returns the sorted names of the schedulable nodes matching the label selector
+func matchingSchedulableNodes(nodes *corev1.NodeList, selector string) ([]string, error) {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Labels[kvcorev1.NodeSchedulable] == StrTrue && sel.Matches(labels.Set(node.Labels)) {
+			names = append(names, node.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
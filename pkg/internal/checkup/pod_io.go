@@ -0,0 +1,231 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	ioBaselinePvcName    = "checkup-io-baseline-pvc"
+	ioBaselinePodName    = "checkup-io-baseline"
+	ioBaselinePvcSize    = "1Gi"
+	ioBaselineMountPath  = "/data"
+	ioBaselineDevicePath = "/dev/io-baseline"
+	// ioBaselineSizeMiB is the amount of data written and read back by the workload
+	ioBaselineSizeMiB = 256
+	// ioBaselineFSGroup is the checkup image user, owning the volume when the checkup pod has no fsGroup assigned
+	ioBaselineFSGroup = 900
+
+	ErrPodIOBaselineFailed = "pod I/O baseline failed"
+)
+
+// checkPodIOBaseline runs a sequential direct I/O workload in a plain pod on a PVC from the same storage class the VMs
+// use, so storage performance can be told apart from virtualization overhead. checkVMIOBaseline compares it to the VM.
+func (c *Checkup) checkPodIOBaseline(ctx context.Context, errStr *string) error {
+	log.Print("checkPodIOBaseline")
+
+	if c.defaultStorageClass == "" && c.checkupConfig.StorageClass == "" {
+		log.Print(MessageSkipNoDefaultStorageClass)
+		c.results.PodIOBaseline = MessageSkipNoDefaultStorageClass
+		return nil
+	}
+
+	if !c.pvcBound {
+		log.Print(MessageSkipPVCNotBound)
+		c.results.PodIOBaseline = MessageSkipPVCNotBound
+		return nil
+	}
+
+	checkupPod, err := c.client.GetPod(ctx, c.namespace, c.checkupConfig.PodName)
+	if err != nil {
+		return fmt.Errorf("failed to get checkup pod: %w", err)
+	}
+
+	checkFailed := failedSince(errStr)
+	dv := c.newBlankDataVolume(ioBaselinePvcName, ioBaselinePvcSize)
+	if _, err := c.client.CreateDataVolume(ctx, c.namespace, dv); err != nil {
+		return err
+	}
+	defer c.deleteUnlessFailed(ctx, checkFailed, func(ctx context.Context) {
+		c.deleteDataVolume(ctx, ioBaselinePvcName)
+	})
+
+	if !c.waitForPVCBound(ctx, ioBaselinePvcName, &c.results.PodIOBaseline, errStr) {
+		return nil
+	}
+	pvc, err := c.client.GetPersistentVolumeClaim(ctx, c.namespace, ioBaselinePvcName)
+	if err != nil {
+		return err
+	}
+
+	pod := c.newIOBaselinePod(ioBaselinePodName, checkupPod, pvc, "io-baseline", ioWorkloadScript)
+	if _, err := c.client.CreatePod(ctx, c.namespace, pod); err != nil {
+		return fmt.Errorf("failed to create I/O baseline pod: %w", err)
+	}
+	defer c.deleteUnlessFailed(ctx, checkFailed, func(ctx context.Context) {
+		if err := c.client.DeletePod(ctx, c.namespace, ioBaselinePodName); ignoreNotFound(err) != nil {
+			log.Printf("failed to delete pod %q: %s", ioBaselinePodName, err)
+		}
+	})
+
+	if err := c.waitForPodCompletion(ctx, ioBaselinePodName); err != nil {
+		res := fmt.Sprintf("%s: %v", ErrPodIOBaselineFailed, err)
+		log.Print(res)
+		appendSep(&c.results.PodIOBaseline, res)
+		appendSep(errStr, ErrPodIOBaselineFailed)
+		return nil
+	}
+
+	logs, err := c.client.GetPodLogs(ctx, c.namespace, ioBaselinePodName, "", 0)
+	if err != nil {
+		return fmt.Errorf("failed to get I/O baseline pod logs: %w", err)
+	}
+
+	c.podIOMetrics = parseIOBaselineLogs(logs)
+	res := fmt.Sprintf("Pod I/O baseline on storage class %q: %s", stringValue(pvc.Spec.StorageClassName),
+		formatIOMetrics(c.podIOMetrics))
+	log.Print(res)
+	appendSep(&c.results.PodIOBaseline, res)
+
+	return nil
+}
+
+// newIOBaselinePod returns a restricted pod running the script on the PVC block device, or on fileName in its
+// filesystem, with the volume owned by the fsGroup of the checkup pod, which OpenShift assigns from the namespace range.
+func (c *Checkup) newIOBaselinePod(name string, checkupPod *corev1.Pod, pvc *corev1.PersistentVolumeClaim, fileName string,
+	script func(target string) string) *corev1.Pod {
+	target := ioBaselineMountPath + "/" + fileName
+	container := corev1.Container{
+		Name:  "io-baseline",
+		Image: checkupPod.Spec.Containers[0].Image,
+	}
+	if pvc.Spec.VolumeMode != nil && *pvc.Spec.VolumeMode == corev1.PersistentVolumeBlock {
+		target = ioBaselineDevicePath
+		container.VolumeDevices = []corev1.VolumeDevice{{Name: "data", DevicePath: ioBaselineDevicePath}}
+	} else {
+		container.VolumeMounts = []corev1.VolumeMount{{Name: "data", MountPath: ioBaselineMountPath}}
+	}
+
+	container.Command = []string{"/bin/sh", "-c", script(target)}
+
+	fsGroup := int64(ioBaselineFSGroup)
+	if checkupPod.Spec.SecurityContext != nil && checkupPod.Spec.SecurityContext.FSGroup != nil {
+		fsGroup = *checkupPod.Spec.SecurityContext.FSGroup
+	}
+
+	return restrictPod(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       c.checkupConfig.PodName,
+				UID:        types.UID(c.checkupConfig.PodUID),
+			}},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:   corev1.RestartPolicyNever,
+			SecurityContext: &corev1.PodSecurityContext{FSGroup: &fsGroup},
+			Containers:      []corev1.Container{container},
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc.Name},
+				},
+			}},
+		},
+	})
+}
+
+func (c *Checkup) waitForPodCompletion(ctx context.Context, name string) error {
+	conditionFn := func(ctx context.Context) (bool, error) {
+		pod, err := c.client.GetPod(ctx, c.namespace, name)
+		if err != nil {
+			return false, ignoreNotFound(err)
+		}
+		switch pod.Status.Phase {
+		case corev1.PodSucceeded:
+			return true, nil
+		case corev1.PodFailed:
+			return false, errors.New("pod failed")
+		default:
+			return false, nil
+		}
+	}
+
+	log.Printf("Waiting for pod %q completion", name)
+	return wait.PollImmediateWithContext(ctx, pollInterval, c.checkupConfig.VMITimeout, conditionFn)
+}
+
+// ioWorkloadScript writes and reads back ioBaselineSizeMiB with direct I/O on target, printing the dd summary lines
+func ioWorkloadScript(target string) string {
+	return fmt.Sprintf(
+		"echo \"write: $(dd if=/dev/zero of=%[1]s bs=1M count=%[2]d oflag=direct conv=fsync 2>&1 | tail -n 1)\" && "+
+			"echo \"read: $(dd if=%[1]s of=/dev/null bs=1M count=%[2]d iflag=direct 2>&1 | tail -n 1)\"",
+		target, ioBaselineSizeMiB)
+}
+
+// ioMetric is the throughput of an I/O workload operation, e.g. "write" at "224 MB/s"
+type ioMetric struct {
+	op   string
+	rate string
+}
+
+// parseIOBaselineLogs extracts the throughput from the dd summary lines, e.g.
+// "write: 268435456 bytes (268 MB, 256 MiB) copied, 1.2 s, 224 MB/s"
+func parseIOBaselineLogs(logs string) []ioMetric {
+	var metrics []ioMetric
+	for _, line := range strings.Split(strings.TrimSpace(logs), "\n") {
+		op, summary, found := strings.Cut(line, ": ")
+		if !found {
+			continue
+		}
+		if i := strings.LastIndex(summary, ", "); i != -1 {
+			summary = summary[i+2:]
+		}
+		metrics = append(metrics, ioMetric{op: op, rate: summary})
+	}
+	return metrics
+}
+
+func formatIOMetrics(metrics []ioMetric) string {
+	var formatted []string
+	for _, metric := range metrics {
+		formatted = append(formatted, metric.op+" "+metric.rate)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,323 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+// qemuUID is the uid and gid of the qemu user running in virt-launcher
+const qemuUID = "107"
+
+// remediation is the fix of a finding, as a manifest to create or a merge patch to apply
+type remediation struct {
+	// file name of the manifest, also its key in the remediation ConfigMap
+	file        string
+	finding     string
+	explanation string
+	command     string
+	manifest    map[string]interface{}
+	// template is what must be edited in the manifest before applying it, when its values are only placeholders
+	template string
+}
+
+func (r *remediation) yaml() (string, error) {
+	manifest, err := yaml.Marshal(r.manifest)
+	if err != nil {
+		return "", err
+	}
+	header := fmt.Sprintf("# Finding: %s\n# %s\n", r.finding, r.explanation)
+	if r.template != "" {
+		header += fmt.Sprintf("# Template, edit before applying: %s\n", r.template)
+	}
+	return fmt.Sprintf("%s# Apply: %s\n%s", header, r.command, manifest), nil
+}
+
+// checkRemediations reports a manifest or patch fixing each of the well known findings, and optionally writes them to
+// the remediation ConfigMap
+func (c *Checkup) checkRemediations(ctx context.Context, scs *storagev1.StorageClassList, sps *cdiv1.StorageProfileList,
+	vscs *snapshotv1.VolumeSnapshotClassList) error {
+	log.Print("checkRemediations")
+
+	var remediations []remediation
+	remediations = append(remediations, emptyClaimPropertySetsRemediations(sps)...)
+	remediations = append(remediations, c.multipleDefaultStorageClassesRemediations(scs)...)
+	remediations = append(remediations, missingVolumeSnapshotClassRemediations(sps, vscs)...)
+	efsRemediations, err := c.unsetEfsStorageClassRemediations(scs)
+	if err != nil {
+		return err
+	}
+	remediations = append(remediations, efsRemediations...)
+	rbdRemediations, err := c.nonVirtRbdStorageClassRemediations(scs)
+	if err != nil {
+		return err
+	}
+	remediations = append(remediations, rbdRemediations...)
+	if len(remediations) == 0 {
+		return nil
+	}
+
+	var sections []string
+	data := map[string]string{}
+	for i := range remediations {
+		section, err := remediations[i].yaml()
+		if err != nil {
+			return err
+		}
+		sections = append(sections, section)
+		data[remediations[i].file] = section
+	}
+	c.results.Remediations = strings.Join(sections, "---\n")
+
+	if cmName := c.checkupConfig.RemediationConfigMap; cmName != "" {
+		return c.writeRemediationConfigMap(ctx, cmName, data)
+	}
+	return nil
+}
+
+func (c *Checkup) writeRemediationConfigMap(ctx context.Context, name string, data map[string]string) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: c.namespace},
+		Data:       data,
+	}
+	log.Printf("Writing remediations to ConfigMap %s/%s", c.namespace, name)
+	if err := c.createOrUpdateConfigMap(ctx, cm); err != nil {
+		return fmt.Errorf("failed to write remediation ConfigMap: %w", err)
+	}
+	return nil
+}
+
+// createOrUpdateConfigMap creates the ConfigMap, or overwrites it when it already exists from a previous run
+func (c *Checkup) createOrUpdateConfigMap(ctx context.Context, cm *corev1.ConfigMap) error {
+	_, err := c.client.CreateConfigMap(ctx, c.namespace, cm)
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = c.client.UpdateConfigMap(ctx, c.namespace, cm)
+	}
+	return err
+}
+
+// emptyClaimPropertySetsRemediations sets the claimPropertySets of the StorageProfiles of provisioners CDI does not know.
+// The modes a provisioner supports cannot be told from its CSIDriver, so the patch is a template to edit.
+func emptyClaimPropertySetsRemediations(sps *cdiv1.StorageProfileList) []remediation {
+	var remediations []remediation
+	for i := range sps.Items {
+		sp := &sps.Items[i]
+		provisioner := sp.Status.Provisioner
+		if provisioner == nil || unsupportedProvisioner(*provisioner) || len(sp.Status.ClaimPropertySets) != 0 {
+			continue
+		}
+		file := fmt.Sprintf("storageprofile-%s.yaml", sp.Name)
+		remediations = append(remediations, remediation{
+			file:    file,
+			finding: fmt.Sprintf("StorageProfile %s has empty claimPropertySets", sp.Name),
+			explanation: fmt.Sprintf("CDI does not know provisioner %q, set the access and volume modes it supports "+
+				"(ReadWriteMany is required for live migration)", *provisioner),
+			command:  fmt.Sprintf("kubectl patch storageprofile %s --type merge --patch-file %s", sp.Name, file),
+			template: "replace the placeholder accessModes and volumeMode with the ones the provisioner supports",
+			manifest: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"claimPropertySets": []interface{}{map[string]interface{}{
+						"accessModes": []string{string(corev1.ReadWriteOnce)},
+						"volumeMode":  string(corev1.PersistentVolumeFilesystem),
+					}},
+				},
+			},
+		})
+	}
+	return remediations
+}
+
+// multipleDefaultStorageClassesRemediations unsets each default annotation on all the storage classes but the first one
+// having it, as checkDefaultStorageClass picks the first one
+func (c *Checkup) multipleDefaultStorageClassesRemediations(scs *storagev1.StorageClassList) []remediation {
+	if c.results.DefaultStorageClass != ErrMultipleDefaultStorageClasses {
+		return nil
+	}
+	kept := map[string]string{}
+	var remediations []remediation
+	for i := range scs.Items {
+		sc := &scs.Items[i]
+		annotations := map[string]interface{}{}
+		var keptNames []string
+		for _, ann := range []string{AnnDefaultVirtStorageClass, AnnDefaultStorageClass} {
+			if sc.Annotations[ann] != StrTrue {
+				continue
+			}
+			if kept[ann] == "" {
+				kept[ann] = sc.Name
+				continue
+			}
+			annotations[ann] = StrFalse
+			keptNames = append(keptNames, fmt.Sprintf("%s for %s", kept[ann], ann))
+		}
+		if len(annotations) == 0 {
+			continue
+		}
+		file := fmt.Sprintf("storageclass-%s.yaml", sc.Name)
+		remediations = append(remediations, remediation{
+			file:        file,
+			finding:     fmt.Sprintf("storage class %s is one of multiple default storage classes", sc.Name),
+			explanation: "keep a single default storage class: " + strings.Join(keptNames, ", "),
+			command:     fmt.Sprintf("kubectl patch storageclass %s --type merge --patch-file %s", sc.Name, file),
+			manifest:    map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}},
+		})
+	}
+	return remediations
+}
+
+// missingVolumeSnapshotClassRemediations creates a default VolumeSnapshotClass for each provisioner of the StorageProfiles
+// cloning with snapshots without one
+func missingVolumeSnapshotClassRemediations(sps *cdiv1.StorageProfileList, vscs *snapshotv1.VolumeSnapshotClassList) []remediation {
+	var remediations []remediation
+	drivers := map[string]bool{}
+	for i := range sps.Items {
+		sp := &sps.Items[i]
+		strategy := sp.Status.CloneStrategy
+		provisioner := sp.Status.Provisioner
+		if provisioner == nil || unsupportedProvisioner(*provisioner) || drivers[*provisioner] ||
+			(strategy != nil && *strategy != cdiv1.CloneStrategySnapshot) || hasDriver(vscs, *provisioner) {
+			continue
+		}
+		drivers[*provisioner] = true
+		name := sp.Name + "-snapclass"
+		file := fmt.Sprintf("volumesnapshotclass-%s.yaml", name)
+		remediations = append(remediations, remediation{
+			file:    file,
+			finding: fmt.Sprintf("StorageProfile %s clones with snapshots, but there is no VolumeSnapshotClass for %s", sp.Name, *provisioner),
+			explanation: "create a default VolumeSnapshotClass if the CSI driver supports snapshots, otherwise set the " +
+				"StorageProfile spec.cloneStrategy to csi-clone or copy",
+			command: fmt.Sprintf("kubectl create -f %s", file),
+			manifest: map[string]interface{}{
+				"apiVersion":     snapshotv1.SchemeGroupVersion.String(),
+				"kind":           "VolumeSnapshotClass",
+				"metadata":       map[string]interface{}{"name": name, "annotations": map[string]string{AnnDefaultSnapshotClass: StrTrue}},
+				"driver":         *provisioner,
+				"deletionPolicy": string(snapshotv1.VolumeSnapshotContentDelete),
+			},
+		})
+	}
+	return remediations
+}
+
+// unsetEfsStorageClassRemediations creates a copy of the EFS storage class used by VMs with the qemu uid and gid, as
+// storage class parameters are immutable
+func (c *Checkup) unsetEfsStorageClassRemediations(scs *storagev1.StorageClassList) ([]remediation, error) {
+	if c.results.VMsWithUnsetEfsStorageClass == "" {
+		return nil, nil
+	}
+	scName, err := c.getUnsetEfsStorageClass(scs)
+	if err != nil || scName == nil {
+		return nil, err
+	}
+	sc := getStorageClass(scs, *scName)
+	params := map[string]string{}
+	for k, v := range sc.Parameters {
+		params[k] = v
+	}
+	params["uid"] = qemuUID
+	params["gid"] = qemuUID
+	name := sc.Name + "-virt"
+	file := fmt.Sprintf("storageclass-%s.yaml", name)
+	manifest := map[string]interface{}{
+		"apiVersion":  storagev1.SchemeGroupVersion.String(),
+		"kind":        "StorageClass",
+		"metadata":    map[string]interface{}{"name": name},
+		"provisioner": sc.Provisioner,
+		"parameters":  params,
+	}
+	if sc.ReclaimPolicy != nil {
+		manifest["reclaimPolicy"] = string(*sc.ReclaimPolicy)
+	}
+	if sc.VolumeBindingMode != nil {
+		manifest["volumeBindingMode"] = string(*sc.VolumeBindingMode)
+	}
+
+	return []remediation{{
+		file:    file,
+		finding: fmt.Sprintf("VMs use EFS storage class %s whose uid and gid are not set", sc.Name),
+		explanation: fmt.Sprintf("storage class parameters are immutable, create %s with the uid and gid of the qemu user "+
+			"and use it for the VM disks", name),
+		command:  fmt.Sprintf("kubectl create -f %s", file),
+		manifest: manifest,
+	}}, nil
+}
+
+// nonVirtRbdStorageClassRemediations makes the RBD virtualization storage class the default one for VM disks, unsetting
+// the current default first so there is a single one
+func (c *Checkup) nonVirtRbdStorageClassRemediations(scs *storagev1.StorageClassList) ([]remediation, error) {
+	if c.results.VMsWithNonVirtRbdStorageClass == "" {
+		return nil, nil
+	}
+	virtSC, err := c.getVirtStorageClass(scs)
+	if err != nil || virtSC == nil {
+		return nil, err
+	}
+
+	var remediations []remediation
+	for i := range scs.Items {
+		sc := &scs.Items[i]
+		if sc.Name == *virtSC || sc.Annotations[AnnDefaultVirtStorageClass] != StrTrue {
+			continue
+		}
+		file := fmt.Sprintf("storageclass-%s.yaml", sc.Name)
+		remediations = append(remediations, remediation{
+			file:        file,
+			finding:     fmt.Sprintf("storage class %s is the default storage class for VM disks instead of %s", sc.Name, *virtSC),
+			explanation: fmt.Sprintf("unset %s as the default storage class for VM disks before making %s the default", sc.Name, *virtSC),
+			command:     fmt.Sprintf("kubectl patch storageclass %s --type merge --patch-file %s", sc.Name, file),
+			manifest: map[string]interface{}{
+				"metadata": map[string]interface{}{"annotations": map[string]string{AnnDefaultVirtStorageClass: StrFalse}},
+			},
+		})
+	}
+
+	file := fmt.Sprintf("storageclass-%s.yaml", *virtSC)
+	return append(remediations, remediation{
+		file:    file,
+		finding: "VMs use an RBD storage class without the krbd:rxbounce map option",
+		explanation: fmt.Sprintf("make %s the default storage class for VM disks, and recreate or storage migrate the "+
+			"disks of the VMs reported in vmsWithNonVirtRbdStorageClass to it", *virtSC),
+		command: fmt.Sprintf("kubectl patch storageclass %s --type merge --patch-file %s", *virtSC, file),
+		manifest: map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": map[string]string{AnnDefaultVirtStorageClass: StrTrue}},
+		},
+	}), nil
+}
+
+func getStorageClass(scs *storagev1.StorageClassList, name string) *storagev1.StorageClass {
+	for i := range scs.Items {
+		if scs.Items[i].Name == name {
+			return &scs.Items[i]
+		}
+	}
+	return nil
+}
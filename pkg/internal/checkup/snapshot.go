@@ -0,0 +1,247 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+const (
+	snapshotRoundTripSize = "100Mi"
+
+	ErrVolumeSnapshotRoundTripFailed    = "VolumeSnapshot round trip failed"
+	MessageSkipNoSnapshotCapableStorage = "Skip check - no snapshot-capable storage class"
+)
+
+// checkVolumeSnapshotRoundTrip snapshots a small volume on each snapshot-capable storage class and restores it,
+// so a broken snapshot controller or CSI snapshotter sidecar is caught before VMs depend on it
+func (c *Checkup) checkVolumeSnapshotRoundTrip(ctx context.Context, sps *cdiv1.StorageProfileList,
+	vscs *snapshotv1.VolumeSnapshotClassList, errStr *string) error {
+	log.Print("checkVolumeSnapshotRoundTrip")
+
+	res := ""
+	failed := false
+	for i := range sps.Items {
+		sp := &sps.Items[i]
+		provisioner := sp.Status.Provisioner
+		sc := sp.Status.StorageClass
+		if provisioner == nil || sc == nil || unsupportedProvisioner(*provisioner) || !hasDriver(vscs, *provisioner) {
+			continue
+		}
+
+		timings, err := c.volumeSnapshotRoundTrip(ctx, i, *sc, snapshotClassName(sp, vscs))
+		line := fmt.Sprintf("%s: %s", *sc, timings)
+		if err != nil {
+			line = fmt.Sprintf("%s: %s: %v", *sc, ErrVolumeSnapshotRoundTripFailed, err)
+			failed = true
+		}
+		log.Print(line)
+		appendSep(&res, line)
+	}
+
+	if res == "" {
+		log.Print(MessageSkipNoSnapshotCapableStorage)
+		res = MessageSkipNoSnapshotCapableStorage
+	}
+	c.results.VolumeSnapshotRoundTrip = res
+	if failed {
+		appendSep(errStr, ErrVolumeSnapshotRoundTripFailed)
+	}
+
+	return nil
+}
+
+// snapshotClassName returns the VolumeSnapshotClass CDI would use for the StorageProfile
+func snapshotClassName(sp *cdiv1.StorageProfile, vscs *snapshotv1.VolumeSnapshotClassList) *string {
+	if sp.Spec.SnapshotClass != nil {
+		return sp.Spec.SnapshotClass
+	}
+	if defaults := defaultVolumeSnapshotClasses(vscs, *sp.Status.Provisioner); len(defaults) != 0 {
+		return &defaults[0]
+	}
+	for i := range vscs.Items {
+		if vscs.Items[i].Driver == *sp.Status.Provisioner {
+			return &vscs.Items[i].Name
+		}
+	}
+	return nil
+}
+
+// volumeSnapshotRoundTrip creates a source volume, snapshots it and restores the snapshot, returning the timings
+func (c *Checkup) volumeSnapshotRoundTrip(ctx context.Context, idx int, sc string, vscName *string) (res string, err error) {
+	roundTripFailed := func() bool { return err != nil }
+	srcName := fmt.Sprintf("checkup-snapshot-src-%d", idx)
+	snapName := fmt.Sprintf("checkup-snapshot-%d", idx)
+	restoreName := fmt.Sprintf("checkup-snapshot-restore-%d", idx)
+
+	srcDv := c.newBlankDataVolume(srcName, snapshotRoundTripSize)
+	srcDv.Spec.Storage.StorageClassName = &sc
+	if _, err := c.client.CreateDataVolume(ctx, c.namespace, srcDv); err != nil {
+		return "", err
+	}
+	defer c.deleteUnlessFailed(ctx, roundTripFailed, func(ctx context.Context) {
+		c.deleteDataVolume(ctx, srcName)
+	})
+
+	if _, err := c.pollPVCBound(ctx, srcName, c.checkupConfig.VMITimeout); err != nil {
+		return "", fmt.Errorf("source PVC %q: %w", srcName, err)
+	}
+	// Snapshot only once CDI is done populating the source, so the measured time does not race it
+	if err := c.waitForDataVolumePhase(ctx, srcName, cdiv1.Succeeded); err != nil {
+		return "", fmt.Errorf("source DataVolume %q: %w", srcName, err)
+	}
+
+	snapshot := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: snapName,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       c.checkupConfig.PodName,
+				UID:        types.UID(c.checkupConfig.PodUID),
+			}},
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source:                  snapshotv1.VolumeSnapshotSource{PersistentVolumeClaimName: &srcName},
+			VolumeSnapshotClassName: vscName,
+		},
+	}
+	snapStart := time.Now()
+	if _, err := c.client.CreateVolumeSnapshot(ctx, c.namespace, snapshot); err != nil {
+		return "", err
+	}
+	defer c.deleteUnlessFailed(ctx, roundTripFailed, func(ctx context.Context) {
+		if err := c.client.DeleteVolumeSnapshot(ctx, c.namespace, snapName); ignoreNotFound(err) != nil {
+			log.Printf("failed to delete VolumeSnapshot %q: %s", snapName, err)
+		}
+	})
+
+	snapshot, err = c.waitForVolumeSnapshotReady(ctx, snapName)
+	if err != nil {
+		return "", fmt.Errorf("VolumeSnapshot %q: %w", snapName, err)
+	}
+	readyDuration := time.Since(snapStart).Round(time.Second)
+
+	restoreSize := resource.MustParse(snapshotRoundTripSize)
+	if snapshot.Status.RestoreSize != nil {
+		restoreSize = *snapshot.Status.RestoreSize
+	}
+
+	restoreDv := c.newBlankDataVolume(restoreName, restoreSize.String())
+	restoreDv.Spec.Storage.StorageClassName = &sc
+	restoreDv.Spec.Source = &cdiv1.DataVolumeSource{
+		Snapshot: &cdiv1.DataVolumeSourceSnapshot{Namespace: c.namespace, Name: snapName},
+	}
+	restoreStart := time.Now()
+	if _, err := c.client.CreateDataVolume(ctx, c.namespace, restoreDv); err != nil {
+		return "", err
+	}
+	defer c.deleteUnlessFailed(ctx, roundTripFailed, func(ctx context.Context) {
+		c.deleteDataVolume(ctx, restoreName)
+	})
+
+	pvc, err := c.pollPVCBound(ctx, restoreName, c.checkupConfig.VMITimeout)
+	if err != nil {
+		return "", fmt.Errorf("restored PVC %q: %w", restoreName, err)
+	}
+	restoreDuration := time.Since(restoreStart).Round(time.Second)
+
+	if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; !ok || capacity.Cmp(restoreSize) < 0 {
+		return "", fmt.Errorf("restored PVC %q capacity %s is smaller than restoreSize %s", restoreName,
+			capacity.String(), restoreSize.String())
+	}
+
+	return fmt.Sprintf("snapshot ready in %s, restore bound in %s, restoreSize %s", readyDuration, restoreDuration,
+		restoreSize.String()), nil
+}
+
+func (c *Checkup) waitForVolumeSnapshotReady(ctx context.Context, name string) (*snapshotv1.VolumeSnapshot, error) {
+	var snapshot *snapshotv1.VolumeSnapshot
+	lastErr := ""
+	conditionFn := func(ctx context.Context) (bool, error) {
+		var err error
+		snapshot, err = c.client.GetVolumeSnapshot(ctx, c.namespace, name)
+		if err != nil {
+			return false, ignoreNotFound(err)
+		}
+		if snapshot.Status == nil {
+			return false, nil
+		}
+		if snapshot.Status.Error != nil && snapshot.Status.Error.Message != nil {
+			lastErr = *snapshot.Status.Error.Message
+		}
+		return snapshot.Status.ReadyToUse != nil && *snapshot.Status.ReadyToUse, nil
+	}
+
+	log.Printf("Waiting for VolumeSnapshot %q readyToUse", name)
+	if err := wait.PollImmediateWithContext(ctx, pollInterval, c.checkupConfig.VMITimeout, conditionFn); err != nil {
+		if lastErr != "" {
+			return nil, errors.New(lastErr)
+		}
+		return nil, errors.New("not ready to use")
+	}
+
+	return snapshot, nil
+}
+
+func (c *Checkup) deleteDataVolume(ctx context.Context, name string) {
+	if err := c.client.DeleteDataVolume(ctx, c.namespace, name); ignoreNotFound(err) != nil {
+		log.Printf("failed to delete DataVolume %q: %s", name, err)
+	}
+}
+
+func (c *Checkup) waitForDataVolumePhase(ctx context.Context, name string, phase cdiv1.DataVolumePhase) error {
+	conditionFn := func(ctx context.Context) (bool, error) {
+		dv, err := c.client.GetDataVolume(ctx, c.namespace, name)
+		if err != nil {
+			return false, ignoreNotFound(err)
+		}
+		if dv.Status.Phase == cdiv1.Failed && phase != cdiv1.Failed {
+			return false, fmt.Errorf("DataVolume failed: %s", dataVolumeConditionMessage(dv))
+		}
+		return dv.Status.Phase == phase, nil
+	}
+
+	log.Printf("Waiting for DataVolume %q phase %s", name, phase)
+	return wait.PollImmediateWithContext(ctx, pollInterval, c.checkupConfig.VMITimeout, conditionFn)
+}
+
+func dataVolumeConditionMessage(dv *cdiv1.DataVolume) string {
+	for _, cond := range dv.Status.Conditions {
+		if cond.Type == cdiv1.DataVolumeRunning && cond.Message != "" {
+			return cond.Message
+		}
+	}
+	return ""
+}
@@ -0,0 +1,232 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+const (
+	// storageObjectStuckAge is how long a PVC or DataVolume may stay in a transient state before it is reported
+	storageObjectStuckAge = 10 * time.Minute
+	noStorageClass        = "<none>"
+	// annSelectedNode is set by the scheduler on a WaitForFirstConsumer PVC once its consumer pod is scheduled
+	annSelectedNode = "volume.kubernetes.io/selected-node"
+
+	ErrUnhealthyStorageObjects = "there are unhealthy storage objects in VM namespaces"
+)
+
+// storageIssues maps storage class names to the issues found with their storage objects
+type storageIssues map[string][]string
+
+func (si storageIssues) add(storageClass *string, format string, args ...interface{}) {
+	sc := noStorageClass
+	if storageClass != nil && *storageClass != "" {
+		sc = *storageClass
+	}
+	si[sc] = append(si[sc], fmt.Sprintf(format, args...))
+}
+
+func (si storageIssues) String() string {
+	scs := make([]string, 0, len(si))
+	for sc := range si {
+		scs = append(scs, sc)
+	}
+	sort.Strings(scs)
+
+	var res string
+	for _, sc := range scs {
+		appendSep(&res, fmt.Sprintf("%s: %s", sc, strings.Join(si[sc], ", ")))
+	}
+	return res
+}
+
+// checkStorageHygiene scans the namespaces having VMs or DataVolumes for PVCs stuck Pending or Terminating,
+// DataVolumes stuck importing, cloning or failed, and Released or Failed PVs which were bound to VM disks.
+// Pending WaitForFirstConsumer PVCs are only reported once a node is selected for them.
+func (c *Checkup) checkStorageHygiene(ctx context.Context, scs *storagev1.StorageClassList, errStr *string) error {
+	log.Print("checkStorageHygiene")
+
+	vms, err := c.client.ListVirtualMachines(ctx, metav1.NamespaceAll)
+	if err != nil {
+		return err
+	}
+	dvs, err := c.client.ListDataVolumes(ctx, metav1.NamespaceAll)
+	if err != nil {
+		return err
+	}
+	pvcs, err := c.client.ListPersistentVolumeClaims(ctx, metav1.NamespaceAll, "")
+	if err != nil {
+		return err
+	}
+	pvs, err := c.client.ListPersistentVolumes(ctx)
+	if err != nil {
+		return err
+	}
+
+	vmNamespaces, vmDisks := vmNamespacesAndDisks(vms, dvs)
+	issues := storageIssues{}
+	now := time.Now()
+	issues.addPersistentVolumeClaims(pvcs, vmNamespaces, waitForFirstConsumerStorageClasses(scs), now)
+	issues.addDataVolumes(dvs, pvcs, now)
+	issues.addPersistentVolumes(pvs, vmDisks, now)
+
+	if len(issues) == 0 {
+		return nil
+	}
+	res := issues.String()
+	log.Print(res)
+	c.results.UnhealthyStorageObjects = res
+	appendSep(errStr, ErrUnhealthyStorageObjects)
+
+	return nil
+}
+
+func (si storageIssues) addPersistentVolumeClaims(pvcs *corev1.PersistentVolumeClaimList, vmNamespaces,
+	wffcScs map[string]bool, now time.Time) {
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		if !vmNamespaces[pvc.Namespace] {
+			continue
+		}
+		if pvc.DeletionTimestamp != nil {
+			if age := now.Sub(pvc.DeletionTimestamp.Time); len(pvc.Finalizers) > 0 && age > storageObjectStuckAge {
+				si.add(pvc.Spec.StorageClassName, "PVC %s/%s Terminating for %s (finalizers %s)", pvc.Namespace, pvc.Name,
+					formatAge(age), strings.Join(pvc.Finalizers, ", "))
+			}
+			continue
+		}
+		if pvc.Status.Phase != corev1.ClaimPending || !shouldBeBound(pvc, wffcScs) {
+			continue
+		}
+		if age := now.Sub(pvc.CreationTimestamp.Time); age > storageObjectStuckAge {
+			si.add(pvc.Spec.StorageClassName, "PVC %s/%s Pending for %s", pvc.Namespace, pvc.Name, formatAge(age))
+		}
+	}
+}
+
+// shouldBeBound returns whether the PVC is expected to be bound, which a WaitForFirstConsumer PVC is not until a node
+// is selected for its consumer
+func shouldBeBound(pvc *corev1.PersistentVolumeClaim, wffcScs map[string]bool) bool {
+	if pvc.Spec.StorageClassName == nil || !wffcScs[*pvc.Spec.StorageClassName] {
+		return true
+	}
+	_, selected := pvc.Annotations[annSelectedNode]
+	return selected
+}
+
+// waitForFirstConsumerStorageClasses returns the names of the WaitForFirstConsumer storage classes
+func waitForFirstConsumerStorageClasses(scs *storagev1.StorageClassList) map[string]bool {
+	wffcScs := map[string]bool{}
+	for i := range scs.Items {
+		sc := &scs.Items[i]
+		if sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+			wffcScs[sc.Name] = true
+		}
+	}
+	return wffcScs
+}
+
+func (si storageIssues) addDataVolumes(dvs *cdiv1.DataVolumeList, pvcs *corev1.PersistentVolumeClaimList, now time.Time) {
+	pvcScs := map[string]*string{}
+	for i := range pvcs.Items {
+		pvcScs[pvcs.Items[i].Namespace+"/"+pvcs.Items[i].Name] = pvcs.Items[i].Spec.StorageClassName
+	}
+
+	for i := range dvs.Items {
+		dv := &dvs.Items[i]
+		age := now.Sub(dv.CreationTimestamp.Time)
+		inProgress := dv.Status.Phase == cdiv1.ImportInProgress || dv.Status.Phase == cdiv1.CloneInProgress
+		if !(inProgress && age > storageObjectStuckAge) && dv.Status.Phase != cdiv1.Failed {
+			continue
+		}
+		sc, ok := pvcScs[dv.Namespace+"/"+dv.Name]
+		if !ok {
+			sc = dataVolumeStorageClass(dv)
+		}
+		si.add(sc, "DataVolume %s/%s %s for %s", dv.Namespace, dv.Name, dv.Status.Phase, formatAge(age))
+	}
+}
+
+func (si storageIssues) addPersistentVolumes(pvs *corev1.PersistentVolumeList, vmDisks map[string]bool, now time.Time) {
+	for i := range pvs.Items {
+		pv := &pvs.Items[i]
+		if pv.Status.Phase != corev1.VolumeReleased && pv.Status.Phase != corev1.VolumeFailed {
+			continue
+		}
+		ref := pv.Spec.ClaimRef
+		if ref == nil || !vmDisks[ref.Namespace+"/"+ref.Name] {
+			continue
+		}
+		si.add(&pv.Spec.StorageClassName, "PV %s %s, was bound to %s/%s, for %s", pv.Name, pv.Status.Phase,
+			ref.Namespace, ref.Name, formatAge(now.Sub(pv.CreationTimestamp.Time)))
+	}
+}
+
+// vmNamespacesAndDisks returns the namespaces having VMs or DataVolumes, and the PVCs backing VM disks by namespaced name
+func vmNamespacesAndDisks(vms *kvcorev1.VirtualMachineList, dvs *cdiv1.DataVolumeList) (namespaces, disks map[string]bool) {
+	namespaces = map[string]bool{}
+	disks = map[string]bool{}
+
+	for i := range vms.Items {
+		vm := &vms.Items[i]
+		namespaces[vm.Namespace] = true
+		if vm.Spec.Template == nil {
+			continue
+		}
+		for _, vol := range vm.Spec.Template.Spec.Volumes {
+			if vol.DataVolume != nil {
+				disks[vm.Namespace+"/"+vol.DataVolume.Name] = true
+			} else if vol.PersistentVolumeClaim != nil {
+				disks[vm.Namespace+"/"+vol.PersistentVolumeClaim.ClaimName] = true
+			}
+		}
+	}
+
+	// A DataVolume PVC has the DataVolume name, and is a VM disk even if the VM is gone
+	for i := range dvs.Items {
+		namespaces[dvs.Items[i].Namespace] = true
+		disks[dvs.Items[i].Namespace+"/"+dvs.Items[i].Name] = true
+	}
+
+	return namespaces, disks
+}
+
+func dataVolumeStorageClass(dv *cdiv1.DataVolume) *string {
+	if dv.Spec.Storage != nil {
+		return dv.Spec.Storage.StorageClassName
+	}
+	if dv.Spec.PVC != nil {
+		return dv.Spec.PVC.StorageClassName
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+)
+
+const (
+	MessageSkipNoTargetStorageClass = "Skip check - no targetStorageClass"
+	ErrTargetStorageClassNotFound   = "targetStorageClass %q does not exist"
+	ErrStorageMigrationRejected     = "VM volumes update for the storage migration was rejected"
+
+	updateVolumesStrategyMigration = "Migration"
+)
+
+type jsonPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// checkVMIStorageMigration moves the running VM disk to the target storage class using the Migration
+// updateVolumesStrategy, and verifies the VMI keeps running on the migrated volume
+func (c *Checkup) checkVMIStorageMigration(ctx context.Context, scs *storagev1.StorageClassList, errStr *string) error {
+	log.Print("checkVMIStorageMigration")
+
+	if skip := c.storageMigrationSkipReason(); skip != "" {
+		log.Print(skip)
+		c.results.VMStorageMigration = skip
+		return nil
+	}
+
+	targetSc := c.checkupConfig.TargetStorageClass
+	if !storageClassExists(scs, targetSc) {
+		res := fmt.Sprintf(ErrTargetStorageClassNotFound, targetSc)
+		log.Print(res)
+		c.results.VMStorageMigration = res
+		appendSep(errStr, res)
+		return nil
+	}
+
+	nodes, err := c.client.ListNodes(ctx)
+	if err != nil {
+		return err
+	}
+	if len(nodes.Items) == 1 {
+		log.Print(MessageSkipSingleNode)
+		c.results.VMStorageMigration = MessageSkipSingleNode
+		return nil
+	}
+
+	vmName := c.vmUnderTest.Name
+	vmi, err := c.client.GetVirtualMachineInstance(ctx, c.namespace, vmName)
+	if err != nil {
+		return err
+	}
+	var prevMigrationUID types.UID
+	if vmi.Status.MigrationState != nil {
+		prevMigrationUID = vmi.Status.MigrationState.MigrationUID
+	}
+
+	srcDvName := getVMDvName(vmName)
+	dv, srcSc, err := c.createStorageMigrationDataVolume(ctx, srcDvName, getStorageMigrationDvName(vmName), targetSc)
+	if err != nil {
+		return err
+	}
+
+	patch, err := storageMigrationPatch(c.vmUnderTest, srcDvName, dv)
+	if err != nil {
+		return err
+	}
+	log.Printf("Migrating VM %q volume from storage class %q to %q", vmName, srcSc, targetSc)
+	start := time.Now()
+	if err := c.client.PatchVirtualMachine(ctx, c.namespace, vmName, patch); err != nil {
+		res := fmt.Sprintf("%s: %v", ErrStorageMigrationRejected, err)
+		log.Print(res)
+		appendSep(&c.results.VMStorageMigration, res)
+		appendSep(errStr, ErrStorageMigrationRejected)
+		return nil
+	}
+
+	var ms *kvcorev1.VirtualMachineInstanceMigrationState
+	var migrationErrs string
+	if err := c.waitForVMIStatus(ctx, vmName, "storage migration completed", &c.results.VMStorageMigration, &migrationErrs,
+		func(vmi *kvcorev1.VirtualMachineInstance) (done bool, err error) {
+			if vmi.Status.MigrationState == nil || vmi.Status.MigrationState.MigrationUID == prevMigrationUID {
+				return false, nil
+			}
+			ms = vmi.Status.MigrationState
+			if ms.Failed {
+				return false, errors.New("storage migration failed")
+			}
+			return ms.Completed && hasDataVolume(vmi, dv.Name), nil
+		}); err != nil {
+		return err
+	}
+	if migrationErrs != "" {
+		appendSep(errStr, migrationErrs)
+		return nil
+	}
+
+	vmi, err = c.client.GetVirtualMachineInstance(ctx, c.namespace, vmName)
+	if err != nil {
+		return err
+	}
+	if vmi.Status.Phase != kvcorev1.Running {
+		res := fmt.Sprintf("VMI %q is not running after storage migration, phase %q", vmName, vmi.Status.Phase)
+		log.Print(res)
+		appendSep(&c.results.VMStorageMigration, res)
+		appendSep(errStr, res)
+		return nil
+	}
+
+	res := fmt.Sprintf("volume migrated from storage class %q to %q in %s, VMI %q still running",
+		srcSc, targetSc, migrationDuration(ms, time.Since(start)), vmName)
+	appendSep(&res, "method: "+storageMigrationMethod(ms))
+	log.Print(res)
+	appendSep(&c.results.VMStorageMigration, res)
+
+	return nil
+}
+
+func (c *Checkup) storageMigrationSkipReason() string {
+	if c.vmUnderTest == nil {
+		return MessageSkipNoVMI
+	}
+	if c.checkupConfig.TargetStorageClass == "" {
+		return MessageSkipNoTargetStorageClass
+	}
+	if !c.isFeatureGateEnabled(FeatureGateVolumeMigration) {
+		return fmt.Sprintf(MessageSkipFeatureGateDisabled, FeatureGateVolumeMigration)
+	}
+	if !c.isFeatureGateEnabled(FeatureGateVolumesUpdateStrategy) {
+		return fmt.Sprintf(MessageSkipFeatureGateDisabled, FeatureGateVolumesUpdateStrategy)
+	}
+	// Volume updates of a running VM are only rolled out to its VMI with the LiveUpdate strategy
+	if c.kubeVirt != nil && c.vmRolloutStrategy != VMRolloutStrategyLiveUpdate {
+		return fmt.Sprintf(MessageSkipNoLiveUpdateRollout, stringOrUnset(c.vmRolloutStrategy))
+	}
+	return ""
+}
+
+// createStorageMigrationDataVolume creates the migration target DataVolume, and returns it with the source storage class
+func (c *Checkup) createStorageMigrationDataVolume(ctx context.Context, srcDvName, name, storageClass string) (
+	dv *cdiv1.DataVolume, srcSc string, err error) {
+	srcPvc, err := c.client.GetPersistentVolumeClaim(ctx, c.namespace, srcDvName)
+	if err != nil {
+		return nil, "", err
+	}
+	if srcPvc.Spec.StorageClassName != nil {
+		srcSc = *srcPvc.Spec.StorageClassName
+	}
+
+	dv = c.newStorageMigrationDataVolume(name, storageClass, srcPvc)
+	if _, err := c.client.CreateDataVolume(ctx, c.namespace, dv); err != nil {
+		return nil, "", err
+	}
+
+	return dv, srcSc, nil
+}
+
+// newStorageMigrationDataVolume returns a blank DataVolume on the target storage class, at least as large as the source PVC
+func (c *Checkup) newStorageMigrationDataVolume(name, storageClass string, srcPvc *corev1.PersistentVolumeClaim) *cdiv1.DataVolume {
+	size, ok := srcPvc.Status.Capacity[corev1.ResourceStorage]
+	if !ok {
+		size = srcPvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	}
+
+	return &cdiv1.DataVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       c.checkupConfig.PodName,
+				UID:        types.UID(c.checkupConfig.PodUID),
+			}},
+		},
+		Spec: cdiv1.DataVolumeSpec{
+			Source: &cdiv1.DataVolumeSource{Blank: &cdiv1.DataVolumeBlankImage{}},
+			Storage: &cdiv1.StorageSpec{
+				StorageClassName: &storageClass,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+				},
+			},
+		},
+	}
+}
+
+// storageMigrationPatch returns the VM JSON patch replacing the source DataVolume with the target one. The vendored
+// KubeVirt API predates updateVolumesStrategy, so the VM cannot simply be updated.
+func storageMigrationPatch(vm *kvcorev1.VirtualMachine, srcDvName string, dv *cdiv1.DataVolume) ([]byte, error) {
+	ops := []jsonPatchOp{{Op: "add", Path: "/spec/updateVolumesStrategy", Value: updateVolumesStrategyMigration}}
+
+	for i := range vm.Spec.DataVolumeTemplates {
+		if vm.Spec.DataVolumeTemplates[i].Name == srcDvName {
+			// Otherwise the VM controller recreates the source DataVolume from its template
+			ops = append(ops, jsonPatchOp{
+				Op:    "replace",
+				Path:  fmt.Sprintf("/spec/dataVolumeTemplates/%d", i),
+				Value: kvcorev1.DataVolumeTemplateSpec{ObjectMeta: metav1.ObjectMeta{Name: dv.Name}, Spec: dv.Spec},
+			})
+		}
+	}
+
+	found := false
+	for i, vol := range vm.Spec.Template.Spec.Volumes {
+		if vol.DataVolume != nil && vol.DataVolume.Name == srcDvName {
+			ops = append(ops, jsonPatchOp{
+				Op:    "replace",
+				Path:  fmt.Sprintf("/spec/template/spec/volumes/%d/dataVolume/name", i),
+				Value: dv.Name,
+			})
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("VM %q has no volume of DataVolume %q", vm.Name, srcDvName)
+	}
+
+	return json.Marshal(ops)
+}
+
+func hasDataVolume(vmi *kvcorev1.VirtualMachineInstance, dvName string) bool {
+	for _, vol := range vmi.Spec.Volumes {
+		if vol.DataVolume != nil && vol.DataVolume.Name == dvName {
+			return true
+		}
+	}
+	return false
+}
+
+func migrationDuration(ms *kvcorev1.VirtualMachineInstanceMigrationState, measured time.Duration) time.Duration {
+	if ms != nil && ms.StartTimestamp != nil && ms.EndTimestamp != nil {
+		return ms.EndTimestamp.Sub(ms.StartTimestamp.Time)
+	}
+	return measured.Round(time.Second)
+}
+
+func storageMigrationMethod(ms *kvcorev1.VirtualMachineInstanceMigrationState) string {
+	mode := "unknown"
+	if ms != nil && ms.Mode != "" {
+		mode = string(ms.Mode)
+	}
+	return fmt.Sprintf("updateVolumesStrategy %s, live migration mode %s", updateVolumesStrategyMigration, mode)
+}
+
+func getStorageMigrationDvName(vmName string) string {
+	return fmt.Sprintf("%s-migrated", getVMDvName(vmName))
+}
+
+func storageClassExists(scs *storagev1.StorageClassList, name string) bool {
+	for i := range scs.Items {
+		if scs.Items[i].Name == name {
+			return true
+		}
+	}
+	return false
+}
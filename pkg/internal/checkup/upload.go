@@ -0,0 +1,325 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	cdiuploadv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/upload/v1beta1"
+
+	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/imageserver"
+)
+
+const (
+	uploadDvName           = "checkup-upload"
+	uploadVMName           = "checkup-upload-vm"
+	uploadDvSize           = "100Mi"
+	uploadProxyPath        = "/v1beta1/upload"
+	uploadProxyService     = "cdi-uploadproxy"
+	uploadProxyLabel       = "cdi.kubevirt.io=" + uploadProxyService
+	uploadProxyCABundle    = "cdi-uploadproxy-signer-bundle"
+	uploadProxyCABundleKey = "ca-bundle.crt"
+	uploadHTTPTimeout      = 2 * time.Minute
+
+	ErrUploadFailed           = "upload through cdi-uploadproxy failed"
+	ErrUploadProxyCertificate = "upload proxy certificate cannot be verified"
+)
+
+// checkUploadProxy uploads a small generated disk image through the in-cluster cdi-uploadproxy service, the way
+// virtctl image-upload does, and starts a VM on the resulting PVC. The external route published in the CDIConfig is
+// only reported, as it is served with the ingress certificate rather than the one CDI signs.
+func (c *Checkup) checkUploadProxy(ctx context.Context, errStr *string) error {
+	log.Print("checkUploadProxy")
+
+	if c.defaultStorageClass == "" && c.checkupConfig.StorageClass == "" {
+		log.Print(MessageSkipNoDefaultStorageClass)
+		c.results.CDIUpload = MessageSkipNoDefaultStorageClass
+		return nil
+	}
+
+	if !c.pvcBound {
+		log.Print(MessageSkipPVCNotBound)
+		c.results.CDIUpload = MessageSkipPVCNotBound
+		return nil
+	}
+
+	proxy, err := c.getUploadProxyService(ctx)
+	if err != nil {
+		c.uploadFailed(err, errStr)
+		return nil
+	}
+	res := fmt.Sprintf("Upload proxy: https://%s", uploadProxyServiceName(proxy))
+	log.Print(res)
+	appendSep(&c.results.CDIUpload, res)
+	if route := c.getUploadProxyRoute(ctx); route != "" {
+		res := fmt.Sprintf("Upload proxy route: %s", route)
+		log.Print(res)
+		appendSep(&c.results.CDIUpload, res)
+	}
+
+	checkFailed := failedSince(errStr)
+	dv := c.newBlankDataVolume(uploadDvName, uploadDvSize)
+	dv.Spec.Source = &cdiv1.DataVolumeSource{Upload: &cdiv1.DataVolumeSourceUpload{}}
+	if _, err := c.client.CreateDataVolume(ctx, c.namespace, dv); err != nil {
+		return err
+	}
+	defer c.deleteUnlessFailed(ctx, checkFailed, func(ctx context.Context) {
+		c.deleteDataVolume(ctx, uploadDvName)
+	})
+
+	if err := c.waitForDataVolumePhase(ctx, uploadDvName, cdiv1.UploadReady); err != nil {
+		c.uploadFailed(fmt.Errorf("DataVolume %q not ready for upload: %w", uploadDvName, err), errStr)
+		return nil
+	}
+
+	tokenRequest, err := c.client.CreateUploadTokenRequest(ctx, c.namespace, &cdiuploadv1.UploadTokenRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: uploadDvName},
+		Spec:       cdiuploadv1.UploadTokenRequestSpec{PvcName: uploadDvName},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to request upload token: %w", err)
+	}
+
+	certErr, err := uploadImage(ctx, proxy, tokenRequest.Status.Token, c.getUploadProxyCertPool(ctx, proxy.Namespace),
+		c.checkupConfig.UploadProxyInsecure, imageserver.NewRawImage(imageserver.ImageSize))
+	if certErr != nil {
+		res := fmt.Sprintf("%s: %v", ErrUploadProxyCertificate, certErr)
+		log.Print(res)
+		appendSep(&c.results.CDIUpload, res)
+		appendSep(errStr, ErrUploadProxyCertificate)
+		if !c.checkupConfig.UploadProxyInsecure {
+			return nil
+		}
+	}
+	if err != nil {
+		c.uploadFailed(err, errStr)
+		return nil
+	}
+
+	if err := c.waitForDataVolumePhase(ctx, uploadDvName, cdiv1.Succeeded); err != nil {
+		c.uploadFailed(fmt.Errorf("DataVolume %q: %w", uploadDvName, err), errStr)
+		return nil
+	}
+	res = fmt.Sprintf("DataVolume %q upload succeeded", uploadDvName)
+	log.Print(res)
+	appendSep(&c.results.CDIUpload, res)
+
+	log.Printf("Creating VM %q", uploadVMName)
+	if _, err := c.client.CreateVirtualMachine(ctx, c.namespace, newVMWithPVC(uploadVMName, uploadDvName, c.checkupConfig)); err != nil {
+		return fmt.Errorf("failed to create VM: %w", err)
+	}
+	defer c.deleteUnlessFailed(ctx, checkFailed, func(ctx context.Context) {
+		if err := c.client.DeleteVirtualMachine(ctx, c.namespace, uploadVMName); ignoreNotFound(err) != nil {
+			log.Printf("failed to delete VM %q: %s", uploadVMName, err)
+		}
+	})
+
+	return c.waitForVMIReady(ctx, uploadVMName, &c.results.CDIUpload, errStr)
+}
+
+func (c *Checkup) uploadFailed(err error, errStr *string) {
+	res := fmt.Sprintf("%s: %v", ErrUploadFailed, err)
+	log.Print(res)
+	appendSep(&c.results.CDIUpload, res)
+	appendSep(errStr, ErrUploadFailed)
+}
+
+// getUploadProxyRoute returns the external upload proxy URL published in the CDIConfig, if any
+func (c *Checkup) getUploadProxyRoute(ctx context.Context) string {
+	cdiConfig, err := c.client.GetCDIConfig(ctx)
+	if err != nil {
+		log.Printf("failed to get CDIConfig: %s", err)
+		return ""
+	}
+	if proxyURL := cdiConfig.Status.UploadProxyURL; proxyURL != nil && *proxyURL != "" {
+		if !strings.Contains(*proxyURL, "://") {
+			return "https://" + *proxyURL
+		}
+		return *proxyURL
+	}
+	return ""
+}
+
+// getCDINamespace returns the namespace CDI is deployed in. The CDI CR is cluster scoped and does not record it, so
+// it is the namespace of the upload proxy service the CDI CR owns.
+func (c *Checkup) getCDINamespace(ctx context.Context) (string, error) {
+	if c.cdiNamespace == "" {
+		proxy, err := c.getUploadProxyService(ctx)
+		if err != nil {
+			return "", err
+		}
+		c.cdiNamespace = proxy.Namespace
+	}
+	return c.cdiNamespace, nil
+}
+
+// getUploadProxyService returns the cdi-uploadproxy service owned by the CDI CR, or the only one found when none
+// carries the owner reference
+func (c *Checkup) getUploadProxyService(ctx context.Context) (*corev1.Service, error) {
+	cdis, err := c.client.ListCDIs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(cdis.Items) == 0 {
+		return nil, errors.New("no CDI CR found")
+	}
+	cdi := &cdis.Items[0]
+
+	svcs, err := c.client.ListServices(ctx, metav1.NamespaceAll, uploadProxyLabel)
+	if err != nil {
+		return nil, err
+	}
+	for i := range svcs.Items {
+		for _, ref := range svcs.Items[i].OwnerReferences {
+			if ref.UID == cdi.UID {
+				return &svcs.Items[i], nil
+			}
+		}
+	}
+	if len(svcs.Items) == 1 {
+		return &svcs.Items[0], nil
+	}
+
+	return nil, fmt.Errorf("no %s service of CDI CR %q found", uploadProxyService, cdi.Name)
+}
+
+// uploadProxyServiceName returns the in-cluster name of the upload proxy, which its certificate is issued for
+func uploadProxyServiceName(proxy *corev1.Service) string {
+	return fmt.Sprintf("%s.%s.svc", proxy.Name, proxy.Namespace)
+}
+
+// uploadProxyAddress returns the cluster IP and port to reach the upload proxy service at, so the upload does not
+// depend on the cluster DNS
+func uploadProxyAddress(proxy *corev1.Service) string {
+	port := int32(443)
+	if len(proxy.Spec.Ports) > 0 {
+		port = proxy.Spec.Ports[0].Port
+	}
+	return "https://" + net.JoinHostPort(proxy.Spec.ClusterIP, strconv.Itoa(int(port)))
+}
+
+// getUploadProxyCertPool returns the system roots along with the CA CDI signs the upload proxy certificate with
+func (c *Checkup) getUploadProxyCertPool(ctx context.Context, cdiNamespace string) *x509.CertPool {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	cm, err := c.client.GetConfigMap(ctx, cdiNamespace, uploadProxyCABundle)
+	if err != nil {
+		log.Printf("failed to get upload proxy CA bundle: %s", err)
+		return pool
+	}
+	if !pool.AppendCertsFromPEM([]byte(cm.Data[uploadProxyCABundleKey])) {
+		log.Printf("no certificates found in ConfigMap %q", uploadProxyCABundle)
+	}
+
+	return pool
+}
+
+// uploadImage posts the image to the upload proxy service, returning the certificate error when the proxy certificate
+// cannot be verified for the service name. The upload is then retried without verification only when insecure is set,
+// so the rest of the upload path is still exercised.
+func uploadImage(ctx context.Context, proxy *corev1.Service, token string, pool *x509.CertPool, insecure bool,
+	image []byte) (certErr, err error) {
+	proxyURL := uploadProxyAddress(proxy)
+	tlsConfig := &tls.Config{RootCAs: pool, ServerName: uploadProxyServiceName(proxy), MinVersion: tls.VersionTLS12}
+	err = postImage(ctx, proxyURL, token, tlsConfig, image)
+	if certErr = certificateError(err); certErr == nil || !insecure {
+		return certErr, err
+	}
+
+	//nolint:gosec // verification is skipped only when explicitly requested, the certificate problem is still reported
+	return certErr, postImage(ctx, proxyURL, token, &tls.Config{InsecureSkipVerify: true}, image)
+}
+
+// certificateError returns the x509 verification error wrapped by err, if any
+func certificateError(err error) error {
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	if errors.As(err, &unknownAuthorityErr) {
+		return unknownAuthorityErr
+	}
+	var certInvalidErr x509.CertificateInvalidError
+	if errors.As(err, &certInvalidErr) {
+		return certInvalidErr
+	}
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &hostnameErr) {
+		return hostnameErr
+	}
+	return nil
+}
+
+func postImage(ctx context.Context, proxyURL, token string, tlsConfig *tls.Config, image []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, proxyURL+uploadProxyPath, bytes.NewReader(image))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		Timeout:   uploadHTTPTimeout,
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		const maxBodyLen = 512
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodyLen))
+		return fmt.Errorf("upload proxy returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+func (c *Checkup) waitForVMIReady(ctx context.Context, vmName string, result, errStr *string) error {
+	return c.waitForVMIStatus(ctx, vmName, "ready", result, errStr,
+		func(vmi *kvcorev1.VirtualMachineInstance) (done bool, err error) {
+			for i := range vmi.Status.Conditions {
+				condition := vmi.Status.Conditions[i]
+				if condition.Type == kvcorev1.VirtualMachineInstanceReady && condition.Status == corev1.ConditionTrue {
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+}
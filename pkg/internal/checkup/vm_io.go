@@ -0,0 +1,212 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/checkup/vmi"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	ioBaselineVMName          = "checkup-io-baseline-vm"
+	ioBaselineReaderPodName   = "checkup-io-baseline-reader"
+	ioBaselineCloudInitVolume = "cloudinit"
+	// ioBaselineDiskSerial identifies the VM blank disk the workload runs on, as /dev/disk/by-id/virtio-<serial>
+	ioBaselineDiskSerial = "io-baseline"
+	// ioBaselineOutputSize bounds the workload output the guest writes at the start of the blank disk
+	ioBaselineOutputSize = 4096
+
+	ErrVMIOBaselineFailed = "VM I/O baseline failed"
+)
+
+// ioRateUnits are the multipliers of the dd throughput units
+var ioRateUnits = map[string]float64{
+	"B/s": 1, "kB/s": 1e3, "MB/s": 1e6, "GB/s": 1e9, "TB/s": 1e12,
+	"KiB/s": 1 << 10, "MiB/s": 1 << 20, "GiB/s": 1 << 30, "TiB/s": 1 << 40,
+}
+
+// checkVMIOBaseline runs the pod I/O baseline workload in a VM booted from the golden image, on a blank disk of the
+// same storage class, and reports the VM-to-pod throughput ratio of each operation. As the checkup cannot run
+// commands in the guest, the cloud-init user data runs the workload, writes its output at the start of the blank disk
+// and powers the VM off, and a pod reads the output back from the disk.
+func (c *Checkup) checkVMIOBaseline(ctx context.Context, errStr *string) error {
+	log.Print("checkVMIOBaseline")
+
+	// the pod baseline was skipped or failed, and is reported as such
+	if len(c.podIOMetrics) == 0 {
+		return nil
+	}
+
+	if c.goldenImagePvc == nil && c.goldenImageSnap == nil {
+		log.Print(MessageSkipNoGoldenImage)
+		appendSep(&c.results.PodIOBaseline, "VM I/O baseline: "+MessageSkipNoGoldenImage)
+		return nil
+	}
+
+	checkupPod, err := c.client.GetPod(ctx, c.namespace, c.checkupConfig.PodName)
+	if err != nil {
+		return fmt.Errorf("failed to get checkup pod: %w", err)
+	}
+
+	checkFailed := failedSince(errStr)
+	log.Printf("Creating VM %q", ioBaselineVMName)
+	if _, err := c.client.CreateVirtualMachine(ctx, c.namespace, c.newIOBaselineVM()); err != nil {
+		return fmt.Errorf("failed to create VM: %w", err)
+	}
+	defer c.deleteUnlessFailed(ctx, checkFailed, func(ctx context.Context) {
+		if err := c.client.DeleteVirtualMachine(ctx, c.namespace, ioBaselineVMName); ignoreNotFound(err) != nil {
+			log.Printf("failed to delete VM %q: %s", ioBaselineVMName, err)
+		}
+	})
+
+	if err := c.waitForVMIStatus(ctx, ioBaselineVMName, "powered off after the I/O workload", &c.results.PodIOBaseline, errStr,
+		func(vmi *kvcorev1.VirtualMachineInstance) (done bool, err error) {
+			if vmi.Status.Phase == kvcorev1.Failed {
+				return false, errors.New("VMI failed")
+			}
+			return vmi.Status.Phase == kvcorev1.Succeeded, nil
+		}); err != nil {
+		return err
+	}
+	if checkFailed() {
+		return nil
+	}
+
+	pvc, err := c.client.GetPersistentVolumeClaim(ctx, c.namespace, getIOBaselineBlankDvName())
+	if err != nil {
+		return err
+	}
+
+	pod := c.newIOBaselinePod(ioBaselineReaderPodName, checkupPod, pvc, "disk.img", ioBaselineOutputScript)
+	if _, err := c.client.CreatePod(ctx, c.namespace, pod); err != nil {
+		return fmt.Errorf("failed to create I/O baseline reader pod: %w", err)
+	}
+	defer c.deleteUnlessFailed(ctx, checkFailed, func(ctx context.Context) {
+		if err := c.client.DeletePod(ctx, c.namespace, ioBaselineReaderPodName); ignoreNotFound(err) != nil {
+			log.Printf("failed to delete pod %q: %s", ioBaselineReaderPodName, err)
+		}
+	})
+
+	if err := c.waitForPodCompletion(ctx, ioBaselineReaderPodName); err != nil {
+		c.vmIOBaselineFailed(fmt.Errorf("reader pod: %w", err), errStr)
+		return nil
+	}
+
+	logs, err := c.client.GetPodLogs(ctx, c.namespace, ioBaselineReaderPodName, "", 0)
+	if err != nil {
+		return fmt.Errorf("failed to get I/O baseline reader pod logs: %w", err)
+	}
+	vmMetrics := parseIOBaselineLogs(logs)
+	if len(vmMetrics) == 0 {
+		c.vmIOBaselineFailed(errors.New("no workload output on the VM disk"), errStr)
+		return nil
+	}
+
+	res := fmt.Sprintf("VM I/O baseline on storage class %q: %s\nVM/pod throughput ratio: %s",
+		stringValue(pvc.Spec.StorageClassName), formatIOMetrics(vmMetrics), ioRatios(c.podIOMetrics, vmMetrics))
+	log.Print(res)
+	appendSep(&c.results.PodIOBaseline, res)
+
+	return nil
+}
+
+func (c *Checkup) vmIOBaselineFailed(err error, errStr *string) {
+	res := fmt.Sprintf("%s: %v", ErrVMIOBaselineFailed, err)
+	log.Print(res)
+	appendSep(&c.results.PodIOBaseline, res)
+	appendSep(errStr, ErrVMIOBaselineFailed)
+}
+
+// newIOBaselineVM returns a VM under test running the I/O workload once from its cloud-init user data. Its disks are
+// listed, so the blank one gets the serial the workload finds it by.
+func (c *Checkup) newIOBaselineVM() *kvcorev1.VirtualMachine {
+	vm := newVMUnderTest(ioBaselineVMName, c.goldenImagePvc, c.goldenImageSnap, c.checkupConfig, true)
+	for _, option := range []vmi.Option{
+		vmi.WithCloudInitNoCloud(ioBaselineCloudInitVolume, ioBaselineUserData()),
+		vmi.WithVirtioDisk(getVMDvName(ioBaselineVMName), ""),
+		vmi.WithVirtioDisk(getIOBaselineBlankDvName(), ioBaselineDiskSerial),
+		vmi.WithVirtioDisk(ioBaselineCloudInitVolume, ""),
+		vmi.WithRunStrategy(kvcorev1.RunStrategyRerunOnFailure),
+	} {
+		option(vm)
+	}
+	return vm
+}
+
+func getIOBaselineBlankDvName() string {
+	return getVMDvName(ioBaselineVMName) + "-blank"
+}
+
+// ioBaselineUserData runs the workload on the blank disk, writes its output at the start of the disk and powers off
+func ioBaselineUserData() string {
+	disk := "/dev/disk/by-id/virtio-" + ioBaselineDiskSerial
+	return "#!/bin/sh\n" +
+		"(" + ioWorkloadScript(disk) + ") > /tmp/io-baseline\n" +
+		"dd if=/tmp/io-baseline of=" + disk + " conv=fsync\n" +
+		"poweroff\n"
+}
+
+// ioBaselineOutputScript prints the workload output the guest wrote at the start of the disk, without the zeroes
+// following it
+func ioBaselineOutputScript(target string) string {
+	return fmt.Sprintf("head -c %d %s | tr -d '\\000'", ioBaselineOutputSize, target)
+}
+
+// ioRatios returns the VM-to-pod throughput ratio of each operation, e.g. "write 0.50, read 0.75"
+func ioRatios(podMetrics, vmMetrics []ioMetric) string {
+	var ratios []string
+	for _, vmMetric := range vmMetrics {
+		for _, podMetric := range podMetrics {
+			if podMetric.op != vmMetric.op {
+				continue
+			}
+			vmRate, vmParsed := parseIORate(vmMetric.rate)
+			podRate, podParsed := parseIORate(podMetric.rate)
+			if !vmParsed || !podParsed || podRate == 0 {
+				ratios = append(ratios, vmMetric.op+" unknown")
+				continue
+			}
+			ratios = append(ratios, fmt.Sprintf("%s %.2f", vmMetric.op, vmRate/podRate))
+		}
+	}
+	return strings.Join(ratios, ", ")
+}
+
+// parseIORate returns the bytes per second of a dd throughput, e.g. "224 MB/s"
+func parseIORate(rate string) (float64, bool) {
+	value, unit, found := strings.Cut(rate, " ")
+	multiplier, known := ioRateUnits[unit]
+	if !found || !known {
+		return 0, false
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return parsed * multiplier, true
+}
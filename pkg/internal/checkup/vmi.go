@@ -76,3 +76,13 @@ func newVMUnderTest(name string, pvc *corev1.PersistentVolumeClaim, snap *snapsh
 func getVMDvName(vmName string) string {
 	return fmt.Sprintf("%s-dv", vmName)
 }
+
+// newVMWithPVC returns a VM using an existing PVC as its only disk
+func newVMWithPVC(name, claimName string, checkupConfig config.Config) *kvcorev1.VirtualMachine {
+	return vmi.NewVM(name,
+		vmi.WithPersistentVolumeClaim(claimName, claimName),
+		vmi.WithMemory(guestMemory),
+		vmi.WithTerminationGracePeriodSeconds(terminationGracePeriodSeconds),
+		vmi.WithOwnerReference(checkupConfig.PodName, checkupConfig.PodUID),
+	)
+}
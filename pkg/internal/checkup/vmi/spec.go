@@ -123,6 +123,51 @@ func WithDataVolumeStorageClass(storageClass string) DataVolumeOption {
 	}
 }
 
+func WithPersistentVolumeClaim(volumeName, claimName string) Option {
+	return func(vm *kvcorev1.VirtualMachine) {
+		vm.Spec.Template.Spec.Volumes = append(vm.Spec.Template.Spec.Volumes, kvcorev1.Volume{
+			Name: volumeName,
+			VolumeSource: kvcorev1.VolumeSource{
+				PersistentVolumeClaim: &kvcorev1.PersistentVolumeClaimVolumeSource{
+					PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: claimName,
+					},
+				},
+			},
+		})
+	}
+}
+
+// WithCloudInitNoCloud adds a cloud-init NoCloud volume with the given user data
+func WithCloudInitNoCloud(volumeName, userData string) Option {
+	return func(vm *kvcorev1.VirtualMachine) {
+		vm.Spec.Template.Spec.Volumes = append(vm.Spec.Template.Spec.Volumes, kvcorev1.Volume{
+			Name: volumeName,
+			VolumeSource: kvcorev1.VolumeSource{
+				CloudInitNoCloud: &kvcorev1.CloudInitNoCloudSource{UserData: userData},
+			},
+		})
+	}
+}
+
+// WithVirtioDisk adds a virtio disk for the volume, with an optional serial the guest finds it by under
+// /dev/disk/by-id. Disks are attached in the order they are added.
+func WithVirtioDisk(volumeName, serial string) Option {
+	return func(vm *kvcorev1.VirtualMachine) {
+		vm.Spec.Template.Spec.Domain.Devices.Disks = append(vm.Spec.Template.Spec.Domain.Devices.Disks, kvcorev1.Disk{
+			Name:       volumeName,
+			Serial:     serial,
+			DiskDevice: kvcorev1.DiskDevice{Disk: &kvcorev1.DiskTarget{Bus: kvcorev1.DiskBusVirtio}},
+		})
+	}
+}
+
+func WithRunStrategy(runStrategy kvcorev1.VirtualMachineRunStrategy) Option {
+	return func(vm *kvcorev1.VirtualMachine) {
+		vm.Spec.RunStrategy = Pointer(runStrategy)
+	}
+}
+
 func WithMemory(guestMemory string) Option {
 	return func(vm *kvcorev1.VirtualMachine) {
 		guestMemoryQuantity := resource.MustParse(guestMemory)
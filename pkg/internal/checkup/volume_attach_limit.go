@@ -0,0 +1,253 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+)
+
+var hotplugAttachmentPodSelector = kvcorev1.AppLabel + "=" + string(kvcorev1.HotplugAttachment)
+
+// nodeDriverVolumes is a set of PV names per node and CSI driver
+type nodeDriverVolumes map[string]map[string]map[string]bool
+
+func (v nodeDriverVolumes) add(node, driver, pv string) {
+	if v[node] == nil {
+		v[node] = map[string]map[string]bool{}
+	}
+	if v[node][driver] == nil {
+		v[node][driver] = map[string]bool{}
+	}
+	v[node][driver][pv] = true
+}
+
+// checkVolumeAttachLimits compares the volumes attached per node and CSI driver, including the ones of hotplug
+// attachment pods, with the CSINode allocatable count, and estimates how many more VMs like the VM under test fit
+func (c *Checkup) checkVolumeAttachLimits(ctx context.Context, scs *storagev1.StorageClassList) error {
+	log.Print("checkVolumeAttachLimits")
+
+	csiNodes, err := c.client.ListCSINodes(ctx)
+	if err != nil {
+		return err
+	}
+	limits := map[string]map[string]*int32{}
+	for i := range csiNodes.Items {
+		csiNode := &csiNodes.Items[i]
+		limits[csiNode.Name] = map[string]*int32{}
+		for _, driver := range csiNode.Spec.Drivers {
+			limits[csiNode.Name][driver.Name] = nil
+			if driver.Allocatable != nil {
+				limits[csiNode.Name][driver.Name] = driver.Allocatable.Count
+			}
+		}
+	}
+
+	attached, hotplug, err := c.getAttachedVolumes(ctx, scs)
+	if err != nil {
+		return err
+	}
+
+	var res, warnings string
+	for _, node := range sortedKeys(limits) {
+		for _, driver := range sortedKeys(limits[node]) {
+			used := len(attached[node][driver])
+			line := fmt.Sprintf("%s %s: %d attached (%d by hotplug attachment pods)", node, driver, used, len(hotplug[node][driver]))
+			limit := limits[node][driver]
+			if limit == nil {
+				appendSep(&res, line+", no limit")
+				continue
+			}
+			headroom := 0
+			if *limit > 0 && used < int(*limit) {
+				headroom = (int(*limit) - used) * 100 / int(*limit)
+			}
+			appendSep(&res, fmt.Sprintf("%s, limit %d, headroom %d%%", line, *limit, headroom))
+			if headroom < c.checkupConfig.AttachHeadroomPercent {
+				appendSep(&warnings, fmt.Sprintf("%s %s: headroom %d%% is below %d%%", node, driver, headroom,
+					c.checkupConfig.AttachHeadroomPercent))
+			}
+		}
+	}
+
+	estimate, err := c.estimateVMsWithinAttachLimits(ctx, scs, limits, attached)
+	if err != nil {
+		return err
+	}
+	if estimate != "" {
+		appendSep(&res, estimate)
+	}
+
+	log.Print(res)
+	c.results.VolumeAttachLimits = res
+	if warnings != "" {
+		log.Print(warnings)
+		c.results.VolumeAttachLimitWarnings = warnings
+	}
+
+	return nil
+}
+
+// getAttachedVolumes returns the PVs attached per node and CSI driver according to the VolumeAttachments and the
+// hotplug attachment pods, along with the ones of the hotplug attachment pods only
+func (c *Checkup) getAttachedVolumes(ctx context.Context, scs *storagev1.StorageClassList) (
+	attached, hotplug nodeDriverVolumes, err error) {
+	vas, err := c.client.ListVolumeAttachments(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	attached, hotplug = nodeDriverVolumes{}, nodeDriverVolumes{}
+	for i := range vas.Items {
+		va := &vas.Items[i]
+		if va.DeletionTimestamp == nil && va.Spec.Source.PersistentVolumeName != nil {
+			attached.add(va.Spec.NodeName, va.Spec.Attacher, *va.Spec.Source.PersistentVolumeName)
+		}
+	}
+
+	pods, err := c.client.ListPods(ctx, metav1.NamespaceAll, hotplugAttachmentPodSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, vol := range pod.Spec.Volumes {
+			if vol.PersistentVolumeClaim == nil {
+				continue
+			}
+			pvc, err := c.client.GetPersistentVolumeClaim(ctx, pod.Namespace, vol.PersistentVolumeClaim.ClaimName)
+			if err != nil {
+				return nil, nil, err
+			}
+			driver := storageClassProvisioner(scs, pvc.Spec.StorageClassName)
+			if driver == "" || pvc.Spec.VolumeName == "" {
+				continue
+			}
+			attached.add(pod.Spec.NodeName, driver, pvc.Spec.VolumeName)
+			hotplug.add(pod.Spec.NodeName, driver, pvc.Spec.VolumeName)
+		}
+	}
+
+	return attached, hotplug, nil
+}
+
+// estimateVMsWithinAttachLimits returns how many more VMs with the volumes of the VM under test fit on the schedulable
+// nodes within their volume attach limits
+func (c *Checkup) estimateVMsWithinAttachLimits(ctx context.Context, scs *storagev1.StorageClassList,
+	limits map[string]map[string]*int32, attached nodeDriverVolumes) (string, error) {
+	if c.vmUnderTest == nil {
+		return "", nil
+	}
+	needs := map[string]int{}
+	for _, vol := range c.vmUnderTest.Spec.Template.Spec.Volumes {
+		if vol.DataVolume == nil {
+			continue
+		}
+		pvc, err := c.client.GetPersistentVolumeClaim(ctx, c.namespace, vol.DataVolume.Name)
+		if err != nil {
+			return "", err
+		}
+		if driver := storageClassProvisioner(scs, pvc.Spec.StorageClassName); driver != "" {
+			needs[driver]++
+		}
+	}
+	if len(needs) == 0 {
+		return "", nil
+	}
+
+	nodes, err := c.client.ListNodes(ctx)
+	if err != nil {
+		return "", err
+	}
+	total := 0
+	var unlimited []string
+	for _, node := range schedulableNodeNames(nodes) {
+		fits, ok := vmsFitOnNode(needs, limits[node], attached[node])
+		if !ok {
+			continue
+		}
+		if fits < 0 {
+			unlimited = append(unlimited, node)
+		} else {
+			total += fits
+		}
+	}
+
+	res := fmt.Sprintf("room for %d more VMs like %q within the volume attach limits", total, c.vmUnderTest.Name)
+	if len(unlimited) != 0 {
+		res += ", no limit on " + strings.Join(unlimited, ", ")
+	}
+	return res, nil
+}
+
+// vmsFitOnNode returns how many VMs needing the given volumes per CSI driver fit on a node, -1 when there is no limit,
+// and false when a driver is not registered on the node
+func vmsFitOnNode(needs map[string]int, limits map[string]*int32, attached map[string]map[string]bool) (int, bool) {
+	fits := -1
+	for driver, n := range needs {
+		limit, registered := limits[driver]
+		if !registered {
+			return 0, false
+		}
+		if limit == nil {
+			continue
+		}
+		free := int(*limit) - len(attached[driver])
+		if free < 0 {
+			free = 0
+		}
+		if fits < 0 || free/n < fits {
+			fits = free / n
+		}
+	}
+	return fits, true
+}
+
+func storageClassProvisioner(scs *storagev1.StorageClassList, name *string) string {
+	if name == nil {
+		return ""
+	}
+	for i := range scs.Items {
+		if scs.Items[i].Name == *name {
+			return scs.Items[i].Provisioner
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,291 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kvcorev1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	// eventReasonFailedAttachVolume is the reason of the events the attach/detach controller emits on pods whose volumes
+	// cannot be attached, e.g. with a Multi-Attach error when a RWO volume is still attached to another node
+	eventReasonFailedAttachVolume = "FailedAttachVolume"
+	multiAttachErrorMessage       = "Multi-Attach error"
+	virtLauncherPodPrefix         = "virt-launcher-"
+	// multiAttachEventWindow is how long ago a Multi-Attach error must have last occurred to be reported
+	multiAttachEventWindow = time.Hour
+	// migrationDetachGracePeriod is how long after a migration completes its source node attachment may still be
+	// detaching, so it is not told stale
+	migrationDetachGracePeriod = 5 * time.Minute
+
+	ErrVolumeAttachmentIssues = "there are VolumeAttachment errors or stale attachments of VMI volumes"
+)
+
+// pvConsumer is a VMI using a PV, along with the nodes the PV may be attached to and the end of its last completed
+// migration, if any
+type pvConsumer struct {
+	vmi        string
+	nodes      []string
+	migratedAt *time.Time
+}
+
+// checkVolumeAttachments reports the VolumeAttachments of PVs used by VMIs with attach or detach errors, the ones left
+// on nodes none of their VMIs runs on, and the recent Multi-Attach errors of virt-launcher pods, along with the time the
+// volumes of the VM under test took to attach. Attachments being deleted, or of VMIs which just migrated, are still
+// detaching and not told stale.
+func (c *Checkup) checkVolumeAttachments(ctx context.Context, errStr *string) error {
+	log.Print("checkVolumeAttachments")
+
+	vmis, err := c.listVMIsWithVMUnderTest(ctx)
+	if err != nil {
+		return err
+	}
+	consumers, err := c.getPVConsumers(ctx, vmis)
+	if err != nil {
+		return err
+	}
+	vas, err := c.client.ListVolumeAttachments(ctx)
+	if err != nil {
+		return err
+	}
+
+	var attachTimes, issues string
+	checked := 0
+	for i := range vas.Items {
+		va := &vas.Items[i]
+		pvName := va.Spec.Source.PersistentVolumeName
+		if pvName == nil || len(consumers[*pvName]) == 0 {
+			continue
+		}
+		checked++
+		desc := fmt.Sprintf("VolumeAttachment %s (PV %s, node %s)", va.Name, *pvName, va.Spec.NodeName)
+		if attachErr := va.Status.AttachError; attachErr != nil {
+			appendSep(&issues, fmt.Sprintf("%s attach error: %s", desc, attachErr.Message))
+		}
+		if detachErr := va.Status.DetachError; detachErr != nil {
+			appendSep(&issues, fmt.Sprintf("%s detach error: %s", desc, detachErr.Message))
+		}
+		if va.Status.Attached && va.DeletionTimestamp == nil && isStaleAttachment(va.Spec.NodeName, consumers[*pvName]) {
+			appendSep(&issues, fmt.Sprintf("%s is stale, used by %s", desc, formatPVConsumers(consumers[*pvName])))
+		}
+		if c.vmUnderTest != nil && isPVConsumer(consumers[*pvName], c.namespace+"/"+c.vmUnderTest.Name) {
+			if d, ok := attachDuration(va); ok {
+				appendSep(&attachTimes, fmt.Sprintf("%s of VMI %q attached in %s", desc, c.vmUnderTest.Name, d))
+			} else {
+				appendSep(&attachTimes, fmt.Sprintf("%s of VMI %q attach time unknown", desc, c.vmUnderTest.Name))
+			}
+		}
+	}
+
+	multiAttachErrs, err := c.listMultiAttachErrors(ctx, vmis)
+	if err != nil {
+		return err
+	}
+	if multiAttachErrs != "" {
+		appendSep(&issues, multiAttachErrs)
+	}
+
+	res := fmt.Sprintf("%d VolumeAttachments of VMI volumes checked", checked)
+	if attachTimes != "" {
+		appendSep(&res, attachTimes)
+	}
+	log.Print(res)
+	c.results.VolumeAttachments = res
+	if issues != "" {
+		log.Print(issues)
+		c.results.VolumeAttachmentIssues = issues
+		appendSep(errStr, ErrVolumeAttachmentIssues)
+	}
+
+	return nil
+}
+
+// listMultiAttachErrors returns the Multi-Attach errors reported within multiAttachEventWindow on the virt-launcher pods
+// in the namespaces of the VMIs
+func (c *Checkup) listMultiAttachErrors(ctx context.Context, vmis []kvcorev1.VirtualMachineInstance) (string, error) {
+	vmiNamespaces := map[string]bool{}
+	for i := range vmis {
+		vmiNamespaces[vmis[i].Namespace] = true
+	}
+
+	events, err := c.client.ListEvents(ctx, metav1.NamespaceAll)
+	if err != nil {
+		return "", fmt.Errorf("failed ListEvents: %s", err)
+	}
+
+	var res string
+	reported := map[string]bool{}
+	since := time.Now().Add(-multiAttachEventWindow)
+	for i := range events.Items {
+		event := &events.Items[i]
+		obj := event.InvolvedObject
+		if event.Reason != eventReasonFailedAttachVolume || !strings.Contains(event.Message, multiAttachErrorMessage) ||
+			obj.Kind != "Pod" || !strings.HasPrefix(obj.Name, virtLauncherPodPrefix) || !vmiNamespaces[obj.Namespace] ||
+			eventTime(event).Before(since) {
+			continue
+		}
+		line := fmt.Sprintf("Pod %s/%s: %s", obj.Namespace, obj.Name, event.Message)
+		if !reported[line] {
+			reported[line] = true
+			appendSep(&res, line)
+		}
+	}
+	return res, nil
+}
+
+// listVMIsWithVMUnderTest lists the VMIs of all namespaces, making sure the VMI under test is included even if it was
+// created after the list was cached
+func (c *Checkup) listVMIsWithVMUnderTest(ctx context.Context) ([]kvcorev1.VirtualMachineInstance, error) {
+	vmis, err := c.client.ListVirtualMachinesInstances(ctx, metav1.NamespaceAll)
+	if err != nil {
+		return nil, fmt.Errorf("failed ListVirtualMachinesInstances: %s", err)
+	}
+	if c.vmUnderTest == nil {
+		return vmis.Items, nil
+	}
+	for i := range vmis.Items {
+		if vmis.Items[i].Namespace == c.namespace && vmis.Items[i].Name == c.vmUnderTest.Name {
+			return vmis.Items, nil
+		}
+	}
+	vmi, err := c.client.GetVirtualMachineInstance(ctx, c.namespace, c.vmUnderTest.Name)
+	if err != nil {
+		return nil, ignoreNotFound(err)
+	}
+	return append(vmis.Items, *vmi), nil
+}
+
+// getPVConsumers maps the PVs of the VMI volumes to the VMIs using them
+func (c *Checkup) getPVConsumers(ctx context.Context, vmis []kvcorev1.VirtualMachineInstance) (map[string][]pvConsumer, error) {
+	consumers := map[string][]pvConsumer{}
+	for i := range vmis {
+		vmi := &vmis[i]
+		if vmi.Status.NodeName == "" && vmi.Status.Phase != kvcorev1.Running {
+			continue
+		}
+		consumer := pvConsumer{vmi: vmi.Namespace + "/" + vmi.Name, nodes: vmiNodes(vmi)}
+		if ms := vmi.Status.MigrationState; ms != nil && ms.Completed && ms.EndTimestamp != nil {
+			consumer.migratedAt = &ms.EndTimestamp.Time
+		}
+		for _, vol := range vmi.Spec.Volumes {
+			claimName := ""
+			if vol.PersistentVolumeClaim != nil {
+				claimName = vol.PersistentVolumeClaim.ClaimName
+			} else if vol.DataVolume != nil {
+				claimName = vol.DataVolume.Name
+			} else {
+				continue
+			}
+			pvc, err := c.client.GetPersistentVolumeClaim(ctx, vmi.Namespace, claimName)
+			if err != nil {
+				if ignoreNotFound(err) == nil {
+					continue
+				}
+				return nil, fmt.Errorf("failed GetPersistentVolumeClaim: %s", err)
+			}
+			if pvc.Spec.VolumeName != "" {
+				consumers[pvc.Spec.VolumeName] = append(consumers[pvc.Spec.VolumeName], consumer)
+			}
+		}
+	}
+	return consumers, nil
+}
+
+// vmiNodes returns the node the VMI runs on, and the migration target node while it is migrating
+func vmiNodes(vmi *kvcorev1.VirtualMachineInstance) []string {
+	nodes := []string{vmi.Status.NodeName}
+	if ms := vmi.Status.MigrationState; ms != nil && !ms.Completed && !ms.Failed && ms.TargetNode != "" {
+		nodes = append(nodes, ms.TargetNode)
+	}
+	return nodes
+}
+
+func isStaleAttachment(node string, consumers []pvConsumer) bool {
+	for _, consumer := range consumers {
+		if consumer.migratedAt != nil && time.Since(*consumer.migratedAt) < migrationDetachGracePeriod {
+			return false
+		}
+		for _, n := range consumer.nodes {
+			// The VMI node is unknown, so the attachment cannot be told stale
+			if n == "" || n == node {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// eventTime returns the last time the event occurred, falling back to the fields set by the events.k8s.io API
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case event.Series != nil:
+		return event.Series.LastObservedTime.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.FirstTimestamp.Time
+	}
+}
+
+func isPVConsumer(consumers []pvConsumer, vmi string) bool {
+	for _, consumer := range consumers {
+		if consumer.vmi == vmi {
+			return true
+		}
+	}
+	return false
+}
+
+func formatPVConsumers(consumers []pvConsumer) string {
+	var res []string
+	for _, consumer := range consumers {
+		res = append(res, fmt.Sprintf("VMI %s on node %s", consumer.vmi, strings.Join(consumer.nodes, ", ")))
+	}
+	return strings.Join(res, ", ")
+}
+
+// attachDuration returns the time from the VolumeAttachment creation to its earliest status update kept in its
+// managedFields, as the status has no timestamp of its own. This only approximates the attach time: managedFields keep
+// the time of the last update by each field manager, so a later status update by the external attacher makes it an
+// upper bound.
+func attachDuration(va *storagev1.VolumeAttachment) (time.Duration, bool) {
+	var attached *time.Time
+	for _, mf := range va.ManagedFields {
+		if mf.Subresource == "status" && mf.Time != nil && (attached == nil || mf.Time.Time.Before(*attached)) {
+			attached = &mf.Time.Time
+		}
+	}
+	if !va.Status.Attached || attached == nil {
+		return 0, false
+	}
+	return attached.Sub(va.CreationTimestamp.Time), true
+}
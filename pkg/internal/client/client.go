@@ -24,11 +24,14 @@ import (
 	"fmt"
 	"log"
 
+	authv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 
 	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
@@ -36,8 +39,10 @@ import (
 	configv1client "github.com/openshift/client-go/config/clientset/versioned/typed/config/v1"
 
 	kvcorev1 "kubevirt.io/api/core/v1"
+	exportv1 "kubevirt.io/api/export/v1alpha1"
 	"kubevirt.io/client-go/kubecli"
 	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+	cdiuploadv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/upload/v1beta1"
 )
 
 type Client struct {
@@ -74,6 +79,12 @@ func (c *Client) CreateVirtualMachine(ctx context.Context, namespace string, vm
 	return c.VirtualMachine(namespace).Create(ctx, vm)
 }
 
+// PatchVirtualMachine applies a JSON patch to the VM
+func (c *Client) PatchVirtualMachine(ctx context.Context, namespace, name string, patch []byte) error {
+	_, err := c.VirtualMachine(namespace).Patch(ctx, name, types.JSONPatchType, patch, &metav1.PatchOptions{})
+	return err
+}
+
 func (c *Client) DeleteVirtualMachine(ctx context.Context, namespace, name string) error {
 	return c.VirtualMachine(namespace).Delete(ctx, name, &metav1.DeleteOptions{})
 }
@@ -87,6 +98,22 @@ func (c *Client) CreateVirtualMachineInstanceMigration(ctx context.Context, name
 	return c.VirtualMachineInstanceMigration(namespace).Create(vmim, &metav1.CreateOptions{})
 }
 
+var kubeVirtGVR = kvcorev1.GroupVersion.WithResource("kubevirts")
+
+// GetUnstructuredKubeVirt gets the KubeVirt CR including the fields the vendored KubeVirt API lacks
+func (c *Client) GetUnstructuredKubeVirt(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	return c.DynamicClient().Resource(kubeVirtGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+var virtualMachineInstanceMigrationGVR = kvcorev1.GroupVersion.WithResource("virtualmachineinstancemigrations")
+
+// CreateUnstructuredVirtualMachineInstanceMigration creates a VMIM using fields the vendored KubeVirt API lacks
+func (c *Client) CreateUnstructuredVirtualMachineInstanceMigration(ctx context.Context, namespace string,
+	vmim *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	return c.DynamicClient().Resource(virtualMachineInstanceMigrationGVR).Namespace(namespace).Create(ctx, vmim,
+		metav1.CreateOptions{})
+}
+
 func (c *Client) AddVirtualMachineInstanceVolume(ctx context.Context, namespace, name string,
 	addVolumeOptions *kvcorev1.AddVolumeOptions) error {
 	return c.VirtualMachineInstance(namespace).AddVolume(ctx, name, addVolumeOptions)
@@ -105,10 +132,41 @@ func (c *Client) DeleteDataVolume(ctx context.Context, namespace, name string) e
 	return c.CdiClient().CdiV1beta1().DataVolumes(namespace).Delete(ctx, name, metav1.DeleteOptions{})
 }
 
+func (c *Client) GetDataVolume(ctx context.Context, namespace, name string) (*cdiv1.DataVolume, error) {
+	return c.CdiClient().CdiV1beta1().DataVolumes(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (c *Client) CreateUploadTokenRequest(ctx context.Context, namespace string, request *cdiuploadv1.UploadTokenRequest) (
+	*cdiuploadv1.UploadTokenRequest, error) {
+	return c.CdiClient().UploadV1beta1().UploadTokenRequests(namespace).Create(ctx, request, metav1.CreateOptions{})
+}
+
+func (c *Client) CreateSubjectAccessReview(ctx context.Context, sar *authv1.SubjectAccessReview) (
+	*authv1.SubjectAccessReview, error) {
+	return c.AuthorizationV1().SubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+}
+
 func (c *Client) DeletePersistentVolumeClaim(ctx context.Context, namespace, name string) error {
 	return c.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, name, metav1.DeleteOptions{})
 }
 
+func (c *Client) CreatePod(ctx context.Context, namespace string, pod *corev1.Pod) (*corev1.Pod, error) {
+	return c.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+}
+
+func (c *Client) DeletePod(ctx context.Context, namespace, name string) error {
+	return c.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (c *Client) CreateVolumeSnapshot(ctx context.Context, namespace string, snapshot *snapshotv1.VolumeSnapshot) (
+	*snapshotv1.VolumeSnapshot, error) {
+	return c.KubernetesSnapshotClient().SnapshotV1().VolumeSnapshots(namespace).Create(ctx, snapshot, metav1.CreateOptions{})
+}
+
+func (c *Client) DeleteVolumeSnapshot(ctx context.Context, namespace, name string) error {
+	return c.KubernetesSnapshotClient().SnapshotV1().VolumeSnapshots(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
 func (c *Client) ListNodes(ctx context.Context) (*corev1.NodeList, error) {
 	return c.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
 }
@@ -125,6 +183,18 @@ func (c *Client) ListStorageProfiles(ctx context.Context) (*cdiv1.StorageProfile
 	return c.CdiClient().CdiV1beta1().StorageProfiles().List(ctx, metav1.ListOptions{})
 }
 
+func (c *Client) ListCSIDrivers(ctx context.Context) (*storagev1.CSIDriverList, error) {
+	return c.StorageV1().CSIDrivers().List(ctx, metav1.ListOptions{})
+}
+
+func (c *Client) ListVolumeAttachments(ctx context.Context) (*storagev1.VolumeAttachmentList, error) {
+	return c.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+}
+
+func (c *Client) ListCSINodes(ctx context.Context) (*storagev1.CSINodeList, error) {
+	return c.StorageV1().CSINodes().List(ctx, metav1.ListOptions{})
+}
+
 func (c *Client) ListVolumeSnapshotClasses(ctx context.Context) (*snapshotv1.VolumeSnapshotClassList, error) {
 	return c.KubernetesSnapshotClient().SnapshotV1().VolumeSnapshotClasses().List(ctx, metav1.ListOptions{})
 }
@@ -133,10 +203,53 @@ func (c *Client) ListDataImportCrons(ctx context.Context, namespace string) (*cd
 	return c.CdiClient().CdiV1beta1().DataImportCrons(namespace).List(ctx, metav1.ListOptions{})
 }
 
+func (c *Client) ListServices(ctx context.Context, namespace, labelSelector string) (*corev1.ServiceList, error) {
+	return c.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+}
+
+func (c *Client) ListPersistentVolumeClaims(ctx context.Context, namespace, labelSelector string) (
+	*corev1.PersistentVolumeClaimList, error) {
+	return c.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+}
+
+func (c *Client) ListPersistentVolumes(ctx context.Context) (*corev1.PersistentVolumeList, error) {
+	return c.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+}
+
+func (c *Client) ListDataVolumes(ctx context.Context, namespace string) (*cdiv1.DataVolumeList, error) {
+	return c.CdiClient().CdiV1beta1().DataVolumes(namespace).List(ctx, metav1.ListOptions{})
+}
+
+func (c *Client) ListVirtualMachines(ctx context.Context, namespace string) (*kvcorev1.VirtualMachineList, error) {
+	return c.VirtualMachine(namespace).List(ctx, &metav1.ListOptions{})
+}
+
+func (c *Client) ListVolumeSnapshots(ctx context.Context, namespace, labelSelector string) (*snapshotv1.VolumeSnapshotList, error) {
+	return c.KubernetesSnapshotClient().SnapshotV1().VolumeSnapshots(namespace).List(ctx,
+		metav1.ListOptions{LabelSelector: labelSelector})
+}
+
+func (c *Client) ListResourceQuotas(ctx context.Context, namespace string) (*corev1.ResourceQuotaList, error) {
+	return c.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+}
+
+func (c *Client) ListEvents(ctx context.Context, namespace string) (*corev1.EventList, error) {
+	return c.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+}
+
+func (c *Client) ListPods(ctx context.Context, namespace, labelSelector string) (*corev1.PodList, error) {
+	return c.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+}
+
 func (c *Client) ListVirtualMachinesInstances(ctx context.Context, namespace string) (*kvcorev1.VirtualMachineInstanceList, error) {
 	return c.VirtualMachineInstance(namespace).List(ctx, &metav1.ListOptions{})
 }
 
+func (c *Client) ListVirtualMachineInstanceMigrations(ctx context.Context, namespace string) (
+	*kvcorev1.VirtualMachineInstanceMigrationList, error) {
+	return c.VirtualMachineInstanceMigration(namespace).List(&metav1.ListOptions{})
+}
+
 func (c *Client) ListCDIs(ctx context.Context) (*cdiv1.CDIList, error) {
 	return c.CdiClient().CdiV1beta1().CDIs().List(ctx, metav1.ListOptions{})
 }
@@ -145,6 +258,36 @@ func (c *Client) GetNamespace(ctx context.Context, name string) (*corev1.Namespa
 	return c.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
 }
 
+func (c *Client) GetConfigMap(ctx context.Context, namespace, name string) (*corev1.ConfigMap, error) {
+	return c.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (c *Client) CreateConfigMap(ctx context.Context, namespace string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	return c.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+}
+
+func (c *Client) UpdateConfigMap(ctx context.Context, namespace string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	return c.CoreV1().ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{})
+}
+
+func (c *Client) GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
+	return c.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// GetPodLogs returns the logs of the pod container, or of its only container when container is empty, limited to the
+// last tailLines lines if tailLines is positive
+func (c *Client) GetPodLogs(ctx context.Context, namespace, name, container string, tailLines int64) (string, error) {
+	opts := &corev1.PodLogOptions{Container: container}
+	if tailLines > 0 {
+		opts.TailLines = &tailLines
+	}
+	logs, err := c.CoreV1().Pods(namespace).GetLogs(name, opts).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(logs), nil
+}
+
 func (c *Client) GetPersistentVolumeClaim(ctx context.Context, namespace, name string) (*corev1.PersistentVolumeClaim, error) {
 	return c.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
 }
@@ -157,6 +300,10 @@ func (c *Client) GetVolumeSnapshot(ctx context.Context, namespace, name string)
 	return c.KubernetesSnapshotClient().SnapshotV1().VolumeSnapshots(namespace).Get(ctx, name, metav1.GetOptions{})
 }
 
+func (c *Client) GetVolumeSnapshotContent(ctx context.Context, name string) (*snapshotv1.VolumeSnapshotContent, error) {
+	return c.KubernetesSnapshotClient().SnapshotV1().VolumeSnapshotContents().Get(ctx, name, metav1.GetOptions{})
+}
+
 func (c *Client) GetCSIDriver(ctx context.Context, name string) (*storagev1.CSIDriver, error) {
 	return c.StorageV1().CSIDrivers().Get(ctx, name, metav1.GetOptions{})
 }
@@ -165,6 +312,10 @@ func (c *Client) GetDataSource(ctx context.Context, namespace, name string) (*cd
 	return c.CdiClient().CdiV1beta1().DataSources(namespace).Get(ctx, name, metav1.GetOptions{})
 }
 
+func (c *Client) GetCDIConfig(ctx context.Context) (*cdiv1.CDIConfig, error) {
+	return c.CdiClient().CdiV1beta1().CDIConfigs().Get(ctx, "config", metav1.GetOptions{})
+}
+
 func (c *Client) GetClusterVersion(ctx context.Context, name string) (*configv1.ClusterVersion, error) {
 	// Return NotFound error if OpenShift client is unavailable
 	if c.ClusterVersionsGetter == nil {
@@ -181,6 +332,38 @@ func (c *Client) GetKubeVirt(ctx context.Context, namespace, name string) (*kvco
 	return c.KubeVirt(namespace).Get(name, &metav1.GetOptions{})
 }
 
+func (c *Client) ListKubeVirts(ctx context.Context) (*kvcorev1.KubeVirtList, error) {
+	return c.KubeVirt(metav1.NamespaceAll).List(&metav1.ListOptions{})
+}
+
+func (c *Client) CreateVirtualMachineExport(ctx context.Context, namespace string, export *exportv1.VirtualMachineExport) (
+	*exportv1.VirtualMachineExport, error) {
+	return c.VirtualMachineExport(namespace).Create(ctx, export, metav1.CreateOptions{})
+}
+
+func (c *Client) GetVirtualMachineExport(ctx context.Context, namespace, name string) (*exportv1.VirtualMachineExport, error) {
+	return c.VirtualMachineExport(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (c *Client) DeleteVirtualMachineExport(ctx context.Context, namespace, name string) error {
+	return c.VirtualMachineExport(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+func (c *Client) CreateSecret(ctx context.Context, namespace string, secret *corev1.Secret) (*corev1.Secret, error) {
+	return c.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+}
+
+func (c *Client) DeleteSecret(ctx context.Context, namespace, name string) error {
+	return c.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+var hyperConvergedGVR = schema.GroupVersionResource{Group: "hco.kubevirt.io", Version: "v1beta1", Resource: "hyperconvergeds"}
+
+// GetHyperConverged retrieves the HyperConverged CR; its API is not vendored, hence the dynamic client
+func (c *Client) GetHyperConverged(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	return c.DynamicClient().Resource(hyperConvergedGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
 // GetKubernetesVersion retrieves the Kubernetes server version
 func (c *Client) GetKubernetesVersion() (string, error) {
 	versionInfo, err := c.Discovery().ServerVersion()
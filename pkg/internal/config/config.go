@@ -24,12 +24,14 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	kconfig "github.com/kiagnose/kiagnose/kiagnose/config"
 	kconfigmap "github.com/kiagnose/kiagnose/kiagnose/configmap"
 	"github.com/kiagnose/kiagnose/kiagnose/types"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/platform"
@@ -37,11 +39,32 @@ import (
 
 const (
 	StorageClassParamName          = "storageClass"
+	TargetStorageClassParamName    = "targetStorageClass"
 	VMITimeoutParamName            = "vmiTimeout"
+	BootP90ThresholdParamName      = "bootP90Threshold"
+	BootP99ThresholdParamName      = "bootP99Threshold"
+	BootRampParamName              = "bootRamp"
+	BootRampInitialVMsParamName    = "bootRampInitialVMs"
+	BootRampMaxFailedVMsParamName  = "bootRampMaxFailedVMs"
+	MaxNumOfVMsParamName           = "maxNumOfVMs"
 	NumOfVMsParamName              = "numOfVMs"
 	SkipTeardownParamName          = "skipTeardown"
 	PlatformParamName              = "platform"
 	GoldenImagesNamespaceParamName = "goldenImagesNamespace"
+	RWOOnlyProvisionersParamName   = "rwoOnlyProvisioners"
+	RWOOnlyStorageClassesParamName = "rwoOnlyStorageClasses"
+	UploadProxyInsecureParamName   = "uploadProxyInsecure"
+	TenantServiceAccountParamName  = "tenantServiceAccount"
+	GoldenImageMaxAgeParamName     = "goldenImageMaxAge"
+	AttachHeadroomPercentParamName = "attachHeadroomPercent"
+
+	MigrationSourceNodeSelectorParamName = "migrationSourceNodeSelector"
+	MigrationTargetNodeSelectorParamName = "migrationTargetNodeSelector"
+	MigrationAllNodesParamName           = "migrationAllNodes"
+
+	RemediationConfigMapParamName = "remediationConfigMap"
+	DiagnosticsConfigMapParamName = "diagnosticsConfigMap"
+	DiagnosticsDirParamName       = "diagnosticsDir"
 )
 
 // SkipTeardownMode defines the possible modes for skipping teardown.
@@ -56,12 +79,28 @@ const (
 const (
 	VMITimeoutDefault = 3 * time.Minute
 	NumOfVMsDefault   = 10
+
+	MaxNumOfVMsDefault        = 100
+	BootRampInitialVMsDefault = 5
+
+	AttachHeadroomPercentDefault = 20
 )
 
 var (
-	ErrInvalidVMITimeout       = errors.New("invalid VMI timeout")
-	ErrInvalidNumOfVMs         = errors.New("invalid number of VMIs")
-	ErrInvalidSkipTeardownMode = errors.New("invalid skip teardown mode")
+	ErrInvalidVMITimeout          = errors.New("invalid VMI timeout")
+	ErrInvalidNumOfVMs            = errors.New("invalid number of VMIs")
+	ErrInvalidSkipTeardownMode    = errors.New("invalid skip teardown mode")
+	ErrInvalidUploadProxyInsecure = errors.New("invalid upload proxy insecure mode")
+	ErrInvalidServiceAccount      = errors.New("invalid service account, expected <namespace>/<name>")
+	ErrInvalidGoldenImageAge      = errors.New("invalid golden image max age")
+	ErrInvalidAttachHeadroom      = errors.New("invalid attach headroom percent")
+	ErrInvalidNodeSelector        = errors.New("invalid migration node selector")
+	ErrInvalidMigrationAllNodes   = errors.New("invalid migration all nodes mode")
+	ErrConflictingMigration       = errors.New("migration all nodes mode cannot be combined with migration node selectors")
+	ErrInvalidBootThreshold       = errors.New("invalid boot time threshold")
+	ErrInvalidBootRamp            = errors.New("invalid boot ramp mode")
+	ErrInvalidBootRampVMs         = errors.New("invalid number of boot ramp VMs")
+	ErrMaxNumOfVMsWithoutRamp     = errors.New("maxNumOfVMs can only be set in boot ramp mode")
 )
 
 type Config struct {
@@ -72,12 +111,63 @@ type Config struct {
 	NumOfVMs     int
 	SkipTeardown SkipTeardownMode
 
+	// Maximal p90 and p99 concurrent VM boot times (optional, not checked when zero)
+	BootP90Threshold time.Duration
+	BootP99Threshold time.Duration
+
+	// Boot waves of doubling size from BootRampInitialVMs up to NumOfVMs, until a wave has more than BootRampMaxFailedVMs
+	// failed VMs or exceeds the boot time thresholds (optional)
+	BootRamp             bool
+	BootRampInitialVMs   int
+	BootRampMaxFailedVMs int
+
+	// Maximal NumOfVMs, which can only be raised in boot ramp mode
+	MaxNumOfVMs int
+
+	// Storage class the VM volume is migrated to (optional, storage migration is not checked when empty)
+	TargetStorageClass string
+
 	// Platform override (optional)
 	// Values: "openshift", "vanilla-k8s", or "" (auto-detect)
 	Platform string
 
 	// Golden images namespace (optional for OpenShift, required for vanilla-k8s)
 	GoldenImagesNamespace string
+
+	// Provisioners and storage classes supporting the ReadWriteOnce access mode only, in addition to the well-known
+	// cloud and local provisioners, e.g. the iSCSI class of a backend also serving NFS (optional)
+	RWOOnlyProvisioners   []string
+	RWOOnlyStorageClasses []string
+
+	// Retry the upload without TLS verification when the upload proxy certificate cannot be verified, so the rest of
+	// the upload path is still checked (optional, the certificate problem fails the checkup either way)
+	UploadProxyInsecure bool
+
+	// Tenant service account whose permission to clone golden images is checked (optional)
+	TenantServiceAccountNamespace string
+	TenantServiceAccountName      string
+
+	// Maximal age of the last golden image import (optional, not checked when zero)
+	GoldenImageMaxAge time.Duration
+
+	// Minimal percentage of free volume attachments per node and CSI driver before warning
+	AttachHeadroomPercent int
+
+	// Label selectors of the live migration source and target nodes (optional, the scheduler picks the target when both
+	// are empty)
+	MigrationSourceNodeSelector string
+	MigrationTargetNodeSelector string
+
+	// Migrate the VM around all the schedulable nodes in turn (optional)
+	MigrationAllNodes bool
+
+	// Name of the ConfigMap the remediation manifests are written to (optional, not written when empty)
+	RemediationConfigMap string
+
+	// Name of the ConfigMap and path of the local directory the diagnostics bundle is written to when a check fails
+	// (optional, nothing is collected when both are empty)
+	DiagnosticsConfigMap string
+	DiagnosticsDir       string
 }
 
 func New(baseConfig kconfig.Config) (Config, error) {
@@ -86,6 +176,10 @@ func New(baseConfig kconfig.Config) (Config, error) {
 		PodUID:     baseConfig.PodUID,
 		VMITimeout: VMITimeoutDefault,
 		NumOfVMs:   NumOfVMsDefault,
+
+		MaxNumOfVMs:           MaxNumOfVMsDefault,
+		BootRampInitialVMs:    BootRampInitialVMsDefault,
+		AttachHeadroomPercent: AttachHeadroomPercentDefault,
 	}
 
 	return setOptionalParams(baseConfig, newConfig)
@@ -98,14 +192,26 @@ func setOptionalParams(baseConfig kconfig.Config, newConfig Config) (Config, err
 		newConfig.StorageClass = sc
 	}
 
+	if sc, exists := baseConfig.Params[TargetStorageClassParamName]; exists {
+		newConfig.TargetStorageClass = sc
+	}
+
 	if newConfig, err = setVMITimeout(baseConfig, newConfig); err != nil {
 		return Config{}, err
 	}
 
+	if newConfig, err = setBootRamp(baseConfig, newConfig); err != nil {
+		return Config{}, err
+	}
+
 	if newConfig, err = setNumOfVMs(baseConfig, newConfig); err != nil {
 		return Config{}, err
 	}
 
+	if newConfig, err = setBootThresholds(baseConfig, newConfig); err != nil {
+		return Config{}, err
+	}
+
 	if newConfig, err = setSkipTeardown(baseConfig, newConfig); err != nil {
 		return Config{}, err
 	}
@@ -120,6 +226,46 @@ func setOptionalParams(baseConfig kconfig.Config, newConfig Config) (Config, err
 		newConfig.GoldenImagesNamespace = goldenImagesNS
 	}
 
+	if provisioners, exists := baseConfig.Params[RWOOnlyProvisionersParamName]; exists {
+		newConfig.RWOOnlyProvisioners = splitList(provisioners)
+	}
+
+	if scs, exists := baseConfig.Params[RWOOnlyStorageClassesParamName]; exists {
+		newConfig.RWOOnlyStorageClasses = splitList(scs)
+	}
+
+	if newConfig, err = setUploadProxyInsecure(baseConfig, newConfig); err != nil {
+		return Config{}, err
+	}
+
+	if newConfig, err = setTenantServiceAccount(baseConfig, newConfig); err != nil {
+		return Config{}, err
+	}
+
+	if newConfig, err = setGoldenImageMaxAge(baseConfig, newConfig); err != nil {
+		return Config{}, err
+	}
+
+	if newConfig, err = setAttachHeadroomPercent(baseConfig, newConfig); err != nil {
+		return Config{}, err
+	}
+
+	if newConfig, err = setMigrationNodes(baseConfig, newConfig); err != nil {
+		return Config{}, err
+	}
+
+	if cmName, exists := baseConfig.Params[RemediationConfigMapParamName]; exists {
+		newConfig.RemediationConfigMap = cmName
+	}
+
+	if cmName, exists := baseConfig.Params[DiagnosticsConfigMapParamName]; exists {
+		newConfig.DiagnosticsConfigMap = cmName
+	}
+
+	if dir, exists := baseConfig.Params[DiagnosticsDirParamName]; exists {
+		newConfig.DiagnosticsDir = dir
+	}
+
 	return newConfig, nil
 }
 
@@ -134,10 +280,43 @@ func setVMITimeout(baseConfig kconfig.Config, newConfig Config) (Config, error)
 	return newConfig, nil
 }
 
+func setBootRamp(baseConfig kconfig.Config, newConfig Config) (Config, error) {
+	if rawVal, exists := baseConfig.Params[BootRampParamName]; exists && rawVal != "" {
+		bootRamp, err := strconv.ParseBool(rawVal)
+		if err != nil {
+			return Config{}, ErrInvalidBootRamp
+		}
+		newConfig.BootRamp = bootRamp
+	}
+
+	// parsed in a fixed order, so the same params always fail with the same error
+	for _, param := range []struct {
+		name string
+		val  *int
+		min  int
+	}{
+		{BootRampInitialVMsParamName, &newConfig.BootRampInitialVMs, 1},
+		{BootRampMaxFailedVMsParamName, &newConfig.BootRampMaxFailedVMs, 0},
+		{MaxNumOfVMsParamName, &newConfig.MaxNumOfVMs, 1},
+	} {
+		if rawVal, exists := baseConfig.Params[param.name]; exists && rawVal != "" {
+			if param.name == MaxNumOfVMsParamName && !newConfig.BootRamp {
+				return Config{}, ErrMaxNumOfVMsWithoutRamp
+			}
+			val, err := strconv.Atoi(rawVal)
+			if err != nil || val < param.min {
+				return Config{}, ErrInvalidBootRampVMs
+			}
+			*param.val = val
+		}
+	}
+	return newConfig, nil
+}
+
 func setNumOfVMs(baseConfig kconfig.Config, newConfig Config) (Config, error) {
 	if rawVal, exists := baseConfig.Params[NumOfVMsParamName]; exists && rawVal != "" {
 		numOfVMs, err := strconv.Atoi(rawVal)
-		if err != nil || numOfVMs < 1 || numOfVMs > 100 {
+		if err != nil || numOfVMs < 1 || numOfVMs > newConfig.MaxNumOfVMs {
 			return Config{}, ErrInvalidNumOfVMs
 		}
 		newConfig.NumOfVMs = numOfVMs
@@ -145,6 +324,22 @@ func setNumOfVMs(baseConfig kconfig.Config, newConfig Config) (Config, error) {
 	return newConfig, nil
 }
 
+func setBootThresholds(baseConfig kconfig.Config, newConfig Config) (Config, error) {
+	for paramName, threshold := range map[string]*time.Duration{
+		BootP90ThresholdParamName: &newConfig.BootP90Threshold,
+		BootP99ThresholdParamName: &newConfig.BootP99Threshold,
+	} {
+		if rawVal, exists := baseConfig.Params[paramName]; exists && rawVal != "" {
+			d, err := time.ParseDuration(rawVal)
+			if err != nil || d <= 0 {
+				return Config{}, ErrInvalidBootThreshold
+			}
+			*threshold = d
+		}
+	}
+	return newConfig, nil
+}
+
 func setSkipTeardown(baseConfig kconfig.Config, newConfig Config) (Config, error) {
 	if rawVal, exists := baseConfig.Params[SkipTeardownParamName]; exists && rawVal != "" {
 		switch SkipTeardownMode(rawVal) {
@@ -163,6 +358,89 @@ func setSkipTeardown(baseConfig kconfig.Config, newConfig Config) (Config, error
 	return newConfig, nil
 }
 
+// splitList returns the non-empty items of a comma separated list
+func splitList(rawVal string) []string {
+	var items []string
+	for _, item := range strings.Split(rawVal, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func setUploadProxyInsecure(baseConfig kconfig.Config, newConfig Config) (Config, error) {
+	if rawVal, exists := baseConfig.Params[UploadProxyInsecureParamName]; exists && rawVal != "" {
+		insecure, err := strconv.ParseBool(rawVal)
+		if err != nil {
+			return Config{}, ErrInvalidUploadProxyInsecure
+		}
+		newConfig.UploadProxyInsecure = insecure
+	}
+	return newConfig, nil
+}
+
+func setTenantServiceAccount(baseConfig kconfig.Config, newConfig Config) (Config, error) {
+	if rawVal, exists := baseConfig.Params[TenantServiceAccountParamName]; exists && rawVal != "" {
+		namespace, name, found := strings.Cut(rawVal, "/")
+		if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+			return Config{}, ErrInvalidServiceAccount
+		}
+		newConfig.TenantServiceAccountNamespace = namespace
+		newConfig.TenantServiceAccountName = name
+	}
+	return newConfig, nil
+}
+
+func setGoldenImageMaxAge(baseConfig kconfig.Config, newConfig Config) (Config, error) {
+	if rawVal, exists := baseConfig.Params[GoldenImageMaxAgeParamName]; exists && rawVal != "" {
+		maxAge, err := time.ParseDuration(rawVal)
+		if err != nil || maxAge < 0 {
+			return Config{}, ErrInvalidGoldenImageAge
+		}
+		newConfig.GoldenImageMaxAge = maxAge
+	}
+	return newConfig, nil
+}
+
+func setAttachHeadroomPercent(baseConfig kconfig.Config, newConfig Config) (Config, error) {
+	if rawVal, exists := baseConfig.Params[AttachHeadroomPercentParamName]; exists && rawVal != "" {
+		percent, err := strconv.Atoi(rawVal)
+		if err != nil || percent < 0 || percent > 100 {
+			return Config{}, ErrInvalidAttachHeadroom
+		}
+		newConfig.AttachHeadroomPercent = percent
+	}
+	return newConfig, nil
+}
+
+func setMigrationNodes(baseConfig kconfig.Config, newConfig Config) (Config, error) {
+	for paramName, selector := range map[string]*string{
+		MigrationSourceNodeSelectorParamName: &newConfig.MigrationSourceNodeSelector,
+		MigrationTargetNodeSelectorParamName: &newConfig.MigrationTargetNodeSelector,
+	} {
+		if rawVal, exists := baseConfig.Params[paramName]; exists && rawVal != "" {
+			if _, err := labels.Parse(rawVal); err != nil {
+				return Config{}, ErrInvalidNodeSelector
+			}
+			*selector = rawVal
+		}
+	}
+
+	if rawVal, exists := baseConfig.Params[MigrationAllNodesParamName]; exists && rawVal != "" {
+		allNodes, err := strconv.ParseBool(rawVal)
+		if err != nil {
+			return Config{}, ErrInvalidMigrationAllNodes
+		}
+		newConfig.MigrationAllNodes = allNodes
+	}
+
+	if newConfig.MigrationAllNodes && (newConfig.MigrationSourceNodeSelector != "" || newConfig.MigrationTargetNodeSelector != "") {
+		return Config{}, ErrConflictingMigration
+	}
+	return newConfig, nil
+}
+
 // ReadWithDefaults inits the configmap with defaults where needed before reading it by kiagnose config infra
 func ReadWithDefaults(client kubernetes.Interface, namespace string, rawEnv map[string]string) (kconfig.Config, error) {
 	cmNamespace := rawEnv[kconfig.ConfigMapNamespaceEnvVarName]
@@ -231,8 +509,8 @@ func (c *Config) ValidateForPlatform(detectedPlatform platform.Type) error {
 		return fmt.Errorf("vmiTimeout must be positive")
 	}
 
-	if c.NumOfVMs < 1 || c.NumOfVMs > 100 {
-		return fmt.Errorf("numOfVMs must be between 1 and 100")
+	if c.NumOfVMs < 1 || c.NumOfVMs > c.MaxNumOfVMs {
+		return fmt.Errorf("numOfVMs must be between 1 and %d", c.MaxNumOfVMs)
 	}
 
 	return nil
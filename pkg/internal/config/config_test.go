@@ -37,12 +37,23 @@ import (
 )
 
 const (
-	testNamespace     = "target-ns"
-	testConfigMapName = "storage-checkup-config"
-	testStorageClass  = "test-sc"
-	testVMITimeout    = "1m"
-	testPodName       = "pod"
-	testPodUID        = "uid"
+	testNamespace          = "target-ns"
+	testConfigMapName      = "storage-checkup-config"
+	testStorageClass       = "test-sc"
+	testVMITimeout         = "1m"
+	testPodName            = "pod"
+	testPodUID             = "uid"
+	testTenantSA           = "tenant-ns/tenant-sa"
+	testMaxAge             = "720h"
+	testTargetStorageClass = "test-target-sc"
+	testAttachHeadroom     = "30"
+	testSourceNodeSelector = "topology.kubernetes.io/zone=zone-a"
+	testTargetNodeSelector = "topology.kubernetes.io/zone in (zone-b,zone-c)"
+	testRemediationCM      = "storage-checkup-remediations"
+	testBootP90Threshold   = "2m"
+	testDiagnosticsCM      = "storage-checkup-diagnostics"
+	testNumOfVMs           = "150"
+	testMaxNumOfVMs        = "200"
 )
 
 var testEnv = map[string]string{
@@ -124,6 +135,18 @@ func TestNewConfigMapOptionalParams(t *testing.T) {
 	cm := newConfigMap()
 	cm.Data[types.ParamNameKeyPrefix+config.StorageClassParamName] = testStorageClass
 	cm.Data[types.ParamNameKeyPrefix+config.VMITimeoutParamName] = testVMITimeout
+	cm.Data[types.ParamNameKeyPrefix+config.RWOOnlyProvisionersParamName] = "csi.example.com"
+	cm.Data[types.ParamNameKeyPrefix+config.RWOOnlyStorageClassesParamName] = "powerstore-iscsi, powerstore-xfs,"
+	cm.Data[types.ParamNameKeyPrefix+config.UploadProxyInsecureParamName] = "true"
+	cm.Data[types.ParamNameKeyPrefix+config.TenantServiceAccountParamName] = testTenantSA
+	cm.Data[types.ParamNameKeyPrefix+config.GoldenImageMaxAgeParamName] = testMaxAge
+	cm.Data[types.ParamNameKeyPrefix+config.TargetStorageClassParamName] = testTargetStorageClass
+	cm.Data[types.ParamNameKeyPrefix+config.AttachHeadroomPercentParamName] = testAttachHeadroom
+	cm.Data[types.ParamNameKeyPrefix+config.MigrationSourceNodeSelectorParamName] = testSourceNodeSelector
+	cm.Data[types.ParamNameKeyPrefix+config.MigrationTargetNodeSelectorParamName] = testTargetNodeSelector
+	cm.Data[types.ParamNameKeyPrefix+config.RemediationConfigMapParamName] = testRemediationCM
+	cm.Data[types.ParamNameKeyPrefix+config.BootP90ThresholdParamName] = testBootP90Threshold
+	cm.Data[types.ParamNameKeyPrefix+config.DiagnosticsConfigMapParamName] = testDiagnosticsCM
 
 	fakeClient := fake.NewSimpleClientset(cm)
 	baseConfig, err := config.ReadWithDefaults(fakeClient, testNamespace, testEnv)
@@ -138,6 +161,184 @@ func TestNewConfigMapOptionalParams(t *testing.T) {
 	duration, err := time.ParseDuration(testVMITimeout)
 	assert.NoError(t, err)
 	assert.Equal(t, duration, cfg.VMITimeout)
+	assert.Equal(t, []string{"csi.example.com"}, cfg.RWOOnlyProvisioners)
+	assert.Equal(t, []string{"powerstore-iscsi", "powerstore-xfs"}, cfg.RWOOnlyStorageClasses)
+	assert.True(t, cfg.UploadProxyInsecure)
+	assert.Equal(t, "tenant-ns", cfg.TenantServiceAccountNamespace)
+	assert.Equal(t, "tenant-sa", cfg.TenantServiceAccountName)
+	assert.Equal(t, 30*24*time.Hour, cfg.GoldenImageMaxAge)
+	assert.Equal(t, testTargetStorageClass, cfg.TargetStorageClass)
+	assert.Equal(t, 30, cfg.AttachHeadroomPercent)
+	assert.Equal(t, testSourceNodeSelector, cfg.MigrationSourceNodeSelector)
+	assert.Equal(t, testTargetNodeSelector, cfg.MigrationTargetNodeSelector)
+	assert.False(t, cfg.MigrationAllNodes)
+	assert.Equal(t, testRemediationCM, cfg.RemediationConfigMap)
+	assert.Equal(t, 2*time.Minute, cfg.BootP90Threshold)
+	assert.Zero(t, cfg.BootP99Threshold)
+	assert.Equal(t, testDiagnosticsCM, cfg.DiagnosticsConfigMap)
+	assert.Empty(t, cfg.DiagnosticsDir)
+}
+
+func TestNewConfigMapInvalidTenantServiceAccount(t *testing.T) {
+	for _, sa := range []string{"tenant-sa", "/tenant-sa", "tenant-ns/", "a/b/c"} {
+		cm := newConfigMap()
+		cm.Data[types.ParamNameKeyPrefix+config.TenantServiceAccountParamName] = sa
+
+		fakeClient := fake.NewSimpleClientset(cm)
+		baseConfig, err := config.ReadWithDefaults(fakeClient, testNamespace, testEnv)
+		assert.NoError(t, err)
+
+		_, err = config.New(baseConfig)
+		assert.ErrorIs(t, err, config.ErrInvalidServiceAccount, sa)
+	}
+}
+
+func TestNewConfigMapInvalidAttachHeadroomPercent(t *testing.T) {
+	for _, percent := range []string{"-1", "101", "ten"} {
+		cm := newConfigMap()
+		cm.Data[types.ParamNameKeyPrefix+config.AttachHeadroomPercentParamName] = percent
+
+		fakeClient := fake.NewSimpleClientset(cm)
+		baseConfig, err := config.ReadWithDefaults(fakeClient, testNamespace, testEnv)
+		assert.NoError(t, err)
+
+		_, err = config.New(baseConfig)
+		assert.ErrorIs(t, err, config.ErrInvalidAttachHeadroom, percent)
+	}
+}
+
+func TestNewConfigMapInvalidBootThreshold(t *testing.T) {
+	for _, threshold := range []string{"0s", "-1m", "two minutes"} {
+		cm := newConfigMap()
+		cm.Data[types.ParamNameKeyPrefix+config.BootP99ThresholdParamName] = threshold
+
+		fakeClient := fake.NewSimpleClientset(cm)
+		baseConfig, err := config.ReadWithDefaults(fakeClient, testNamespace, testEnv)
+		assert.NoError(t, err)
+
+		_, err = config.New(baseConfig)
+		assert.ErrorIs(t, err, config.ErrInvalidBootThreshold, threshold)
+	}
+}
+
+func TestNewConfigMapDiagnosticsConfigMap(t *testing.T) {
+	for _, tc := range []struct {
+		params   map[string]string
+		expected string
+	}{
+		{params: map[string]string{}, expected: ""},
+		{params: map[string]string{config.DiagnosticsConfigMapParamName: testDiagnosticsCM}, expected: testDiagnosticsCM},
+	} {
+		cm := newConfigMap()
+		for param, val := range tc.params {
+			cm.Data[types.ParamNameKeyPrefix+param] = val
+		}
+
+		fakeClient := fake.NewSimpleClientset(cm)
+		baseConfig, err := config.ReadWithDefaults(fakeClient, testNamespace, testEnv)
+		assert.NoError(t, err)
+
+		cfg, err := config.New(baseConfig)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, cfg.DiagnosticsConfigMap)
+	}
+}
+
+func TestNewConfigMapBootRamp(t *testing.T) {
+	cm := newConfigMap()
+	cm.Data[types.ParamNameKeyPrefix+config.BootRampParamName] = "true"
+	cm.Data[types.ParamNameKeyPrefix+config.BootRampMaxFailedVMsParamName] = "2"
+	cm.Data[types.ParamNameKeyPrefix+config.MaxNumOfVMsParamName] = testMaxNumOfVMs
+	cm.Data[types.ParamNameKeyPrefix+config.NumOfVMsParamName] = testNumOfVMs
+
+	fakeClient := fake.NewSimpleClientset(cm)
+	baseConfig, err := config.ReadWithDefaults(fakeClient, testNamespace, testEnv)
+	assert.NoError(t, err)
+
+	cfg, err := config.New(baseConfig)
+	assert.NoError(t, err)
+	assert.True(t, cfg.BootRamp)
+	assert.Equal(t, config.BootRampInitialVMsDefault, cfg.BootRampInitialVMs)
+	assert.Equal(t, 2, cfg.BootRampMaxFailedVMs)
+	assert.Equal(t, 200, cfg.MaxNumOfVMs)
+	assert.Equal(t, 150, cfg.NumOfVMs)
+}
+
+func TestNewConfigMapInvalidBootRamp(t *testing.T) {
+	testCases := map[string]struct {
+		params      map[string]string
+		expectedErr error
+	}{
+		"invalidBootRamp": {
+			params:      map[string]string{config.BootRampParamName: "ramp"},
+			expectedErr: config.ErrInvalidBootRamp,
+		},
+		"invalidInitialVMs": {
+			params:      map[string]string{config.BootRampParamName: "true", config.BootRampInitialVMsParamName: "0"},
+			expectedErr: config.ErrInvalidBootRampVMs,
+		},
+		"maxNumOfVMsWithoutRamp": {
+			params:      map[string]string{config.MaxNumOfVMsParamName: testMaxNumOfVMs},
+			expectedErr: config.ErrMaxNumOfVMsWithoutRamp,
+		},
+		"invalidInitialVMsAndMaxNumOfVMsWithoutRamp": {
+			params:      map[string]string{config.BootRampInitialVMsParamName: "0", config.MaxNumOfVMsParamName: testMaxNumOfVMs},
+			expectedErr: config.ErrInvalidBootRampVMs,
+		},
+		"numOfVMsAboveDefaultMax": {
+			params:      map[string]string{config.BootRampParamName: "true", config.NumOfVMsParamName: testNumOfVMs},
+			expectedErr: config.ErrInvalidNumOfVMs,
+		},
+	}
+	for name, tc := range testCases {
+		cm := newConfigMap()
+		for param, val := range tc.params {
+			cm.Data[types.ParamNameKeyPrefix+param] = val
+		}
+
+		fakeClient := fake.NewSimpleClientset(cm)
+		baseConfig, err := config.ReadWithDefaults(fakeClient, testNamespace, testEnv)
+		assert.NoError(t, err)
+
+		_, err = config.New(baseConfig)
+		assert.ErrorIs(t, err, tc.expectedErr, name)
+	}
+}
+
+func TestNewConfigMapInvalidMigrationNodes(t *testing.T) {
+	testCases := map[string]struct {
+		params      map[string]string
+		expectedErr error
+	}{
+		"invalidSourceNodeSelector": {
+			params:      map[string]string{config.MigrationSourceNodeSelectorParamName: "zone in zone-a"},
+			expectedErr: config.ErrInvalidNodeSelector,
+		},
+		"invalidAllNodes": {
+			params:      map[string]string{config.MigrationAllNodesParamName: "all"},
+			expectedErr: config.ErrInvalidMigrationAllNodes,
+		},
+		"allNodesWithNodeSelector": {
+			params: map[string]string{
+				config.MigrationAllNodesParamName:           "true",
+				config.MigrationTargetNodeSelectorParamName: testTargetNodeSelector,
+			},
+			expectedErr: config.ErrConflictingMigration,
+		},
+	}
+	for name, tc := range testCases {
+		cm := newConfigMap()
+		for param, val := range tc.params {
+			cm.Data[types.ParamNameKeyPrefix+param] = val
+		}
+
+		fakeClient := fake.NewSimpleClientset(cm)
+		baseConfig, err := config.ReadWithDefaults(fakeClient, testNamespace, testEnv)
+		assert.NoError(t, err)
+
+		_, err = config.New(baseConfig)
+		assert.ErrorIs(t, err, tc.expectedErr, name)
+	}
 }
 
 func newConfigMap() *corev1.ConfigMap {
@@ -0,0 +1,65 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/cron"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2024, time.February, 28, 13, 7, 30, 0, time.UTC)
+	tests := map[string]time.Time{
+		"* * * * *":        time.Date(2024, time.February, 28, 13, 8, 0, 0, time.UTC),
+		"0 */12 * * *":     time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		"24 5/12 * * *":    time.Date(2024, time.February, 28, 17, 24, 0, 0, time.UTC),
+		"0 0 1 * *":        time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		"30 6 * * mon-fri": time.Date(2024, time.February, 29, 6, 30, 0, 0, time.UTC),
+		"0 0 13 * 5":       time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":        time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC),
+		"15,45 9 1 jan *":  time.Date(2025, time.January, 1, 9, 15, 0, 0, time.UTC),
+		"@weekly":          time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC),
+		"@hourly":          time.Date(2024, time.February, 28, 14, 0, 0, 0, time.UTC),
+	}
+
+	for spec, expected := range tests {
+		schedule, err := cron.Parse(spec)
+		assert.NoError(t, err, spec)
+		assert.Equal(t, expected, schedule.Next(from), spec)
+	}
+}
+
+func TestNextNeverFires(t *testing.T) {
+	schedule, err := cron.Parse("0 0 30 2 *")
+	assert.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *",
+		"5-1 * * * *", "* * * foo *", "@reboot"} {
+		_, err := cron.Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+// Package imageserver generates tiny disk images and serves them over HTTP, so the CDI import path can be checked
+// without any external image source
+package imageserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// Command is the argument the checkup image is started with to serve the generated disk images
+	Command = "image-server"
+	Port    = 8080
+
+	RawImage    = "disk.img"
+	Qcow2Image  = "disk.qcow2"
+	GzipImage   = "disk.img.gz"
+	ImageSize   = 1024 * 1024
+	readTimeout = 10 * time.Second
+
+	qcow2ClusterBits = 16
+	qcow2ClusterSize = 1 << qcow2ClusterBits
+)
+
+// Images returns the served images by file name
+func Images() (map[string][]byte, error) {
+	raw := NewRawImage(ImageSize)
+	gz, err := gzipImage(raw)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		RawImage:   raw,
+		Qcow2Image: NewQcow2Image(ImageSize),
+		GzipImage:  gz,
+	}, nil
+}
+
+// ListenAndServe serves the generated images on Port until it fails
+func ListenAndServe() error {
+	images, err := Images()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	modTime := time.Now()
+	for name, image := range images {
+		name, image := name, image
+		mux.HandleFunc("/"+name, func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, name, modTime, bytes.NewReader(image))
+		})
+	}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", Port),
+		Handler:           mux,
+		ReadHeaderTimeout: readTimeout,
+	}
+	log.Printf("Serving %d images on %s", len(images), server.Addr)
+	return server.ListenAndServe()
+}
+
+// NewRawImage returns an empty raw disk image with an MBR boot signature
+func NewRawImage(size int) []byte {
+	image := make([]byte, size)
+	image[510] = 0x55
+	image[511] = 0xAA
+	return image
+}
+
+// NewQcow2Image returns a qcow2 (version 2) image of the given virtual size with no allocated data clusters.
+// The clusters are laid out as: header, refcount table, refcount block, L1 table.
+func NewQcow2Image(virtualSize uint64) []byte {
+	const (
+		refcountTableOffset = 1 * qcow2ClusterSize
+		refcountBlockOffset = 2 * qcow2ClusterSize
+		l1TableOffset       = 3 * qcow2ClusterSize
+		numClusters         = 4
+		// Each L2 table holds one 8 bytes entry per cluster
+		l2Coverage = qcow2ClusterSize / 8 * qcow2ClusterSize
+	)
+
+	image := make([]byte, numClusters*qcow2ClusterSize)
+	be := binary.BigEndian
+
+	copy(image[0:4], "QFI\xfb")
+	be.PutUint32(image[4:8], 2)
+	// backing_file_offset and backing_file_size are left zero
+	be.PutUint32(image[20:24], qcow2ClusterBits)
+	be.PutUint64(image[24:32], virtualSize)
+	// crypt_method is left zero
+	be.PutUint32(image[36:40], uint32((virtualSize+l2Coverage-1)/l2Coverage))
+	be.PutUint64(image[40:48], l1TableOffset)
+	be.PutUint64(image[48:56], refcountTableOffset)
+	be.PutUint32(image[56:60], 1)
+	// nb_snapshots and snapshots_offset are left zero
+
+	be.PutUint64(image[refcountTableOffset:], refcountBlockOffset)
+	for i := 0; i < numClusters; i++ {
+		be.PutUint16(image[refcountBlockOffset+2*i:], 1)
+	}
+
+	return image
+}
+
+func gzipImage(image []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(image); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,70 @@
+/*
+ * This file is part of the kiagnose project
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package imageserver_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/kiagnose/kubevirt-storage-checkup/pkg/internal/imageserver"
+)
+
+func TestImages(t *testing.T) {
+	images, err := imageserver.Images()
+	assert.NoError(t, err)
+	assert.Len(t, images, 3)
+
+	raw := images[imageserver.RawImage]
+	assert.Len(t, raw, imageserver.ImageSize)
+	assert.Equal(t, []byte{0x55, 0xAA}, raw[510:512])
+
+	gz, err := gzip.NewReader(bytes.NewReader(images[imageserver.GzipImage]))
+	assert.NoError(t, err)
+	unzipped, err := io.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, unzipped)
+}
+
+func TestQcow2Header(t *testing.T) {
+	image := imageserver.NewQcow2Image(imageserver.ImageSize)
+	be := binary.BigEndian
+
+	assert.Equal(t, []byte("QFI\xfb"), image[0:4])
+	assert.Equal(t, uint32(2), be.Uint32(image[4:8]))
+	assert.Equal(t, uint64(imageserver.ImageSize), be.Uint64(image[24:32]))
+	assert.Equal(t, uint32(1), be.Uint32(image[36:40]))
+
+	clusterSize := uint64(1) << be.Uint32(image[20:24])
+	l1TableOffset := be.Uint64(image[40:48])
+	refcountTableOffset := be.Uint64(image[48:56])
+	assert.Zero(t, l1TableOffset%clusterSize)
+	assert.Zero(t, refcountTableOffset%clusterSize)
+	assert.Equal(t, uint64(len(image)), l1TableOffset+clusterSize)
+
+	refcountBlockOffset := be.Uint64(image[refcountTableOffset:])
+	for cluster := uint64(0); cluster < uint64(len(image))/clusterSize; cluster++ {
+		assert.Equal(t, uint16(1), be.Uint16(image[refcountBlockOffset+2*cluster:]))
+	}
+}
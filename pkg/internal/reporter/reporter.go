@@ -38,20 +38,57 @@ const (
 	// Storage constants
 	DefaultStorageClassKey                       = "defaultStorageClass"
 	PVCBoundKey                                  = "pvcBound"
+	PodIOBaselineKey                             = "podIOBaseline"
+	CDIUploadKey                                 = "cdiUpload"
+	CDIImportKey                                 = "cdiImport"
+	CDIConfigKey                                 = "cdiConfig"
+	CDIConfigIssuesKey                           = "cdiConfigIssues"
+	KubeVirtConfigKey                            = "kubeVirtConfig"
+	HyperConvergedKey                            = "hyperConverged"
+	HyperConvergedIssuesKey                      = "hyperConvergedIssues"
 	StorageProfilesWithEmptyClaimPropertySetsKey = "storageProfilesWithEmptyClaimPropertySets"
 	StorageProfilesWithSpecClaimPropertySetsKey  = "storageProfilesWithSpecClaimPropertySets"
 	StorageProfilesWithSmartCloneKey             = "storageProfilesWithSmartClone"
 	StorageProfilesWithRWXKey                    = "storageProfilesWithRWX"
+	CSIDriverCapabilitiesKey                     = "csiDriverCapabilities"
+	StorageProfilesCSIDriverMismatchKey          = "storageProfilesCSIDriverMismatch"
+	StorageProfilesFSGroupPolicyKey              = "storageProfilesFSGroupPolicy"
+	CSINodeCoverageKey                           = "csiNodeCoverage"
+	CSINodeCoverageIssuesKey                     = "csiNodeCoverageIssues"
 	StorageProfileMissingVolumeSnapshotClassKey  = "storageProfileMissingVolumeSnapshotClass"
+	StorageProfilesNoDefaultSnapshotClassKey     = "storageProfilesNoDefaultSnapshotClass"
+	StorageProfilesInvalidSnapshotClassKey       = "storageProfilesInvalidSnapshotClass"
+	VolumeSnapshotClassesMultipleDefaultKey      = "volumeSnapshotClassesMultipleDefault"
+	VolumeSnapshotClassesRetainPolicyKey         = "volumeSnapshotClassesRetainPolicy"
+	VolumeSnapshotRoundTripKey                   = "volumeSnapshotRoundTrip"
 	GoldenImagesNotUpToDateKey                   = "goldenImagesNotUpToDate"
 	GoldenImagesNoDataSourceKey                  = "goldenImagesNoDataSource"
+	GoldenImagesOutdatedKey                      = "goldenImagesOutdated"
+	DataImportCronsMissedScheduleKey             = "dataImportCronsMissedSchedule"
+	DataImportCronsExcessImportsKey              = "dataImportCronsExcessImports"
+	GoldenImageClonePermissionKey                = "goldenImageClonePermission"
+	GoldenImagesStorageClassDriftKey             = "goldenImagesStorageClassDrift"
 	VMsWithNonVirtRbdStorageClassKey             = "vmsWithNonVirtRbdStorageClass"
 	VMsWithUnsetEfsStorageClassKey               = "vmsWithUnsetEfsStorageClass"
+	UnhealthyStorageObjectsKey                   = "unhealthyStorageObjects"
+	VolumeAttachmentsKey                         = "volumeAttachments"
+	VolumeAttachmentIssuesKey                    = "volumeAttachmentIssues"
+	VolumeAttachLimitsKey                        = "volumeAttachLimits"
+	VolumeAttachLimitWarningsKey                 = "volumeAttachLimitWarnings"
 	VMBootFromGoldenImageKey                     = "vmBootFromGoldenImage"
 	VMVolumeCloneKey                             = "vmVolumeClone"
 	VMLiveMigrationKey                           = "vmLiveMigration"
+	VMLiveMigrationMatrixKey                     = "vmLiveMigrationMatrix"
+	VMLiveMigrationAdviceKey                     = "vmLiveMigrationAdvice"
 	VMHotplugVolumeKey                           = "vmHotplugVolume"
+	VMStorageMigrationKey                        = "vmStorageMigration"
+	VMExportKey                                  = "vmExport"
 	ConcurrentVMBootKey                          = "concurrentVMBoot"
+	ConcurrentVMBootLatencyKey                   = "concurrentVMBootLatency"
+	ConcurrentVMBootRampKey                      = "concurrentVMBootRamp"
+	ConcurrentVMBootFailuresKey                  = "concurrentVMBootFailures"
+	RemediationsKey                              = "remediations"
+	DiagnosticsKey                               = "diagnostics"
 )
 
 type Reporter struct {
@@ -91,22 +128,59 @@ func FormatResults(checkupResults status.Results) map[string]string {
 		KubeVirtVersionKey: checkupResults.KubeVirtVersion,
 
 		// Storage information
-		DefaultStorageClassKey: checkupResults.DefaultStorageClass,
-		PVCBoundKey:            checkupResults.PVCBound,
+		DefaultStorageClassKey:  checkupResults.DefaultStorageClass,
+		PVCBoundKey:             checkupResults.PVCBound,
+		PodIOBaselineKey:        checkupResults.PodIOBaseline,
+		CDIUploadKey:            checkupResults.CDIUpload,
+		CDIImportKey:            checkupResults.CDIImport,
+		CDIConfigKey:            checkupResults.CDIConfig,
+		CDIConfigIssuesKey:      checkupResults.CDIConfigIssues,
+		KubeVirtConfigKey:       checkupResults.KubeVirtConfig,
+		HyperConvergedKey:       checkupResults.HyperConverged,
+		HyperConvergedIssuesKey: checkupResults.HyperConvergedIssues,
 		StorageProfilesWithEmptyClaimPropertySetsKey: checkupResults.StorageProfilesWithEmptyClaimPropertySets,
 		StorageProfilesWithSpecClaimPropertySetsKey:  checkupResults.StorageProfilesWithSpecClaimPropertySets,
 		StorageProfilesWithSmartCloneKey:             checkupResults.StorageProfilesWithSmartClone,
 		StorageProfilesWithRWXKey:                    checkupResults.StorageProfilesWithRWX,
+		CSIDriverCapabilitiesKey:                     checkupResults.CSIDriverCapabilities,
+		StorageProfilesCSIDriverMismatchKey:          checkupResults.StorageProfilesCSIDriverMismatch,
+		StorageProfilesFSGroupPolicyKey:              checkupResults.StorageProfilesFSGroupPolicy,
+		CSINodeCoverageKey:                           checkupResults.CSINodeCoverage,
+		CSINodeCoverageIssuesKey:                     checkupResults.CSINodeCoverageIssues,
 		StorageProfileMissingVolumeSnapshotClassKey:  checkupResults.StorageProfileMissingVolumeSnapshotClass,
+		StorageProfilesNoDefaultSnapshotClassKey:     checkupResults.StorageProfilesNoDefaultSnapshotClass,
+		StorageProfilesInvalidSnapshotClassKey:       checkupResults.StorageProfilesInvalidSnapshotClass,
+		VolumeSnapshotClassesMultipleDefaultKey:      checkupResults.VolumeSnapshotClassesMultipleDefault,
+		VolumeSnapshotClassesRetainPolicyKey:         checkupResults.VolumeSnapshotClassesRetainPolicy,
+		VolumeSnapshotRoundTripKey:                   checkupResults.VolumeSnapshotRoundTrip,
 		GoldenImagesNotUpToDateKey:                   checkupResults.GoldenImagesNotUpToDate,
 		GoldenImagesNoDataSourceKey:                  checkupResults.GoldenImagesNoDataSource,
+		GoldenImagesOutdatedKey:                      checkupResults.GoldenImagesOutdated,
+		DataImportCronsMissedScheduleKey:             checkupResults.DataImportCronsMissedSchedule,
+		DataImportCronsExcessImportsKey:              checkupResults.DataImportCronsExcessImports,
+		GoldenImageClonePermissionKey:                checkupResults.GoldenImageClonePermission,
+		GoldenImagesStorageClassDriftKey:             checkupResults.GoldenImagesStorageClassDrift,
 		VMsWithNonVirtRbdStorageClassKey:             checkupResults.VMsWithNonVirtRbdStorageClass,
 		VMsWithUnsetEfsStorageClassKey:               checkupResults.VMsWithUnsetEfsStorageClass,
+		UnhealthyStorageObjectsKey:                   checkupResults.UnhealthyStorageObjects,
+		VolumeAttachmentsKey:                         checkupResults.VolumeAttachments,
+		VolumeAttachmentIssuesKey:                    checkupResults.VolumeAttachmentIssues,
+		VolumeAttachLimitsKey:                        checkupResults.VolumeAttachLimits,
+		VolumeAttachLimitWarningsKey:                 checkupResults.VolumeAttachLimitWarnings,
 		VMBootFromGoldenImageKey:                     checkupResults.VMBootFromGoldenImage,
 		VMVolumeCloneKey:                             checkupResults.VMVolumeClone,
 		VMLiveMigrationKey:                           checkupResults.VMLiveMigration,
+		VMLiveMigrationMatrixKey:                     checkupResults.VMLiveMigrationMatrix,
+		VMLiveMigrationAdviceKey:                     checkupResults.VMLiveMigrationAdvice,
 		VMHotplugVolumeKey:                           checkupResults.VMHotplugVolume,
+		VMStorageMigrationKey:                        checkupResults.VMStorageMigration,
+		VMExportKey:                                  checkupResults.VMExport,
 		ConcurrentVMBootKey:                          checkupResults.ConcurrentVMBoot,
+		ConcurrentVMBootLatencyKey:                   checkupResults.ConcurrentVMBootLatency,
+		ConcurrentVMBootRampKey:                      checkupResults.ConcurrentVMBootRamp,
+		ConcurrentVMBootFailuresKey:                  checkupResults.ConcurrentVMBootFailures,
+		RemediationsKey:                              checkupResults.Remediations,
+		DiagnosticsKey:                               checkupResults.Diagnostics,
 	}
 
 	return formattedResults
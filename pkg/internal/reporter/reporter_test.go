@@ -61,24 +61,61 @@ func TestReportShouldSuccessfullyReportResults(t *testing.T) {
 		checkupStatus.FailureReason = []string{}
 		checkupStatus.CompletionTimestamp = time.Now()
 		checkupStatus.Results = status.Results{
-			OCPVersion:          "1.2.3",
-			CNVVersion:          "4.5.6",
-			DefaultStorageClass: "test_sc",
-			PVCBound:            "ok",
+			OCPVersion:           "1.2.3",
+			CNVVersion:           "4.5.6",
+			DefaultStorageClass:  "test_sc",
+			PVCBound:             "ok",
+			PodIOBaseline:        "write 200 MB/s, read 400 MB/s",
+			CDIUpload:            "Upload proxy: https://cdi-uploadproxy.cdi.svc",
+			CDIImport:            "Image server: http://10.0.0.1:8080",
+			CDIConfig:            "featureGates: HonorWaitForFirstConsumer",
+			CDIConfigIssues:      "scratchSpaceStorageClass \"sc\" does not exist",
+			KubeVirtConfig:       "featureGates: HotplugVolumes",
+			HyperConverged:       "scratchSpaceStorageClass: sc",
+			HyperConvergedIssues: "scratchSpaceStorageClass \"sc\" does not exist",
 			StorageProfilesWithEmptyClaimPropertySets: "sc1, sc2",
 			StorageProfilesWithSpecClaimPropertySets:  "sc3, sc4",
 			StorageProfilesWithSmartClone:             "sc4, sc5",
 			StorageProfilesWithRWX:                    "sc5, sc6",
+			CSIDriverCapabilities:                     "driver1: attachRequired=true",
+			StorageProfilesCSIDriverMismatch:          "sc6: mismatch",
+			StorageProfilesFSGroupPolicy:              "sc6: fsGroupPolicy",
+			CSINodeCoverage:                           "csi1: registered on 1/2 schedulable nodes, missing on node2",
+			CSINodeCoverageIssues:                     "node2: no CSI node plugin",
 			StorageProfileMissingVolumeSnapshotClass:  "sc7, sc8",
+			StorageProfilesNoDefaultSnapshotClass:     "sc9",
+			StorageProfilesInvalidSnapshotClass:       "sc10: snapshotClass \"vsc\" not found",
+			VolumeSnapshotClassesMultipleDefault:      "driver2: vsc1, vsc2",
+			VolumeSnapshotClassesRetainPolicy:         "vsc3",
+			VolumeSnapshotRoundTrip:                   "sc11: snapshot ready in 3s, restore bound in 5s, restoreSize 100Mi",
 			GoldenImagesNotUpToDate:                   "dic1, dic2",
 			GoldenImagesNoDataSource:                  "dic3",
+			GoldenImagesOutdated:                      "dic4",
+			DataImportCronsMissedSchedule:             "dic5",
+			DataImportCronsExcessImports:              "dic6",
+			GoldenImageClonePermission:                "ok",
+			GoldenImagesStorageClassDrift:             "dic7",
 			VMsWithNonVirtRbdStorageClass:             "vm1,vm2",
 			VMsWithUnsetEfsStorageClass:               "vm3,vm4",
+			UnhealthyStorageObjects:                   "sc: PVC ns/pvc Pending for 2h0m0s",
+			VolumeAttachments:                         "1 VolumeAttachments of VMI volumes checked",
+			VolumeAttachmentIssues:                    "VolumeAttachment va (PV pv, node node) attach error: timeout",
+			VolumeAttachLimits:                        "node csi: 10 attached (2 by hotplug attachment pods), limit 12, headroom 16%",
+			VolumeAttachLimitWarnings:                 "node csi: headroom 16% is below 20%",
 			VMBootFromGoldenImage:                     "ok",
 			VMVolumeClone:                             "snapshot",
 			VMLiveMigration:                           "success",
+			VMLiveMigrationMatrix:                     "node1 -> node2: 5s",
+			VMLiveMigrationAdvice:                     "use storage class \"sc-nfs\" of the same provisioner",
 			VMHotplugVolume:                           "fail",
+			VMStorageMigration:                        "fail",
+			VMExport:                                  "fail",
 			ConcurrentVMBoot:                          "ok",
+			ConcurrentVMBootLatency:                   "time to agent connected: min 40s",
+			ConcurrentVMBootRamp:                      "wave of 5 VMs: 0 failed",
+			ConcurrentVMBootFailures:                  "vm-1: stalled at guest agent",
+			Remediations:                              "# Finding: StorageProfile sc has empty claimPropertySets",
+			Diagnostics:                               "diagnostics bundle of 12 files written to ConfigMap ns/diag",
 		}
 		assert.NoError(t, testReporter.Report(checkupStatus))
 
@@ -94,20 +131,57 @@ func TestReportShouldSuccessfullyReportResults(t *testing.T) {
 			"status.result.kubevirtVersion":                           checkupStatus.Results.KubeVirtVersion,
 			"status.result.defaultStorageClass":                       checkupStatus.Results.DefaultStorageClass,
 			"status.result.pvcBound":                                  checkupStatus.Results.PVCBound,
+			"status.result.podIOBaseline":                             checkupStatus.Results.PodIOBaseline,
+			"status.result.cdiUpload":                                 checkupStatus.Results.CDIUpload,
+			"status.result.cdiImport":                                 checkupStatus.Results.CDIImport,
+			"status.result.cdiConfig":                                 checkupStatus.Results.CDIConfig,
+			"status.result.cdiConfigIssues":                           checkupStatus.Results.CDIConfigIssues,
+			"status.result.kubeVirtConfig":                            checkupStatus.Results.KubeVirtConfig,
+			"status.result.hyperConverged":                            checkupStatus.Results.HyperConverged,
+			"status.result.hyperConvergedIssues":                      checkupStatus.Results.HyperConvergedIssues,
 			"status.result.storageProfilesWithEmptyClaimPropertySets": checkupStatus.Results.StorageProfilesWithEmptyClaimPropertySets,
 			"status.result.storageProfilesWithSpecClaimPropertySets":  checkupStatus.Results.StorageProfilesWithSpecClaimPropertySets,
 			"status.result.storageProfilesWithSmartClone":             checkupStatus.Results.StorageProfilesWithSmartClone,
 			"status.result.storageProfilesWithRWX":                    checkupStatus.Results.StorageProfilesWithRWX,
+			"status.result.csiDriverCapabilities":                     checkupStatus.Results.CSIDriverCapabilities,
+			"status.result.storageProfilesCSIDriverMismatch":          checkupStatus.Results.StorageProfilesCSIDriverMismatch,
+			"status.result.storageProfilesFSGroupPolicy":              checkupStatus.Results.StorageProfilesFSGroupPolicy,
+			"status.result.csiNodeCoverage":                           checkupStatus.Results.CSINodeCoverage,
+			"status.result.csiNodeCoverageIssues":                     checkupStatus.Results.CSINodeCoverageIssues,
 			"status.result.storageProfileMissingVolumeSnapshotClass":  checkupStatus.Results.StorageProfileMissingVolumeSnapshotClass,
+			"status.result.storageProfilesNoDefaultSnapshotClass":     checkupStatus.Results.StorageProfilesNoDefaultSnapshotClass,
+			"status.result.storageProfilesInvalidSnapshotClass":       checkupStatus.Results.StorageProfilesInvalidSnapshotClass,
+			"status.result.volumeSnapshotClassesMultipleDefault":      checkupStatus.Results.VolumeSnapshotClassesMultipleDefault,
+			"status.result.volumeSnapshotClassesRetainPolicy":         checkupStatus.Results.VolumeSnapshotClassesRetainPolicy,
+			"status.result.volumeSnapshotRoundTrip":                   checkupStatus.Results.VolumeSnapshotRoundTrip,
 			"status.result.goldenImagesNotUpToDate":                   checkupStatus.Results.GoldenImagesNotUpToDate,
 			"status.result.goldenImagesNoDataSource":                  checkupStatus.Results.GoldenImagesNoDataSource,
+			"status.result.goldenImagesOutdated":                      checkupStatus.Results.GoldenImagesOutdated,
+			"status.result.dataImportCronsMissedSchedule":             checkupStatus.Results.DataImportCronsMissedSchedule,
+			"status.result.dataImportCronsExcessImports":              checkupStatus.Results.DataImportCronsExcessImports,
+			"status.result.goldenImageClonePermission":                checkupStatus.Results.GoldenImageClonePermission,
+			"status.result.goldenImagesStorageClassDrift":             checkupStatus.Results.GoldenImagesStorageClassDrift,
 			"status.result.vmsWithNonVirtRbdStorageClass":             checkupStatus.Results.VMsWithNonVirtRbdStorageClass,
 			"status.result.vmsWithUnsetEfsStorageClass":               checkupStatus.Results.VMsWithUnsetEfsStorageClass,
+			"status.result.unhealthyStorageObjects":                   checkupStatus.Results.UnhealthyStorageObjects,
+			"status.result.volumeAttachments":                         checkupStatus.Results.VolumeAttachments,
+			"status.result.volumeAttachmentIssues":                    checkupStatus.Results.VolumeAttachmentIssues,
+			"status.result.volumeAttachLimits":                        checkupStatus.Results.VolumeAttachLimits,
+			"status.result.volumeAttachLimitWarnings":                 checkupStatus.Results.VolumeAttachLimitWarnings,
 			"status.result.vmBootFromGoldenImage":                     checkupStatus.Results.VMBootFromGoldenImage,
 			"status.result.vmVolumeClone":                             checkupStatus.Results.VMVolumeClone,
 			"status.result.vmLiveMigration":                           checkupStatus.Results.VMLiveMigration,
+			"status.result.vmLiveMigrationMatrix":                     checkupStatus.Results.VMLiveMigrationMatrix,
+			"status.result.vmLiveMigrationAdvice":                     checkupStatus.Results.VMLiveMigrationAdvice,
 			"status.result.vmHotplugVolume":                           checkupStatus.Results.VMHotplugVolume,
+			"status.result.vmStorageMigration":                        checkupStatus.Results.VMStorageMigration,
+			"status.result.vmExport":                                  checkupStatus.Results.VMExport,
 			"status.result.concurrentVMBoot":                          checkupStatus.Results.ConcurrentVMBoot,
+			"status.result.concurrentVMBootLatency":                   checkupStatus.Results.ConcurrentVMBootLatency,
+			"status.result.concurrentVMBootRamp":                      checkupStatus.Results.ConcurrentVMBootRamp,
+			"status.result.concurrentVMBootFailures":                  checkupStatus.Results.ConcurrentVMBootFailures,
+			"status.result.remediations":                              checkupStatus.Results.Remediations,
+			"status.result.diagnostics":                               checkupStatus.Results.Diagnostics,
 		}
 		assert.Equal(t, expectedReportData, getCheckupData(t, fakeClient, testNamespace, testConfigMapName))
 	})
@@ -36,20 +36,57 @@ type Results struct {
 	// Existing storage fields
 	DefaultStorageClass                       string
 	PVCBound                                  string
+	PodIOBaseline                             string
+	CDIUpload                                 string
+	CDIImport                                 string
+	CDIConfig                                 string
+	CDIConfigIssues                           string
+	KubeVirtConfig                            string
+	HyperConverged                            string
+	HyperConvergedIssues                      string
 	StorageProfilesWithEmptyClaimPropertySets string
 	StorageProfilesWithSpecClaimPropertySets  string
 	StorageProfilesWithSmartClone             string
 	StorageProfilesWithRWX                    string
+	CSIDriverCapabilities                     string
+	StorageProfilesCSIDriverMismatch          string
+	StorageProfilesFSGroupPolicy              string
+	CSINodeCoverage                           string
+	CSINodeCoverageIssues                     string
 	StorageProfileMissingVolumeSnapshotClass  string
+	StorageProfilesNoDefaultSnapshotClass     string
+	StorageProfilesInvalidSnapshotClass       string
+	VolumeSnapshotClassesMultipleDefault      string
+	VolumeSnapshotClassesRetainPolicy         string
+	VolumeSnapshotRoundTrip                   string
 	GoldenImagesNotUpToDate                   string
 	GoldenImagesNoDataSource                  string
+	GoldenImagesOutdated                      string
+	DataImportCronsMissedSchedule             string
+	DataImportCronsExcessImports              string
+	GoldenImageClonePermission                string
+	GoldenImagesStorageClassDrift             string
 	VMsWithNonVirtRbdStorageClass             string
 	VMsWithUnsetEfsStorageClass               string
+	UnhealthyStorageObjects                   string
+	VolumeAttachments                         string
+	VolumeAttachmentIssues                    string
+	VolumeAttachLimits                        string
+	VolumeAttachLimitWarnings                 string
 	VMBootFromGoldenImage                     string
 	VMVolumeClone                             string
 	VMLiveMigration                           string
+	VMLiveMigrationMatrix                     string
+	VMLiveMigrationAdvice                     string
 	VMHotplugVolume                           string
+	VMStorageMigration                        string
+	VMExport                                  string
 	ConcurrentVMBoot                          string
+	ConcurrentVMBootLatency                   string
+	ConcurrentVMBootRamp                      string
+	ConcurrentVMBootFailures                  string
+	Remediations                              string
+	Diagnostics                               string
 }
 
 type Status struct {